@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
 
+	"sistem-manajemen-armada/internal/archive"
+	"sistem-manajemen-armada/internal/auth"
 	"sistem-manajemen-armada/internal/config"
 	"sistem-manajemen-armada/internal/database"
 	"sistem-manajemen-armada/internal/geofence"
 	httpHandler "sistem-manajemen-armada/internal/http"
+	"sistem-manajemen-armada/internal/migrate"
 	"sistem-manajemen-armada/internal/rabbitmq"
 	"sistem-manajemen-armada/internal/repository"
 	"sistem-manajemen-armada/internal/service"
@@ -17,18 +22,117 @@ import (
 func main() {
 	cfg := config.Load()
 
-	db := database.NewPostgresPool(cfg.PostgresURL)
+	db := database.NewPostgresPool(cfg.PostgresURL, cfg.PostgresTLS)
 	defer db.Close()
 
+	if cfg.MigrateOnStart {
+		if err := migrate.Run(context.Background(), db); err != nil {
+			log.Fatalf("failed to run migrations: %v", err)
+		}
+	}
+
 	gf := geofence.NewGeofence(cfg.GeofenceLat, cfg.GeofenceLon, cfg.GeofenceRadius)
 	rabbit := rabbitmq.NewClient(cfg)
 
 	repo := repository.NewLocationRepository(db)
-	svc := service.NewLocationService(repo, gf, rabbit)
+	rollupRepo := repository.NewRollupRepository(db)
+
+	// Riwayat yang sudah diarsipkan dibaca langsung dari storage arsip
+	store, err := archive.NewStore(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("failed to init archive store: %v", err)
+	}
+	archives := service.NewArchiveService(repository.NewArchiveRepository(db), store, cfg.ArchiveAfter)
+
+	vehicleSvc := service.NewVehicleService(repository.NewVehicleRepository(db), cfg.UnregisteredVehiclePolicy)
+	deviceSvc := service.NewDeviceService(repository.NewDeviceRepository(db), vehicleSvc)
+	driverSvc := service.NewDriverService(repository.NewDriverRepository(db), vehicleSvc)
+	svc := service.NewLocationService(repo, rollupRepo, archives, vehicleSvc, deviceSvc, driverSvc, repository.NewGeofenceEventRepository(db), gf, rabbit)
+
+	// API hanya membaca perjalanan; segmentasi berjalan di mqtt-listener
+	tripSvc := service.NewTripService(repository.NewTripRepository(db), vehicleSvc, driverSvc, rabbit, service.TripConfig{})
+	geofenceSvc := service.NewGeofenceService(repository.NewGeofenceRepository(db))
+	stopSvc := service.NewStopService(repository.NewStopRepository(db), vehicleSvc, geofenceSvc, driverSvc, service.StopConfig{
+		MaxSpeedKmh: cfg.StopMaxSpeedKmh,
+		RadiusM:     cfg.StopRadiusM,
+		MinStop:     cfg.StopMinDuration,
+		MinIdle:     cfg.IdleMinDuration,
+	})
+
+	// API hanya membaca log overspeed dan mengelola batas kecepatan; deteksi berjalan di mqtt-listener
+	overspeedSvc := service.NewOverspeedService(repository.NewOverspeedRepository(db), repository.NewSpeedLimitRepository(db),
+		vehicleSvc, geofenceSvc, driverSvc, rabbit, service.OverspeedConfig{})
+
+	tz, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		log.Fatalf("invalid TIMEZONE %q: %v", cfg.Timezone, err)
+	}
+	distanceSvc := service.NewDistanceService(repository.NewDistanceRepository(db), vehicleSvc, tz)
+
+	// Stream lokasi & event realtime dari RabbitMQ ke klien SSE / WebSocket
+	streamSvc := service.NewStreamService(rabbit, vehicleSvc)
+	go streamSvc.Run(context.Background())
+
+	// Rollup per menit / per jam berjalan di background
+	if cfg.RollupEnabled {
+		go service.NewRollupService(rollupRepo).Run(context.Background(), cfg.RollupInterval)
+	}
+
+	apiKeySvc := service.NewAPIKeyService(repository.NewAPIKeyRepository(db), cfg.APIKeyDefaultRateLimit)
+	// API hanya mengelola webhook dan log pengirimannya; pengiriman berjalan di geofence-worker
+	webhookSvc := service.NewWebhookService(repository.NewWebhookRepository(db), repository.NewWebhookDeliveryRepository(db),
+		nil, service.WebhookConfig{})
+	// aturan notifikasi dikelola dan diuji (dry-run) di API; pencocokan event dan pengiriman di geofence-worker
+	ruleSvc := service.NewNotificationRuleService(repository.NewNotificationRuleRepository(db), repository.NewNotificationRepository(db),
+		vehicleSvc, geofenceSvc, tz)
+	mqttCredSvc := service.NewMQTTCredentialService(repository.NewMQTTCredentialRepository(db), vehicleSvc, deviceSvc,
+		cfg.MQTTUsername, cfg.MQTTPassword)
 
 	r := gin.Default()
-	h := httpHandler.NewHandler(svc)
+	if cfg.AuthEnabled {
+		jwt, err := auth.NewJWTVerifier(auth.JWTConfig{
+			HS256Secret: cfg.JWTHS256Secret,
+			JWKSFile:    cfg.JWTJWKSFile,
+			Issuer:      cfg.JWTIssuer,
+			Audience:    cfg.JWTAudience,
+			Leeway:      cfg.JWTLeeway,
+		})
+		if err != nil {
+			log.Fatalf("failed to init JWT auth: %v", err)
+		}
+		r.Use(httpHandler.Authenticate(jwt, apiKeySvc))
+	} else {
+		log.Println("WARN: AUTH_ENABLED=false, all API routes are public")
+	}
+
+	h := httpHandler.NewHandler(svc, tz)
 	h.RegisterRoutes(r)
+	httpHandler.NewVehicleHandler(vehicleSvc).RegisterRoutes(r)
+	httpHandler.NewDeviceHandler(deviceSvc).RegisterRoutes(r)
+	httpHandler.NewDriverHandler(driverSvc).RegisterRoutes(r)
+	httpHandler.NewTripHandler(tripSvc, svc, tz).RegisterRoutes(r)
+	httpHandler.NewGeofenceHandler(geofenceSvc).RegisterRoutes(r)
+	httpHandler.NewGeofenceEventHandler(svc, tz).RegisterRoutes(r)
+	httpHandler.NewStopHandler(stopSvc).RegisterRoutes(r)
+	httpHandler.NewOverspeedHandler(overspeedSvc).RegisterRoutes(r)
+	httpHandler.NewReportHandler(distanceSvc).RegisterRoutes(r)
+	httpHandler.NewStreamHandler(streamSvc, cfg.StreamAllowedOrigins).RegisterRoutes(r)
+	httpHandler.NewAPIKeyHandler(apiKeySvc).RegisterRoutes(r)
+	httpHandler.NewMQTTCredentialHandler(mqttCredSvc).RegisterRoutes(r)
+	httpHandler.NewWebhookHandler(webhookSvc).RegisterRoutes(r)
+	httpHandler.NewNotificationRuleHandler(ruleSvc).RegisterRoutes(r)
+
+	// Hook auth broker di listener internal sendiri, tanpa middleware token API
+	if cfg.MQTTAuthHookAddr != "" {
+		hooks := gin.Default()
+		httpHandler.NewMQTTCredentialHandler(mqttCredSvc).RegisterHookRoutes(hooks)
+		go func() {
+			log.Printf("MQTT auth hooks listening on %s", cfg.MQTTAuthHookAddr)
+			if err := hooks.Run(cfg.MQTTAuthHookAddr); err != nil {
+				log.Fatalf("mqtt auth hook server failed: %v", err)
+			}
+		}()
+	}
 
 	log.Printf("API server listening on :%s", cfg.AppPort)
 	if err := r.Run(":" + cfg.AppPort); err != nil {
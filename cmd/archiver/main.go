@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"sistem-manajemen-armada/internal/archive"
+	"sistem-manajemen-armada/internal/config"
+	"sistem-manajemen-armada/internal/database"
+	"sistem-manajemen-armada/internal/migrate"
+	"sistem-manajemen-armada/internal/repository"
+	"sistem-manajemen-armada/internal/service"
+)
+
+func main() {
+	once := flag.Bool("once", false, "arsipkan sekali lalu keluar (untuk cron)")
+	flag.Parse()
+
+	cfg := config.Load()
+	ctx := context.Background()
+
+	db := database.NewPostgresPool(cfg.PostgresURL, cfg.PostgresTLS)
+	defer db.Close()
+
+	if cfg.MigrateOnStart {
+		if err := migrate.Run(ctx, db); err != nil {
+			log.Fatalf("failed to run migrations: %v", err)
+		}
+	}
+
+	store, err := archive.NewStore(ctx, cfg)
+	if err != nil {
+		log.Fatalf("failed to init archive store: %v", err)
+	}
+
+	svc := service.NewArchiveService(repository.NewArchiveRepository(db), store, cfg.ArchiveAfter)
+
+	log.Printf("Archiver started: store=%s after=%s", cfg.ArchiveStore, cfg.ArchiveAfter)
+	if *once {
+		n, err := svc.ArchiveOnce(ctx, time.Now())
+		if err != nil {
+			log.Fatalf("archive failed: %v", err)
+		}
+		log.Printf("%d archive file(s) written", n)
+		return
+	}
+
+	svc.Run(ctx, cfg.ArchiveInterval)
+}
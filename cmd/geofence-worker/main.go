@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log"
+	"strings"
 	"time"
 
 	"sistem-manajemen-armada/internal/config"
+	"sistem-manajemen-armada/internal/database"
+	"sistem-manajemen-armada/internal/migrate"
+	"sistem-manajemen-armada/internal/rabbitmq"
+	"sistem-manajemen-armada/internal/repository"
+	"sistem-manajemen-armada/internal/service"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
-type GeofenceEvent struct {
+// FleetEvent adalah field umum event armada (geofence, trip, overspeed, tamper) yang dibaca worker.
+type FleetEvent struct {
+	TenantID  string `json:"tenant_id"`
 	VehicleID string `json:"vehicle_id"`
 	Event     string `json:"event"`
 	Location  struct {
@@ -22,12 +31,57 @@ type GeofenceEvent struct {
 
 func main() {
 	cfg := config.Load()
+	ctx := context.Background()
+
+	// --- Postgres: konfigurasi webhook, aturan notifikasi dan log pengiriman ---
+	db := database.NewPostgresPool(cfg.PostgresURL, cfg.PostgresTLS)
+	defer db.Close()
+
+	if cfg.MigrateOnStart {
+		if err := migrate.Run(ctx, db); err != nil {
+			log.Fatalf("failed to run migrations: %v", err)
+		}
+	}
+
+	webhooks := service.NewWebhookService(repository.NewWebhookRepository(db), repository.NewWebhookDeliveryRepository(db),
+		nil, service.WebhookConfig{
+			Timeout:         cfg.WebhookTimeout,
+			MaxAttempts:     cfg.WebhookMaxAttempts,
+			RetryBase:       cfg.WebhookRetryBase,
+			RetryMax:        cfg.WebhookRetryMax,
+			AllowedNetworks: cfg.WebhookAllowedNetworks,
+		})
+	go webhooks.Run(ctx, cfg.WebhookPollInterval)
+
+	// --- Aturan notifikasi: pencocokan event dan pengiriman email / SMS / chat / webhook ---
+	tz, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		log.Fatalf("invalid TIMEZONE %q: %v", cfg.Timezone, err)
+	}
+	vehicleSvc := service.NewVehicleService(repository.NewVehicleRepository(db), cfg.UnregisteredVehiclePolicy)
+	geofenceSvc := service.NewGeofenceService(repository.NewGeofenceRepository(db))
+	notificationRepo := repository.NewNotificationRepository(db)
+	rules := service.NewNotificationRuleService(repository.NewNotificationRuleRepository(db), notificationRepo,
+		vehicleSvc, geofenceSvc, tz)
+	notifier := service.NewNotificationService(notificationRepo, nil, service.NotificationConfig{
+		Timeout:         cfg.WebhookTimeout,
+		MaxAttempts:     cfg.WebhookMaxAttempts,
+		RetryBase:       cfg.WebhookRetryBase,
+		RetryMax:        cfg.WebhookRetryMax,
+		SMTPAddr:        cfg.SMTPAddr,
+		SMTPUsername:    cfg.SMTPUsername,
+		SMTPPassword:    cfg.SMTPPassword,
+		SMTPFrom:        cfg.SMTPFrom,
+		SMSGatewayURL:   cfg.SMSGatewayURL,
+		SMSGatewayToken: cfg.SMSGatewayToken,
+		AllowedNetworks: cfg.WebhookAllowedNetworks,
+	})
+	go notifier.Run(ctx, cfg.WebhookPollInterval)
 
 	var conn *amqp.Connection
-	var err error
 
 	for i := 1; i <= 10; i++ {
-		conn, err = amqp.Dial(cfg.RabbitURL)
+		conn, err = rabbitmq.Dial(cfg.RabbitURL, cfg.RabbitTLS)
 		if err == nil {
 			break
 		}
@@ -69,20 +123,24 @@ func main() {
 		log.Fatalf("queue declare error: %v", err)
 	}
 
-	if err := ch.QueueBind(
-		q.Name,
-		cfg.RabbitRoutingKey,
-		cfg.RabbitExchange,
-		false,
-		nil,
-	); err != nil {
-		log.Fatalf("queue bind error: %v", err)
+	// routing key diawali tenant, mis. acme.geofence.entry; event lain ikut dikonsumsi untuk webhook
+	for _, key := range append([]string{cfg.RabbitRoutingKey}, cfg.WorkerEventKeys...) {
+		if err := ch.QueueBind(
+			q.Name,
+			rabbitmq.AllTenants(key),
+			cfg.RabbitExchange,
+			false,
+			nil,
+		); err != nil {
+			log.Fatalf("queue bind error: %v", err)
+		}
 	}
 
+	// ack manual: event baru di-ack setelah tercatat untuk webhook dan aturan notifikasi
 	msgs, err := ch.Consume(
 		q.Name,
 		"",
-		true,  // auto-ack
+		false, // auto-ack
 		false, // exclusive
 		false,
 		false,
@@ -95,19 +153,48 @@ func main() {
 	log.Printf("Geofence worker listening on queue: %s", q.Name)
 
 	for msg := range msgs {
-		var event GeofenceEvent
-		if err := json.Unmarshal(msg.Body, &event); err != nil {
-			log.Printf("invalid event: %v", err)
+		var event FleetEvent
+		if err := json.Unmarshal(msg.Body, &event); err != nil || event.Event == "" {
+			log.Printf("invalid event on %s: %v", msg.RoutingKey, err)
+			_ = msg.Ack(false)
 			continue
 		}
+		// payload lama tanpa tenant_id: tenant diambil dari segmen pertama routing key
+		if event.TenantID == "" {
+			event.TenantID, _, _ = strings.Cut(msg.RoutingKey, ".")
+		}
 
+		label := "FLEET EVENT"
+		if event.Event == "geofence_entry" {
+			label = "GEOFENCE ALERT"
+		}
 		log.Printf(
-			"[GEOFENCE ALERT] vehicle=%s event=%s lat=%.6f lon=%.6f ts=%d",
+			"[%s] tenant=%s vehicle=%s event=%s lat=%.6f lon=%.6f ts=%d",
+			label,
+			event.TenantID,
 			event.VehicleID,
 			event.Event,
 			event.Location.Latitude,
 			event.Location.Longitude,
 			event.Timestamp,
 		)
+
+		// Kedua penulisan idempoten per event: bila Process gagal dan pesan di-requeue, pengiriman
+		// webhook yang sudah tercatat tidak dijadwalkan dua kali
+		key := service.EventKey(msg.Body)
+		if _, err := webhooks.Enqueue(ctx, event.TenantID, event.Event, key, msg.Body); err != nil {
+			// dicoba lagi setelah jeda; event tidak hilang walau database sedang bermasalah
+			log.Printf("failed to enqueue webhooks for %s: %v", event.Event, err)
+			time.Sleep(time.Second)
+			_ = msg.Nack(false, true)
+			continue
+		}
+		if _, err := rules.Process(ctx, event.TenantID, key, msg.Body); err != nil {
+			log.Printf("failed to apply notification rules for %s: %v", event.Event, err)
+			time.Sleep(time.Second)
+			_ = msg.Nack(false, true)
+			continue
+		}
+		_ = msg.Ack(false)
 	}
 }
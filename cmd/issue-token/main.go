@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"sistem-manajemen-armada/internal/auth"
+	"sistem-manajemen-armada/internal/config"
+)
+
+// issue-token menerbitkan token HS256 untuk pengembangan / pengujian lokal memakai JWT_HS256_SECRET.
+// Di produksi token diterbitkan identity provider.
+func main() {
+	sub := flag.String("sub", "dev", "subject (sub) token")
+	role := flag.String("role", "admin", "role: admin, dispatcher, viewer")
+	tenant := flag.String("tenant", auth.DefaultTenant, "tenant (perusahaan klien) pemilik token")
+	groups := flag.String("groups", "", "grup kendaraan dipisah koma; kosong = semua grup")
+	scope := flag.String("scope", "", "scope dipisah spasi untuk mempersempit scope role; kosong = semua scope role")
+	ttl := flag.Duration("ttl", time.Hour, "masa berlaku token")
+	flag.Parse()
+
+	cfg := config.Load()
+	if cfg.JWTHS256Secret == "" {
+		log.Fatal("JWT_HS256_SECRET is not set")
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"sub":    *sub,
+		"role":   *role,
+		"tenant": *tenant,
+		"iat":    now.Unix(),
+		"exp":    now.Add(*ttl).Unix(),
+	}
+	if cfg.JWTIssuer != "" {
+		claims["iss"] = cfg.JWTIssuer
+	}
+	if cfg.JWTAudience != "" {
+		claims["aud"] = cfg.JWTAudience
+	}
+	if *scope != "" {
+		claims["scope"] = *scope
+	}
+	if *groups != "" {
+		claims["groups"] = strings.Split(*groups, ",")
+	}
+
+	token, err := auth.SignHS256(claims, cfg.JWTHS256Secret)
+	if err != nil {
+		log.Fatalf("failed to sign token: %v", err)
+	}
+	fmt.Println(token)
+}
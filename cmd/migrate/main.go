@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"sistem-manajemen-armada/internal/config"
+	"sistem-manajemen-armada/internal/database"
+	"sistem-manajemen-armada/internal/migrate"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: migrate [up | down -steps N | status]\n")
+	os.Exit(2)
+}
+
+func main() {
+	cfg := config.Load()
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd := os.Args[1]
+
+	fset := flag.NewFlagSet(cmd, flag.ExitOnError)
+	steps := fset.Int("steps", 1, "jumlah migrasi yang di-rollback (khusus down)")
+	_ = fset.Parse(os.Args[2:])
+
+	db := database.NewPostgresPool(cfg.PostgresURL, cfg.PostgresTLS)
+	defer db.Close()
+
+	m, err := migrate.New(db)
+	if err != nil {
+		log.Fatalf("failed to load migrations: %v", err)
+	}
+
+	ctx := context.Background()
+
+	switch cmd {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			log.Fatalf("migrate up failed: %v", err)
+		}
+		log.Printf("%d migration(s) applied", n)
+
+	case "down":
+		n, err := m.Down(ctx, *steps)
+		if err != nil {
+			log.Fatalf("migrate down failed: %v", err)
+		}
+		log.Printf("%d migration(s) reverted", n)
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			log.Fatalf("migrate status failed: %v", err)
+		}
+		for _, st := range statuses {
+			applied := "pending"
+			if st.Applied {
+				applied = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", st.Version, st.Name, applied)
+		}
+
+	default:
+		usage()
+	}
+}
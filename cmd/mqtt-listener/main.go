@@ -9,37 +9,27 @@ import (
 	"strings"
 	"time"
 
+	"sistem-manajemen-armada/internal/auth"
 	"sistem-manajemen-armada/internal/config"
+	"sistem-manajemen-armada/internal/database"
 	"sistem-manajemen-armada/internal/geofence"
+	"sistem-manajemen-armada/internal/migrate"
+	"sistem-manajemen-armada/internal/models"
+	mqttclient "sistem-manajemen-armada/internal/mqtt"
+	"sistem-manajemen-armada/internal/rabbitmq"
+	"sistem-manajemen-armada/internal/repository"
+	"sistem-manajemen-armada/internal/service"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/jackc/pgx/v5/pgxpool"
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
-type VehicleLocation struct {
-	VehicleID string  `json:"vehicle_id"`
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-	Timestamp int64   `json:"timestamp"`
-}
-
-type GeofenceEvent struct {
-	VehicleID string `json:"vehicle_id"`
-	Event     string `json:"event"`
-	Location  struct {
-		Latitude  float64 `json:"latitude"`
-		Longitude float64 `json:"longitude"`
-	} `json:"location"`
-	Timestamp int64 `json:"timestamp"`
-}
-
 // --- helper retry Postgres ---
-func waitForPostgres(ctx context.Context, dsn string, maxAttempts int, baseDelay time.Duration) (*pgxpool.Pool, error) {
+func waitForPostgres(ctx context.Context, poolCfg *pgxpool.Config, maxAttempts int, baseDelay time.Duration) (*pgxpool.Pool, error) {
 	var lastErr error
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		pool, err := pgxpool.New(ctx, dsn)
+		pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 		if err != nil {
 			log.Printf("postgres create pool try %d failed: %v", attempt, err)
 			lastErr = err
@@ -64,6 +54,26 @@ func waitForPostgres(ctx context.Context, dsn string, maxAttempts int, baseDelay
 	return nil, fmt.Errorf("failed to connect to postgres after %d attempts: %w", maxAttempts, lastErr)
 }
 
+// signedMessage adalah amplop pesan lokasi bertanda tangan. Payload kosong = pesan lokasi biasa
+// tanpa tanda tangan.
+type signedMessage struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"signature"`
+}
+
+// parseLocationTopic membaca tenant dan ID dari topik lokasi:
+// /fleet/{tenant}/vehicle/{id}/location, atau /fleet/vehicle/{id}/location untuk tenant default.
+func parseLocationTopic(topic string) (tenant, id string, ok bool) {
+	parts := strings.Split(topic, "/")
+	switch {
+	case len(parts) == 5 && parts[1] == "fleet" && parts[2] == "vehicle" && parts[4] == "location":
+		return auth.DefaultTenant, parts[3], true
+	case len(parts) == 6 && parts[1] == "fleet" && parts[3] == "vehicle" && parts[5] == "location":
+		return parts[2], parts[4], true
+	}
+	return "", "", false
+}
+
 func main() {
 	cfg := config.Load()
 	ctx := context.Background()
@@ -72,71 +82,72 @@ func main() {
 	gf := geofence.NewGeofence(cfg.GeofenceLat, cfg.GeofenceLon, cfg.GeofenceRadius)
 
 	// --- Postgres dengan retry ---
-	dbpool, err := waitForPostgres(ctx, cfg.PostgresURL, 10, time.Second)
+	poolCfg, err := database.ParseConfig(cfg.PostgresURL, cfg.PostgresTLS)
+	if err != nil {
+		log.Fatalf("failed to parse postgres config: %v", err)
+	}
+	dbpool, err := waitForPostgres(ctx, poolCfg, 10, time.Second)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer dbpool.Close()
 
-	// --- RabbitMQ ---
-	var conn *amqp.Connection
-	for i := 1; i <= 10; i++ {
-		conn, err = amqp.Dial(cfg.RabbitURL)
-		if err == nil {
-			break
+	// Migrasi dijaga advisory lock, aman walau API menjalankannya bersamaan
+	if cfg.MigrateOnStart {
+		if err := migrate.Run(ctx, dbpool); err != nil {
+			log.Fatalf("failed to run migrations: %v", err)
 		}
-		log.Printf("rabbitmq connect try %d failed: %v", i, err)
-		time.Sleep(time.Duration(i) * time.Second)
 	}
-	if err != nil {
-		log.Fatalf("failed to connect to rabbitmq: %v", err)
-	}
-	defer conn.Close()
 
-	ch, err := conn.Channel()
-	if err != nil {
-		log.Fatalf("channel error: %v", err)
+	// --- RabbitMQ ---
+	rabbit := rabbitmq.NewClient(cfg)
+
+	// Insert lokasi, update vehicle_latest, dan event geofence lewat service yang sama dengan API
+	repo := repository.NewLocationRepository(dbpool)
+	vehicleSvc := service.NewVehicleService(repository.NewVehicleRepository(dbpool), cfg.UnregisteredVehiclePolicy)
+	deviceSvc := service.NewDeviceService(repository.NewDeviceRepository(dbpool), vehicleSvc)
+	driverSvc := service.NewDriverService(repository.NewDriverRepository(dbpool), vehicleSvc)
+	svc := service.NewLocationService(repo, nil, nil, vehicleSvc, deviceSvc, driverSvc, repository.NewGeofenceEventRepository(dbpool), gf, rabbit)
+
+	// Segmentasi perjalanan, deteksi berhenti, dan overspeed berjalan inkremental untuk setiap titik baru
+	if cfg.TripDetectionEnabled {
+		svc.AddProcessor(service.NewTripService(repository.NewTripRepository(dbpool), vehicleSvc, driverSvc, rabbit, service.TripConfig{
+			MinSpeedKmh: cfg.TripMinSpeedKmh,
+			StopAfter:   cfg.TripStopAfter,
+			MaxGap:      cfg.TripMaxGap,
+		}))
 	}
-	defer ch.Close()
-
-	if err := ch.ExchangeDeclare(
-		cfg.RabbitExchange,
-		"topic",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	); err != nil {
-		log.Fatalf("exchange declare: %v", err)
+	geofenceSvc := service.NewGeofenceService(repository.NewGeofenceRepository(dbpool))
+	if cfg.StopDetectionEnabled {
+		svc.AddProcessor(service.NewStopService(repository.NewStopRepository(dbpool), vehicleSvc, geofenceSvc, driverSvc, service.StopConfig{
+			MaxSpeedKmh: cfg.StopMaxSpeedKmh,
+			RadiusM:     cfg.StopRadiusM,
+			MinStop:     cfg.StopMinDuration,
+			MinIdle:     cfg.IdleMinDuration,
+		}))
 	}
-
-	q, err := ch.QueueDeclare(
-		cfg.RabbitQueue,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		log.Fatalf("queue declare: %v", err)
+	if cfg.OverspeedDetectionEnabled {
+		svc.AddProcessor(service.NewOverspeedService(repository.NewOverspeedRepository(dbpool), repository.NewSpeedLimitRepository(dbpool),
+			vehicleSvc, geofenceSvc, driverSvc, rabbit, service.OverspeedConfig{
+				LimitKmh: cfg.OverspeedLimitKmh,
+				MaxGap:   cfg.OverspeedMaxGap,
+			}))
 	}
-
-	if err := ch.QueueBind(
-		q.Name,
-		cfg.RabbitRoutingKey,
-		cfg.RabbitExchange,
-		false,
-		nil,
-	); err != nil {
-		log.Fatalf("queue bind: %v", err)
+	if cfg.DistanceTrackingEnabled {
+		tz, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			log.Fatalf("invalid TIMEZONE %q: %v", cfg.Timezone, err)
+		}
+		svc.AddProcessor(service.NewDistanceService(repository.NewDistanceRepository(dbpool), vehicleSvc, tz))
 	}
 
 	// --- MQTT ---
 	opts := mqtt.NewClientOptions().
 		AddBroker(cfg.MQTTBrokerURL).
 		SetClientID("fleet-mqtt-listener")
+	if err := mqttclient.Configure(opts, cfg); err != nil {
+		log.Fatalf("invalid MQTT config: %v", err)
+	}
 
 	client := mqtt.NewClient(opts)
 	if token := client.Connect(); token.Wait() && token.Error() != nil {
@@ -144,70 +155,67 @@ func main() {
 	}
 	log.Println("MQTT listener connected to MQTT broker")
 
-	topic := "/fleet/vehicle/+/location"
+	// Topik per tenant, plus topik lama tanpa tenant yang dipetakan ke tenant default
+	topics := map[string]byte{
+		"/fleet/+/vehicle/+/location": 0,
+		"/fleet/vehicle/+/location":   0,
+	}
 
 	handler := func(c mqtt.Client, m mqtt.Message) {
 		log.Printf("Received on %s: %s", m.Topic(), string(m.Payload()))
 
-		var loc VehicleLocation
-		if err := json.Unmarshal(m.Payload(), &loc); err != nil {
+		tenant, topicID, ok := parseLocationTopic(m.Topic())
+		if !ok {
+			log.Printf("unexpected topic %s", m.Topic())
+			return
+		}
+
+		// Pesan bertanda tangan: {"payload": {...lokasi...}, "signature": "..."}. Tanda tangan
+		// mencakup byte "payload" apa adanya, jadi payload tidak di-encode ulang sebelum diverifikasi.
+		var msg signedMessage
+		if err := json.Unmarshal(m.Payload(), &msg); err != nil {
 			log.Printf("invalid JSON: %v", err)
 			return
 		}
+		raw := m.Payload()
+		if len(msg.Payload) > 0 {
+			raw = msg.Payload
+		}
 
-		// Jika vehicle_id kosong, ambil dari topic: /fleet/vehicle/{id}/location
-		if loc.VehicleID == "" {
-			parts := strings.Split(m.Topic(), "/")
-			if len(parts) >= 4 {
-				loc.VehicleID = parts[3]
-			}
+		var loc models.VehicleLocation
+		if err := json.Unmarshal(raw, &loc); err != nil {
+			log.Printf("invalid JSON: %v", err)
+			return
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+		// Tenant selalu dari topik, bukan dari payload, supaya device tidak bisa menulis ke tenant lain
+		loc.TenantID = tenant
 
-		_, err := dbpool.Exec(ctx,
-			`INSERT INTO vehicle_locations (vehicle_id, latitude, longitude, timestamp)
-             VALUES ($1, $2, $3, $4)`,
-			loc.VehicleID, loc.Latitude, loc.Longitude, loc.Timestamp,
-		)
-		if err != nil {
-			log.Printf("insert failed: %v", err)
+		// ID tracker (device_id, atau vehicle_id bila device_id kosong, atau ID topik bila keduanya
+		// kosong) harus sama dengan ID topik; kendaraannya ditentukan service dari ID itu.
+		switch {
+		case loc.DeviceID != "" && loc.DeviceID != topicID:
+			log.Printf("rejected location on %s: device_id %q does not match topic", m.Topic(), loc.DeviceID)
 			return
+		case loc.DeviceID == "" && loc.VehicleID != "" && loc.VehicleID != topicID:
+			log.Printf("rejected location on %s: vehicle_id %q does not match topic", m.Topic(), loc.VehicleID)
+			return
+		case loc.VehicleID == "" && loc.DeviceID == "":
+			loc.VehicleID = topicID
 		}
 
-		// Pakai geofence.IsInside dari package internal/geofence
-		if gf.IsInside(loc.Latitude, loc.Longitude) {
-			ev := GeofenceEvent{
-				VehicleID: loc.VehicleID,
-				Event:     "geofence_entry",
-				Timestamp: loc.Timestamp,
-			}
-			ev.Location.Latitude = loc.Latitude
-			ev.Location.Longitude = loc.Longitude
-
-			body, _ := json.Marshal(ev)
-			if err := ch.PublishWithContext(ctx,
-				cfg.RabbitExchange,
-				cfg.RabbitRoutingKey,
-				false,
-				false,
-				amqp.Publishing{
-					ContentType: "application/json",
-					Body:        body,
-				},
-			); err != nil {
-				log.Printf("publish geofence event failed: %v", err)
-			} else {
-				log.Printf("Published geofence event for %s", loc.VehicleID)
-			}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := svc.SaveSignedLocation(ctx, loc, msg.Payload, msg.Signature); err != nil {
+			log.Printf("save location for %s failed: %v", loc.VehicleID, err)
 		}
 	}
 
-	if token := client.Subscribe(topic, 0, handler); token.Wait() && token.Error() != nil {
+	if token := client.SubscribeMultiple(topics, handler); token.Wait() && token.Error() != nil {
 		log.Fatalf("mqtt subscribe error: %v", token.Error())
 	}
-	log.Printf("Subscribed to MQTT topic %s", topic)
+	log.Printf("Subscribed to MQTT topics /fleet/+/vehicle/+/location and /fleet/vehicle/+/location")
 
 	select {}
 }
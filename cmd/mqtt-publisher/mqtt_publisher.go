@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"sistem-manajemen-armada/internal/config"
+	mqttclient "sistem-manajemen-armada/internal/mqtt"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
@@ -17,6 +18,9 @@ func main() {
 	opts := mqtt.NewClientOptions().
 		AddBroker(cfg.MQTTBrokerURL).
 		SetClientID("fleet-mock-publisher")
+	if err := mqttclient.Configure(opts, cfg); err != nil {
+		log.Fatalf("invalid MQTT config: %v", err)
+	}
 
 	client := mqtt.NewClient(opts)
 	if token := client.Connect(); token.Wait() && token.Error() != nil {
@@ -24,8 +28,9 @@ func main() {
 	}
 	defer client.Disconnect(250)
 
+	tenant := "default"
 	vehicleID := "B1234XYZ"
-	topic := "/fleet/vehicle/" + vehicleID + "/location"
+	topic := "/fleet/" + tenant + "/vehicle/" + vehicleID + "/location"
 
 	rand.Seed(time.Now().UnixNano())
 	log.Println("Mock publisher started, topic:", topic)
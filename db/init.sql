@@ -1,10 +0,0 @@
-CREATE TABLE IF NOT EXISTS vehicle_locations (
-                                                 id SERIAL PRIMARY KEY,
-                                                 vehicle_id VARCHAR(50) NOT NULL,
-    latitude DOUBLE PRECISION NOT NULL,
-    longitude DOUBLE PRECISION NOT NULL,
-    timestamp BIGINT NOT NULL
-    );
-
-CREATE INDEX IF NOT EXISTS idx_vehicle_time
-    ON vehicle_locations(vehicle_id, timestamp);
\ No newline at end of file
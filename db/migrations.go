@@ -0,0 +1,9 @@
+// Package db menyimpan file migrasi SQL yang di-embed ke dalam binary.
+package db
+
+import "embed"
+
+// Migrations berisi file migrasi dengan format NNNN_nama.up.sql / NNNN_nama.down.sql.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
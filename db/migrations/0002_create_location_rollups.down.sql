@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS rollup_watermarks;
+DROP TABLE IF EXISTS vehicle_location_rollups;
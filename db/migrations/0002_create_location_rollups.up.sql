@@ -0,0 +1,26 @@
+-- Ringkasan lokasi per bucket waktu (resolution dalam detik: 60 = per menit, 3600 = per jam).
+-- last_* menyimpan titik terakhir dalam bucket, dipakai untuk riwayat yang di-downsample.
+CREATE TABLE IF NOT EXISTS vehicle_location_rollups (
+    vehicle_id VARCHAR(50) NOT NULL,
+    resolution INTEGER NOT NULL,
+    bucket_start BIGINT NOT NULL,
+    point_count INTEGER NOT NULL,
+    first_timestamp BIGINT NOT NULL,
+    last_timestamp BIGINT NOT NULL,
+    avg_latitude DOUBLE PRECISION NOT NULL,
+    avg_longitude DOUBLE PRECISION NOT NULL,
+    min_latitude DOUBLE PRECISION NOT NULL,
+    max_latitude DOUBLE PRECISION NOT NULL,
+    min_longitude DOUBLE PRECISION NOT NULL,
+    max_longitude DOUBLE PRECISION NOT NULL,
+    last_location_id BIGINT NOT NULL,
+    last_latitude DOUBLE PRECISION NOT NULL,
+    last_longitude DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (vehicle_id, resolution, bucket_start)
+);
+
+-- Batas atas (eksklusif) bucket yang sudah selesai di-rollup per resolusi.
+CREATE TABLE IF NOT EXISTS rollup_watermarks (
+    resolution INTEGER PRIMARY KEY,
+    completed_until BIGINT NOT NULL
+);
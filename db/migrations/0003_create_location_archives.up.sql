@@ -0,0 +1,18 @@
+-- Manifest file arsip vehicle_locations. Rentang [range_start, range_end) dalam epoch detik.
+CREATE TABLE IF NOT EXISTS location_archives (
+    id SERIAL PRIMARY KEY,
+    range_start BIGINT NOT NULL,
+    range_end BIGINT NOT NULL,
+    object_key TEXT NOT NULL UNIQUE,
+    row_count BIGINT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    vehicle_ids TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_location_archives_range
+    ON location_archives(range_start, range_end);
+
+CREATE INDEX IF NOT EXISTS idx_location_archives_vehicles
+    ON location_archives USING GIN (vehicle_ids);
@@ -0,0 +1,19 @@
+-- Posisi terakhir per kendaraan, diperbarui setiap insert lokasi.
+CREATE TABLE IF NOT EXISTS vehicle_latest (
+    vehicle_id VARCHAR(50) PRIMARY KEY,
+    location_id BIGINT NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    timestamp BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_vehicle_latest_position
+    ON vehicle_latest(latitude, longitude);
+
+-- Isi awal dari data yang sudah ada
+INSERT INTO vehicle_latest (vehicle_id, location_id, latitude, longitude, timestamp)
+SELECT DISTINCT ON (vehicle_id) vehicle_id, id, latitude, longitude, timestamp
+FROM vehicle_locations
+ORDER BY vehicle_id, timestamp DESC, id DESC
+ON CONFLICT (vehicle_id) DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS quarantined_locations;
+DROP TABLE IF EXISTS vehicles;
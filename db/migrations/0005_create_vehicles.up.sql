@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS vehicles (
+    id VARCHAR(50) PRIMARY KEY,
+    plate_number VARCHAR(20) NOT NULL UNIQUE,
+    vin VARCHAR(17) UNIQUE,
+    type VARCHAR(30) NOT NULL DEFAULT '',
+    capacity_kg DOUBLE PRECISION NOT NULL DEFAULT 0,
+    device_imei VARCHAR(20) UNIQUE,
+    group_name VARCHAR(50) NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_vehicles_group
+    ON vehicles(group_name);
+
+-- Lokasi dari kendaraan yang tidak terdaftar / nonaktif, disimpan terpisah untuk ditinjau.
+CREATE TABLE IF NOT EXISTS quarantined_locations (
+    id BIGSERIAL PRIMARY KEY,
+    vehicle_id VARCHAR(50) NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    timestamp BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_quarantined_vehicle_time
+    ON quarantined_locations(vehicle_id, timestamp);
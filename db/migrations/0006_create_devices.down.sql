@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_vehicle_locations_device_time;
+ALTER TABLE vehicle_locations DROP COLUMN IF EXISTS device_id;
+DROP TABLE IF EXISTS device_assignments;
+DROP TABLE IF EXISTS devices;
@@ -0,0 +1,34 @@
+-- btree_gist dibutuhkan untuk exclusion constraint (device_id WITH =)
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- Tracker GPS. id adalah ID yang dikirim device (segmen topik MQTT / field payload).
+CREATE TABLE IF NOT EXISTS devices (
+    id VARCHAR(50) PRIMARY KEY,
+    imei VARCHAR(20) UNIQUE,
+    model VARCHAR(50) NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Pemasangan device ke kendaraan dalam rentang [start_ts, end_ts). end_ts NULL = masih terpasang.
+CREATE TABLE IF NOT EXISTS device_assignments (
+    id BIGSERIAL PRIMARY KEY,
+    device_id VARCHAR(50) NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    vehicle_id VARCHAR(50) NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    start_ts BIGINT NOT NULL,
+    end_ts BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT device_assignments_valid_range CHECK (end_ts IS NULL OR end_ts > start_ts),
+    CONSTRAINT device_assignments_no_overlap
+        EXCLUDE USING gist (device_id WITH =, int8range(start_ts, end_ts) WITH &&)
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_assignments_vehicle
+    ON device_assignments(vehicle_id, start_ts);
+
+-- Device asal setiap titik; kosong untuk data lama / kendaraan tanpa device terdaftar.
+ALTER TABLE vehicle_locations ADD COLUMN IF NOT EXISTS device_id VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_vehicle_locations_device_time
+    ON vehicle_locations(device_id, timestamp) WHERE device_id IS NOT NULL;
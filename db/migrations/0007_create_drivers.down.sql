@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS driver_shifts;
+DROP TABLE IF EXISTS drivers;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS drivers (
+    id VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    license_number VARCHAR(30) NOT NULL UNIQUE,
+    license_expiry DATE NOT NULL,
+    phone VARCHAR(20) NOT NULL DEFAULT '',
+    email VARCHAR(100) NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Shift pengemudi di kendaraan dalam rentang [start_ts, end_ts). end_ts NULL = shift masih berjalan.
+-- Satu kendaraan hanya punya satu pengemudi, dan satu pengemudi hanya di satu kendaraan, pada satu waktu.
+CREATE TABLE IF NOT EXISTS driver_shifts (
+    id BIGSERIAL PRIMARY KEY,
+    driver_id VARCHAR(50) NOT NULL REFERENCES drivers(id) ON DELETE CASCADE,
+    vehicle_id VARCHAR(50) NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    start_ts BIGINT NOT NULL,
+    end_ts BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT driver_shifts_valid_range CHECK (end_ts IS NULL OR end_ts > start_ts),
+    CONSTRAINT driver_shifts_vehicle_no_overlap
+        EXCLUDE USING gist (vehicle_id WITH =, int8range(start_ts, end_ts) WITH &&),
+    CONSTRAINT driver_shifts_driver_no_overlap
+        EXCLUDE USING gist (driver_id WITH =, int8range(start_ts, end_ts) WITH &&)
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS trips;
+ALTER TABLE vehicle_latest
+    DROP COLUMN IF EXISTS ignition,
+    DROP COLUMN IF EXISTS speed;
+ALTER TABLE vehicle_locations
+    DROP COLUMN IF EXISTS ignition,
+    DROP COLUMN IF EXISTS speed;
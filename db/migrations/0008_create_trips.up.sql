@@ -0,0 +1,41 @@
+-- Data opsional dari tracker: kecepatan (km/jam) dan status kunci kontak. NULL = tidak dilaporkan.
+ALTER TABLE vehicle_locations
+    ADD COLUMN IF NOT EXISTS speed DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS ignition BOOLEAN;
+
+ALTER TABLE vehicle_latest
+    ADD COLUMN IF NOT EXISTS speed DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS ignition BOOLEAN;
+
+-- Perjalanan hasil segmentasi lokasi. end_ts NULL = perjalanan masih berlangsung;
+-- last_* menyimpan titik terakhir yang sudah diproses untuk perjalanan yang masih terbuka, stopped_*
+-- titik pertama sejak kendaraan berhenti (NULL bila sedang bergerak).
+CREATE TABLE IF NOT EXISTS trips (
+    id BIGSERIAL PRIMARY KEY,
+    vehicle_id VARCHAR(50) NOT NULL,
+    driver_id VARCHAR(50),
+    start_ts BIGINT NOT NULL,
+    start_latitude DOUBLE PRECISION NOT NULL,
+    start_longitude DOUBLE PRECISION NOT NULL,
+    end_ts BIGINT,
+    end_latitude DOUBLE PRECISION,
+    end_longitude DOUBLE PRECISION,
+    last_ts BIGINT NOT NULL,
+    last_latitude DOUBLE PRECISION NOT NULL,
+    last_longitude DOUBLE PRECISION NOT NULL,
+    stopped_ts BIGINT,
+    stopped_latitude DOUBLE PRECISION,
+    stopped_longitude DOUBLE PRECISION,
+    distance_m DOUBLE PRECISION NOT NULL DEFAULT 0,
+    max_speed_kmh DOUBLE PRECISION NOT NULL DEFAULT 0,
+    point_count INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_trips_vehicle_start
+    ON trips(vehicle_id, start_ts);
+
+-- Paling banyak satu perjalanan terbuka per kendaraan
+CREATE UNIQUE INDEX IF NOT EXISTS idx_trips_vehicle_open
+    ON trips(vehicle_id) WHERE end_ts IS NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS vehicle_stops;
+DROP TABLE IF EXISTS geofences;
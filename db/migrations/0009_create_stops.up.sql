@@ -0,0 +1,33 @@
+-- Area bernama (pelanggan, gudang, pool) untuk mengaitkan lokasi dengan tempat.
+CREATE TABLE IF NOT EXISTS geofences (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    radius_m DOUBLE PRECISION NOT NULL CHECK (radius_m > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Periode kendaraan diam. type 'idle' = kunci kontak on, 'stop' = kunci kontak off / tidak dilaporkan.
+-- end_ts NULL = kendaraan masih diam; last_ts adalah titik diam terakhir yang sudah diproses.
+CREATE TABLE IF NOT EXISTS vehicle_stops (
+    id BIGSERIAL PRIMARY KEY,
+    vehicle_id VARCHAR(50) NOT NULL,
+    driver_id VARCHAR(50),
+    type VARCHAR(10) NOT NULL CHECK (type IN ('stop', 'idle')),
+    start_ts BIGINT NOT NULL,
+    end_ts BIGINT,
+    last_ts BIGINT NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    geofence_id BIGINT REFERENCES geofences(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_vehicle_stops_vehicle_start
+    ON vehicle_stops(vehicle_id, start_ts);
+
+-- Paling banyak satu periode diam terbuka per kendaraan
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vehicle_stops_open
+    ON vehicle_stops(vehicle_id) WHERE end_ts IS NULL;
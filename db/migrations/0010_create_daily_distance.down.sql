@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS vehicle_daily_distance;
+ALTER TABLE vehicle_latest DROP COLUMN IF EXISTS odometer;
+ALTER TABLE vehicle_locations DROP COLUMN IF EXISTS odometer;
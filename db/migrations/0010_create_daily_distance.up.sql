@@ -0,0 +1,21 @@
+-- Odometer (km) opsional dari tracker. NULL = tidak dilaporkan.
+ALTER TABLE vehicle_locations ADD COLUMN IF NOT EXISTS odometer DOUBLE PRECISION;
+ALTER TABLE vehicle_latest ADD COLUMN IF NOT EXISTS odometer DOUBLE PRECISION;
+
+-- Jarak tempuh per kendaraan per hari (zona waktu laporan), diakumulasi saat ingest.
+-- odometer_start adalah bacaan terakhir sebelum / pertama di hari tersebut, odometer_end bacaan terakhir.
+CREATE TABLE IF NOT EXISTS vehicle_daily_distance (
+    vehicle_id VARCHAR(50) NOT NULL,
+    day DATE NOT NULL,
+    gps_distance_m DOUBLE PRECISION NOT NULL DEFAULT 0,
+    point_count INT NOT NULL DEFAULT 0,
+    rejected_segments INT NOT NULL DEFAULT 0,
+    odometer_start DOUBLE PRECISION,
+    odometer_end DOUBLE PRECISION,
+    first_ts BIGINT NOT NULL,
+    last_ts BIGINT NOT NULL,
+    PRIMARY KEY (vehicle_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_vehicle_daily_distance_day
+    ON vehicle_daily_distance(day);
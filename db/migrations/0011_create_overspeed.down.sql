@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS overspeed_events;
+DROP TABLE IF EXISTS vehicle_type_speed_limits;
+ALTER TABLE geofences DROP COLUMN IF EXISTS speed_limit_kmh;
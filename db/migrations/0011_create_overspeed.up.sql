@@ -0,0 +1,39 @@
+-- Batas kecepatan zona: berlaku untuk kendaraan di dalam geofence. NULL = tidak ada batas khusus.
+ALTER TABLE geofences ADD COLUMN IF NOT EXISTS speed_limit_kmh DOUBLE PRECISION CHECK (speed_limit_kmh > 0);
+
+-- Batas kecepatan per jenis kendaraan (vehicles.type).
+CREATE TABLE IF NOT EXISTS vehicle_type_speed_limits (
+    vehicle_type VARCHAR(30) PRIMARY KEY,
+    limit_kmh DOUBLE PRECISION NOT NULL CHECK (limit_kmh > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Periode kendaraan melebihi batas kecepatan. end_ts NULL = masih melebihi batas;
+-- last_ts adalah titik terakhir yang sudah diproses.
+CREATE TABLE IF NOT EXISTS overspeed_events (
+    id BIGSERIAL PRIMARY KEY,
+    vehicle_id VARCHAR(50) NOT NULL,
+    driver_id VARCHAR(50),
+    start_ts BIGINT NOT NULL,
+    end_ts BIGINT,
+    last_ts BIGINT NOT NULL,
+    limit_kmh DOUBLE PRECISION NOT NULL,
+    peak_speed_kmh DOUBLE PRECISION NOT NULL,
+    peak_ts BIGINT NOT NULL,
+    start_latitude DOUBLE PRECISION NOT NULL,
+    start_longitude DOUBLE PRECISION NOT NULL,
+    peak_latitude DOUBLE PRECISION NOT NULL,
+    peak_longitude DOUBLE PRECISION NOT NULL,
+    geofence_id BIGINT REFERENCES geofences(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_overspeed_events_vehicle_start
+    ON overspeed_events(vehicle_id, start_ts);
+
+CREATE INDEX IF NOT EXISTS idx_overspeed_events_start
+    ON overspeed_events(start_ts);
+
+-- Paling banyak satu periode overspeed terbuka per kendaraan
+CREATE UNIQUE INDEX IF NOT EXISTS idx_overspeed_events_open
+    ON overspeed_events(vehicle_id) WHERE end_ts IS NULL;
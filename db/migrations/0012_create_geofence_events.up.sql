@@ -0,0 +1,17 @@
+-- Log event geofence yang juga dipublish ke RabbitMQ, untuk laporan / ekspor.
+CREATE TABLE IF NOT EXISTS geofence_events (
+    id BIGSERIAL PRIMARY KEY,
+    vehicle_id VARCHAR(50) NOT NULL,
+    driver_id VARCHAR(50),
+    event VARCHAR(30) NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    timestamp BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_geofence_events_time
+    ON geofence_events(timestamp);
+
+CREATE INDEX IF NOT EXISTS idx_geofence_events_vehicle_time
+    ON geofence_events(vehicle_id, timestamp);
@@ -0,0 +1,2 @@
+-- Grup kendaraan yang boleh dilihat API key. NULL = semua grup.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS groups TEXT[];
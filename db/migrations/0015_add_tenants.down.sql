@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS idx_api_keys_tenant;
+DROP INDEX IF EXISTS idx_geofence_events_tenant_time;
+DROP INDEX IF EXISTS idx_geofences_tenant;
+DROP INDEX IF EXISTS idx_vehicle_latest_tenant;
+DROP INDEX IF EXISTS idx_vehicles_tenant;
+
+DELETE FROM vehicle_type_speed_limits WHERE tenant_id <> 'default';
+ALTER TABLE vehicle_type_speed_limits DROP CONSTRAINT IF EXISTS vehicle_type_speed_limits_pkey;
+ALTER TABLE vehicle_type_speed_limits ADD PRIMARY KEY (vehicle_type);
+ALTER TABLE vehicle_type_speed_limits DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE geofence_events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE geofences DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE quarantined_locations DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE vehicle_latest DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE vehicle_locations DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,21 @@
+-- Tenant (perusahaan klien) pemilik data. Data yang sudah ada masuk tenant 'default'.
+-- Pada migrasi ini ID kendaraan masih unik global, sehingga tenant cukup dicatat di kendaraan dan
+-- titik lokasinya. Sejak 0021 ID kendaraan unik per tenant.
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE vehicle_locations ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE quarantined_locations ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE vehicle_latest ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE geofences ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE geofence_events ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+
+-- Batas kecepatan per jenis kendaraan diatur masing-masing tenant
+ALTER TABLE vehicle_type_speed_limits ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE vehicle_type_speed_limits DROP CONSTRAINT IF EXISTS vehicle_type_speed_limits_pkey;
+ALTER TABLE vehicle_type_speed_limits ADD PRIMARY KEY (tenant_id, vehicle_type);
+
+CREATE INDEX IF NOT EXISTS idx_vehicles_tenant ON vehicles(tenant_id, group_name);
+CREATE INDEX IF NOT EXISTS idx_vehicle_latest_tenant ON vehicle_latest(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_geofences_tenant ON geofences(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_geofence_events_tenant_time ON geofence_events(tenant_id, timestamp);
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant ON api_keys(tenant_id);
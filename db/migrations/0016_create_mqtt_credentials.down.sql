@@ -0,0 +1 @@
+DROP TABLE IF EXISTS mqtt_credentials;
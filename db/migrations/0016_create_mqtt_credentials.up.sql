@@ -0,0 +1,16 @@
+-- Kredensial MQTT per tracker. Username adalah ID di topik /fleet/{tenant}/vehicle/{id}/...;
+-- hanya hash SHA-256 dari password yang disimpan.
+CREATE TABLE IF NOT EXISTS mqtt_credentials (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(50) NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    password_hash BYTEA NOT NULL,
+    created_by VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ
+);
+
+-- Satu kredensial aktif per username; rotasi = cabut lalu terbitkan ulang
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mqtt_credentials_active
+    ON mqtt_credentials(username) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_mqtt_credentials_tenant ON mqtt_credentials(tenant_id);
@@ -0,0 +1,3 @@
+ALTER TABLE quarantined_locations DROP COLUMN IF EXISTS device_id;
+ALTER TABLE devices DROP COLUMN IF EXISTS signing_key;
+ALTER TABLE devices DROP COLUMN IF EXISTS signing_algorithm;
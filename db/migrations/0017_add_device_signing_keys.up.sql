@@ -0,0 +1,7 @@
+-- Kunci tanda tangan payload per device. signing_key berisi secret HMAC-SHA256 atau public key
+-- Ed25519 (32 byte); NULL = payload device tidak wajib ditandatangani.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS signing_algorithm VARCHAR(20);
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS signing_key BYTEA;
+
+-- Titik yang dikarantina karena tanda tangan bisa ditelusuri ke device pengirimnya
+ALTER TABLE quarantined_locations ADD COLUMN IF NOT EXISTS device_id VARCHAR(50);
@@ -0,0 +1,48 @@
+-- Aturan notifikasi per tenant: event yang cocok dengan semua kriteria memicu actions.
+-- Kriteria array kosong / NULL = tidak dibatasi. time_start / time_end "HH:MM" di zona timezone.
+CREATE TABLE IF NOT EXISTS notification_rules (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    geofence_ids BIGINT[] NOT NULL DEFAULT '{}',
+    vehicle_groups TEXT[] NOT NULL DEFAULT '{}',
+    time_start VARCHAR(5) NOT NULL DEFAULT '',
+    time_end VARCHAR(5) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT '',
+    min_speed_kmh DOUBLE PRECISION,
+    min_duration_s BIGINT,
+    cooldown_s BIGINT NOT NULL DEFAULT 0,
+    actions JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_rules_tenant ON notification_rules(tenant_id);
+
+-- Antrean sekaligus log notifikasi: satu baris per penerima per event yang cocok.
+-- status: pending, succeeded, failed.
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id BIGINT NOT NULL REFERENCES notification_rules(id) ON DELETE CASCADE,
+    tenant_id VARCHAR(50) NOT NULL,
+    vehicle_id VARCHAR(50) NOT NULL DEFAULT '',
+    event_type VARCHAR(50) NOT NULL,
+    action JSONB NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    message TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_due
+    ON notifications(next_attempt_at) WHERE status = 'pending';
+-- log per aturan dan pemeriksaan cooldown per kendaraan
+CREATE INDEX IF NOT EXISTS idx_notifications_rule
+    ON notifications(rule_id, vehicle_id, created_at DESC);
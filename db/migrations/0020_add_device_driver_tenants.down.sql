@@ -0,0 +1,66 @@
+DROP INDEX IF EXISTS idx_vehicle_locations_device_time;
+CREATE INDEX IF NOT EXISTS idx_vehicle_locations_device_time
+    ON vehicle_locations(device_id, timestamp) WHERE device_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_device_assignments_vehicle;
+CREATE INDEX IF NOT EXISTS idx_device_assignments_vehicle
+    ON device_assignments(vehicle_id, start_ts);
+
+ALTER TABLE device_assignments
+    DROP CONSTRAINT IF EXISTS device_assignments_device_id_fkey,
+    DROP CONSTRAINT IF EXISTS device_assignments_vehicle_id_fkey,
+    DROP CONSTRAINT IF EXISTS device_assignments_no_overlap;
+ALTER TABLE driver_shifts
+    DROP CONSTRAINT IF EXISTS driver_shifts_driver_id_fkey,
+    DROP CONSTRAINT IF EXISTS driver_shifts_vehicle_id_fkey,
+    DROP CONSTRAINT IF EXISTS driver_shifts_vehicle_no_overlap,
+    DROP CONSTRAINT IF EXISTS driver_shifts_driver_no_overlap;
+
+-- ID yang sama di beberapa tenant: hanya milik tenant pertama (urut nama) yang dipertahankan
+DELETE FROM devices d USING devices o WHERE o.id = d.id AND o.tenant_id < d.tenant_id;
+DELETE FROM devices d USING devices o WHERE o.imei = d.imei AND o.tenant_id < d.tenant_id;
+DELETE FROM drivers d USING drivers o WHERE o.id = d.id AND o.tenant_id < d.tenant_id;
+DELETE FROM drivers d USING drivers o WHERE o.license_number = d.license_number AND o.tenant_id < d.tenant_id;
+DELETE FROM device_assignments a WHERE NOT EXISTS (
+    SELECT 1 FROM devices d WHERE d.id = a.device_id AND d.tenant_id = a.tenant_id);
+DELETE FROM driver_shifts s WHERE NOT EXISTS (
+    SELECT 1 FROM drivers d WHERE d.id = s.driver_id AND d.tenant_id = s.tenant_id);
+
+ALTER TABLE devices
+    DROP CONSTRAINT IF EXISTS devices_pkey,
+    DROP CONSTRAINT IF EXISTS devices_imei_key;
+ALTER TABLE devices
+    ADD PRIMARY KEY (id),
+    ADD CONSTRAINT devices_imei_key UNIQUE (imei);
+
+ALTER TABLE drivers
+    DROP CONSTRAINT IF EXISTS drivers_pkey,
+    DROP CONSTRAINT IF EXISTS drivers_license_number_key;
+ALTER TABLE drivers
+    ADD PRIMARY KEY (id),
+    ADD CONSTRAINT drivers_license_number_key UNIQUE (license_number);
+
+ALTER TABLE device_assignments
+    ADD CONSTRAINT device_assignments_device_id_fkey
+        FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE,
+    ADD CONSTRAINT device_assignments_vehicle_id_fkey
+        FOREIGN KEY (vehicle_id) REFERENCES vehicles(id) ON DELETE CASCADE,
+    ADD CONSTRAINT device_assignments_no_overlap
+        EXCLUDE USING gist (device_id WITH =, int8range(start_ts, end_ts) WITH &&);
+
+ALTER TABLE driver_shifts
+    ADD CONSTRAINT driver_shifts_driver_id_fkey
+        FOREIGN KEY (driver_id) REFERENCES drivers(id) ON DELETE CASCADE,
+    ADD CONSTRAINT driver_shifts_vehicle_id_fkey
+        FOREIGN KEY (vehicle_id) REFERENCES vehicles(id) ON DELETE CASCADE,
+    ADD CONSTRAINT driver_shifts_vehicle_no_overlap
+        EXCLUDE USING gist (vehicle_id WITH =, int8range(start_ts, end_ts) WITH &&),
+    ADD CONSTRAINT driver_shifts_driver_no_overlap
+        EXCLUDE USING gist (driver_id WITH =, int8range(start_ts, end_ts) WITH &&);
+
+ALTER TABLE vehicles DROP CONSTRAINT IF EXISTS vehicles_tenant_id_id_key;
+
+ALTER TABLE driver_shifts DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE drivers DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE device_assignments DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE devices DROP COLUMN IF EXISTS tenant_id;
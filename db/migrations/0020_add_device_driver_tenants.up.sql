@@ -0,0 +1,82 @@
+-- Device, pengemudi, pemasangan device, dan shift dimiliki tenant. ID device dan pengemudi unik per
+-- tenant; pemasangan dan shift hanya boleh menunjuk kendaraan di tenant yang sama.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE device_assignments ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE drivers ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE driver_shifts ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+
+-- Data lama: device / pengemudi ikut tenant kendaraan pertama tempat ia dipasang / bertugas
+UPDATE devices d SET tenant_id = first.tenant_id
+FROM (
+    SELECT DISTINCT ON (a.device_id) a.device_id, v.tenant_id
+    FROM device_assignments a JOIN vehicles v ON v.id = a.vehicle_id
+    ORDER BY a.device_id, a.start_ts
+) first
+WHERE first.device_id = d.id;
+
+UPDATE drivers d SET tenant_id = first.tenant_id
+FROM (
+    SELECT DISTINCT ON (s.driver_id) s.driver_id, v.tenant_id
+    FROM driver_shifts s JOIN vehicles v ON v.id = s.vehicle_id
+    ORDER BY s.driver_id, s.start_ts
+) first
+WHERE first.driver_id = d.id;
+
+UPDATE device_assignments a SET tenant_id = d.tenant_id FROM devices d WHERE d.id = a.device_id;
+UPDATE driver_shifts s SET tenant_id = d.tenant_id FROM drivers d WHERE d.id = s.driver_id;
+
+-- Pemasangan / shift ke kendaraan tenant lain tidak sah dan dibuang
+DELETE FROM device_assignments a USING vehicles v WHERE v.id = a.vehicle_id AND v.tenant_id <> a.tenant_id;
+DELETE FROM driver_shifts s USING vehicles v WHERE v.id = s.vehicle_id AND v.tenant_id <> s.tenant_id;
+
+ALTER TABLE vehicles ADD CONSTRAINT vehicles_tenant_id_id_key UNIQUE (tenant_id, id);
+
+ALTER TABLE device_assignments
+    DROP CONSTRAINT IF EXISTS device_assignments_device_id_fkey,
+    DROP CONSTRAINT IF EXISTS device_assignments_vehicle_id_fkey,
+    DROP CONSTRAINT IF EXISTS device_assignments_no_overlap;
+ALTER TABLE driver_shifts
+    DROP CONSTRAINT IF EXISTS driver_shifts_driver_id_fkey,
+    DROP CONSTRAINT IF EXISTS driver_shifts_vehicle_id_fkey,
+    DROP CONSTRAINT IF EXISTS driver_shifts_vehicle_no_overlap,
+    DROP CONSTRAINT IF EXISTS driver_shifts_driver_no_overlap;
+
+ALTER TABLE devices
+    DROP CONSTRAINT IF EXISTS devices_pkey,
+    DROP CONSTRAINT IF EXISTS devices_imei_key;
+ALTER TABLE devices
+    ADD PRIMARY KEY (tenant_id, id),
+    ADD CONSTRAINT devices_imei_key UNIQUE (tenant_id, imei);
+
+ALTER TABLE drivers
+    DROP CONSTRAINT IF EXISTS drivers_pkey,
+    DROP CONSTRAINT IF EXISTS drivers_license_number_key;
+ALTER TABLE drivers
+    ADD PRIMARY KEY (tenant_id, id),
+    ADD CONSTRAINT drivers_license_number_key UNIQUE (tenant_id, license_number);
+
+ALTER TABLE device_assignments
+    ADD CONSTRAINT device_assignments_device_id_fkey
+        FOREIGN KEY (tenant_id, device_id) REFERENCES devices(tenant_id, id) ON DELETE CASCADE,
+    ADD CONSTRAINT device_assignments_vehicle_id_fkey
+        FOREIGN KEY (tenant_id, vehicle_id) REFERENCES vehicles(tenant_id, id) ON DELETE CASCADE,
+    ADD CONSTRAINT device_assignments_no_overlap
+        EXCLUDE USING gist (tenant_id WITH =, device_id WITH =, int8range(start_ts, end_ts) WITH &&);
+
+ALTER TABLE driver_shifts
+    ADD CONSTRAINT driver_shifts_driver_id_fkey
+        FOREIGN KEY (tenant_id, driver_id) REFERENCES drivers(tenant_id, id) ON DELETE CASCADE,
+    ADD CONSTRAINT driver_shifts_vehicle_id_fkey
+        FOREIGN KEY (tenant_id, vehicle_id) REFERENCES vehicles(tenant_id, id) ON DELETE CASCADE,
+    ADD CONSTRAINT driver_shifts_vehicle_no_overlap
+        EXCLUDE USING gist (tenant_id WITH =, vehicle_id WITH =, int8range(start_ts, end_ts) WITH &&),
+    ADD CONSTRAINT driver_shifts_driver_no_overlap
+        EXCLUDE USING gist (tenant_id WITH =, driver_id WITH =, int8range(start_ts, end_ts) WITH &&);
+
+DROP INDEX IF EXISTS idx_device_assignments_vehicle;
+CREATE INDEX IF NOT EXISTS idx_device_assignments_vehicle
+    ON device_assignments(tenant_id, vehicle_id, start_ts);
+
+DROP INDEX IF EXISTS idx_vehicle_locations_device_time;
+CREATE INDEX IF NOT EXISTS idx_vehicle_locations_device_time
+    ON vehicle_locations(tenant_id, device_id, timestamp) WHERE device_id IS NOT NULL;
@@ -0,0 +1,68 @@
+DROP INDEX IF EXISTS idx_overspeed_events_open;
+DROP INDEX IF EXISTS idx_overspeed_events_vehicle_start;
+DROP INDEX IF EXISTS idx_vehicle_stops_open;
+DROP INDEX IF EXISTS idx_vehicle_stops_vehicle_start;
+DROP INDEX IF EXISTS idx_trips_vehicle_open;
+DROP INDEX IF EXISTS idx_trips_vehicle_start;
+
+-- ID yang sama di beberapa tenant: hanya milik tenant pertama (urut nama) yang dipertahankan
+DELETE FROM vehicles d USING vehicles o WHERE o.id = d.id AND o.tenant_id < d.tenant_id;
+DELETE FROM vehicles d USING vehicles o WHERE o.plate_number = d.plate_number AND o.tenant_id < d.tenant_id;
+DELETE FROM vehicles d USING vehicles o WHERE o.vin = d.vin AND o.tenant_id < d.tenant_id;
+DELETE FROM vehicles d USING vehicles o WHERE o.device_imei = d.device_imei AND o.tenant_id < d.tenant_id;
+DELETE FROM vehicle_latest d USING vehicle_latest o WHERE o.vehicle_id = d.vehicle_id AND o.tenant_id < d.tenant_id;
+DELETE FROM vehicle_daily_distance d USING vehicle_daily_distance o
+    WHERE o.vehicle_id = d.vehicle_id AND o.day = d.day AND o.tenant_id < d.tenant_id;
+DELETE FROM trips d USING trips o
+    WHERE o.vehicle_id = d.vehicle_id AND o.end_ts IS NULL AND d.end_ts IS NULL AND o.tenant_id < d.tenant_id;
+DELETE FROM vehicle_stops d USING vehicle_stops o
+    WHERE o.vehicle_id = d.vehicle_id AND o.end_ts IS NULL AND d.end_ts IS NULL AND o.tenant_id < d.tenant_id;
+DELETE FROM overspeed_events d USING overspeed_events o
+    WHERE o.vehicle_id = d.vehicle_id AND o.end_ts IS NULL AND d.end_ts IS NULL AND o.tenant_id < d.tenant_id;
+
+CREATE INDEX IF NOT EXISTS idx_trips_vehicle_start
+    ON trips(vehicle_id, start_ts);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_trips_vehicle_open
+    ON trips(vehicle_id) WHERE end_ts IS NULL;
+CREATE INDEX IF NOT EXISTS idx_vehicle_stops_vehicle_start
+    ON vehicle_stops(vehicle_id, start_ts);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vehicle_stops_open
+    ON vehicle_stops(vehicle_id) WHERE end_ts IS NULL;
+CREATE INDEX IF NOT EXISTS idx_overspeed_events_vehicle_start
+    ON overspeed_events(vehicle_id, start_ts);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_overspeed_events_open
+    ON overspeed_events(vehicle_id) WHERE end_ts IS NULL;
+
+ALTER TABLE vehicle_daily_distance DROP CONSTRAINT IF EXISTS vehicle_daily_distance_pkey;
+ALTER TABLE vehicle_daily_distance ADD PRIMARY KEY (vehicle_id, day);
+
+ALTER TABLE vehicle_latest DROP CONSTRAINT IF EXISTS vehicle_latest_pkey;
+ALTER TABLE vehicle_latest ADD PRIMARY KEY (vehicle_id);
+CREATE INDEX IF NOT EXISTS idx_vehicle_latest_tenant ON vehicle_latest(tenant_id);
+
+ALTER TABLE device_assignments DROP CONSTRAINT IF EXISTS device_assignments_vehicle_id_fkey;
+ALTER TABLE driver_shifts DROP CONSTRAINT IF EXISTS driver_shifts_vehicle_id_fkey;
+
+ALTER TABLE vehicles
+    DROP CONSTRAINT IF EXISTS vehicles_pkey,
+    DROP CONSTRAINT IF EXISTS vehicles_plate_number_key,
+    DROP CONSTRAINT IF EXISTS vehicles_vin_key,
+    DROP CONSTRAINT IF EXISTS vehicles_device_imei_key;
+ALTER TABLE vehicles
+    ADD PRIMARY KEY (id),
+    ADD CONSTRAINT vehicles_plate_number_key UNIQUE (plate_number),
+    ADD CONSTRAINT vehicles_vin_key UNIQUE (vin),
+    ADD CONSTRAINT vehicles_device_imei_key UNIQUE (device_imei),
+    ADD CONSTRAINT vehicles_tenant_id_id_key UNIQUE (tenant_id, id);
+
+ALTER TABLE device_assignments
+    ADD CONSTRAINT device_assignments_vehicle_id_fkey
+        FOREIGN KEY (tenant_id, vehicle_id) REFERENCES vehicles(tenant_id, id) ON DELETE CASCADE;
+ALTER TABLE driver_shifts
+    ADD CONSTRAINT driver_shifts_vehicle_id_fkey
+        FOREIGN KEY (tenant_id, vehicle_id) REFERENCES vehicles(tenant_id, id) ON DELETE CASCADE;
+
+ALTER TABLE vehicle_daily_distance DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE overspeed_events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE vehicle_stops DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE trips DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,77 @@
+-- ID, nomor polisi, VIN, dan IMEI kendaraan unik per tenant, sehingga dua tenant boleh memakai ID
+-- yang sama. Menggantikan keunikan global yang diasumsikan 0015. Posisi terakhir dan data turunan (perjalanan, berhenti, overspeed, jarak harian) ikut
+-- dikunci per (tenant_id, vehicle_id).
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE vehicle_stops ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE overspeed_events ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE vehicle_daily_distance ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+
+-- Data lama: tenant dari registry, atau dari posisi terakhir untuk kendaraan yang belum terdaftar.
+-- ID kendaraan masih unik global pada titik ini, jadi pencocokan per vehicle_id aman.
+UPDATE trips t SET tenant_id = COALESCE(
+    (SELECT tenant_id FROM vehicles WHERE id = t.vehicle_id),
+    (SELECT tenant_id FROM vehicle_latest WHERE vehicle_id = t.vehicle_id),
+    t.tenant_id);
+UPDATE vehicle_stops s SET tenant_id = COALESCE(
+    (SELECT tenant_id FROM vehicles WHERE id = s.vehicle_id),
+    (SELECT tenant_id FROM vehicle_latest WHERE vehicle_id = s.vehicle_id),
+    s.tenant_id);
+UPDATE overspeed_events o SET tenant_id = COALESCE(
+    (SELECT tenant_id FROM vehicles WHERE id = o.vehicle_id),
+    (SELECT tenant_id FROM vehicle_latest WHERE vehicle_id = o.vehicle_id),
+    o.tenant_id);
+UPDATE vehicle_daily_distance d SET tenant_id = COALESCE(
+    (SELECT tenant_id FROM vehicles WHERE id = d.vehicle_id),
+    (SELECT tenant_id FROM vehicle_latest WHERE vehicle_id = d.vehicle_id),
+    d.tenant_id);
+
+-- FK komposit dari 0020 bergantung pada vehicles_tenant_id_id_key; diganti primary key yang baru
+ALTER TABLE device_assignments DROP CONSTRAINT IF EXISTS device_assignments_vehicle_id_fkey;
+ALTER TABLE driver_shifts DROP CONSTRAINT IF EXISTS driver_shifts_vehicle_id_fkey;
+
+ALTER TABLE vehicles
+    DROP CONSTRAINT IF EXISTS vehicles_tenant_id_id_key,
+    DROP CONSTRAINT IF EXISTS vehicles_pkey,
+    DROP CONSTRAINT IF EXISTS vehicles_plate_number_key,
+    DROP CONSTRAINT IF EXISTS vehicles_vin_key,
+    DROP CONSTRAINT IF EXISTS vehicles_device_imei_key;
+ALTER TABLE vehicles
+    ADD PRIMARY KEY (tenant_id, id),
+    ADD CONSTRAINT vehicles_plate_number_key UNIQUE (tenant_id, plate_number),
+    ADD CONSTRAINT vehicles_vin_key UNIQUE (tenant_id, vin),
+    ADD CONSTRAINT vehicles_device_imei_key UNIQUE (tenant_id, device_imei);
+
+ALTER TABLE device_assignments
+    ADD CONSTRAINT device_assignments_vehicle_id_fkey
+        FOREIGN KEY (tenant_id, vehicle_id) REFERENCES vehicles(tenant_id, id) ON DELETE CASCADE;
+ALTER TABLE driver_shifts
+    ADD CONSTRAINT driver_shifts_vehicle_id_fkey
+        FOREIGN KEY (tenant_id, vehicle_id) REFERENCES vehicles(tenant_id, id) ON DELETE CASCADE;
+
+ALTER TABLE vehicle_latest DROP CONSTRAINT IF EXISTS vehicle_latest_pkey;
+ALTER TABLE vehicle_latest ADD PRIMARY KEY (tenant_id, vehicle_id);
+DROP INDEX IF EXISTS idx_vehicle_latest_tenant;
+
+ALTER TABLE vehicle_daily_distance DROP CONSTRAINT IF EXISTS vehicle_daily_distance_pkey;
+ALTER TABLE vehicle_daily_distance ADD PRIMARY KEY (tenant_id, vehicle_id, day);
+
+DROP INDEX IF EXISTS idx_trips_vehicle_start;
+DROP INDEX IF EXISTS idx_trips_vehicle_open;
+CREATE INDEX IF NOT EXISTS idx_trips_vehicle_start
+    ON trips(tenant_id, vehicle_id, start_ts);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_trips_vehicle_open
+    ON trips(tenant_id, vehicle_id) WHERE end_ts IS NULL;
+
+DROP INDEX IF EXISTS idx_vehicle_stops_vehicle_start;
+DROP INDEX IF EXISTS idx_vehicle_stops_open;
+CREATE INDEX IF NOT EXISTS idx_vehicle_stops_vehicle_start
+    ON vehicle_stops(tenant_id, vehicle_id, start_ts);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vehicle_stops_open
+    ON vehicle_stops(tenant_id, vehicle_id) WHERE end_ts IS NULL;
+
+DROP INDEX IF EXISTS idx_overspeed_events_vehicle_start;
+DROP INDEX IF EXISTS idx_overspeed_events_open;
+CREATE INDEX IF NOT EXISTS idx_overspeed_events_vehicle_start
+    ON overspeed_events(tenant_id, vehicle_id, start_ts);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_overspeed_events_open
+    ON overspeed_events(tenant_id, vehicle_id) WHERE end_ts IS NULL;
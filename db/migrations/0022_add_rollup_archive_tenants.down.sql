@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_location_archives_tenant_vehicles;
+ALTER TABLE location_archives DROP COLUMN IF EXISTS tenant_vehicle_ids;
+
+-- ID yang sama di beberapa tenant: hanya milik tenant pertama (urut nama) yang dipertahankan
+DELETE FROM vehicle_location_rollups d USING vehicle_location_rollups o
+    WHERE o.vehicle_id = d.vehicle_id AND o.resolution = d.resolution AND o.bucket_start = d.bucket_start
+        AND o.tenant_id < d.tenant_id;
+
+ALTER TABLE vehicle_location_rollups DROP CONSTRAINT IF EXISTS vehicle_location_rollups_pkey;
+ALTER TABLE vehicle_location_rollups ADD PRIMARY KEY (vehicle_id, resolution, bucket_start);
+ALTER TABLE vehicle_location_rollups DROP COLUMN IF EXISTS tenant_id;
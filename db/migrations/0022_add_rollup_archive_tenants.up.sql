@@ -0,0 +1,30 @@
+-- Rollup dan manifest arsip dipisah per tenant, karena ID kendaraan hanya unik per tenant (0021).
+ALTER TABLE vehicle_location_rollups ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+
+-- Data lama: tenant dari titik terakhir bucket, atau dari kendaraan bila titiknya sudah diarsipkan
+UPDATE vehicle_location_rollups r SET tenant_id = l.tenant_id
+FROM vehicle_locations l
+WHERE l.id = r.last_location_id;
+UPDATE vehicle_location_rollups r SET tenant_id = COALESCE(
+    (SELECT MIN(tenant_id) FROM vehicles WHERE id = r.vehicle_id),
+    (SELECT MIN(tenant_id) FROM vehicle_latest WHERE vehicle_id = r.vehicle_id),
+    r.tenant_id)
+WHERE NOT EXISTS (SELECT 1 FROM vehicle_locations l WHERE l.id = r.last_location_id);
+
+ALTER TABLE vehicle_location_rollups DROP CONSTRAINT IF EXISTS vehicle_location_rollups_pkey;
+ALTER TABLE vehicle_location_rollups ADD PRIMARY KEY (tenant_id, vehicle_id, resolution, bucket_start);
+
+-- Pasangan "{tenant}/{vehicle_id}" yang ada di file arsip. File arsip lama tidak punya kolom
+-- tenant_id; tenant barisnya diambil dari kendaraan saat migrasi ini.
+ALTER TABLE location_archives ADD COLUMN IF NOT EXISTS tenant_vehicle_ids TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE location_archives a SET tenant_vehicle_ids = ARRAY(
+    SELECT COALESCE(
+        (SELECT MIN(tenant_id) FROM vehicles WHERE id = ids.vehicle_id),
+        (SELECT MIN(tenant_id) FROM vehicle_latest WHERE vehicle_id = ids.vehicle_id),
+        'default') || '/' || ids.vehicle_id
+    FROM unnest(a.vehicle_ids) AS ids(vehicle_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_location_archives_tenant_vehicles
+    ON location_archives USING GIN (tenant_vehicle_ids);
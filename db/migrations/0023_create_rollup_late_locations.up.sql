@@ -0,0 +1,9 @@
+-- Titik yang masuk ke bucket yang sudah di-rollup (timestamp < completed_until resolusinya),
+-- dicatat saat insert. RollupService menggabungkannya ke rollup yang sudah ada pada putaran
+-- berikutnya, lalu menghapus barisnya.
+CREATE TABLE IF NOT EXISTS rollup_late_locations (
+    resolution INTEGER NOT NULL,
+    location_id BIGINT NOT NULL,
+    bucket_start BIGINT NOT NULL,
+    PRIMARY KEY (resolution, location_id)
+);
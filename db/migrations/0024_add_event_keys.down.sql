@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_notifications_event;
+ALTER TABLE notifications DROP COLUMN IF EXISTS event_seq;
+ALTER TABLE notifications DROP COLUMN IF EXISTS event_key;
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_event;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS event_key;
@@ -0,0 +1,11 @@
+-- Kunci event sumber (hash payload RabbitMQ). Pesan yang dikirim ulang broker setelah Nack atau
+-- koneksi putus tidak menjadwalkan pengiriman / notifikasi dua kali. NULL = redelivery manual.
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS event_key VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event
+    ON webhook_deliveries(webhook_id, event_key) WHERE event_key IS NOT NULL;
+
+-- event_seq membedakan beberapa notifikasi (aksi / penerima) satu aturan untuk event yang sama
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS event_key VARCHAR(64);
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS event_seq INT NOT NULL DEFAULT 0;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_event
+    ON notifications(rule_id, event_key, event_seq) WHERE event_key IS NOT NULL;
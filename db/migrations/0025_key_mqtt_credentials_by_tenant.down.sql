@@ -0,0 +1,9 @@
+-- Username yang aktif di beberapa tenant: hanya milik tenant pertama (urut nama) yang tetap aktif
+UPDATE mqtt_credentials d SET revoked_at = now()
+    FROM mqtt_credentials o
+    WHERE o.username = d.username AND o.tenant_id < d.tenant_id
+      AND o.revoked_at IS NULL AND d.revoked_at IS NULL;
+
+DROP INDEX IF EXISTS idx_mqtt_credentials_active;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mqtt_credentials_active
+    ON mqtt_credentials(username) WHERE revoked_at IS NULL;
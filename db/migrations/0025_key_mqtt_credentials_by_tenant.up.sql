@@ -0,0 +1,5 @@
+-- Kredensial MQTT unik per (tenant_id, username), sama seperti ID kendaraan sejak 0021. Tracker
+-- login sebagai "{tenant}/{username}", sehingga ID yang sama di dua tenant tidak bertabrakan.
+DROP INDEX IF EXISTS idx_mqtt_credentials_active;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mqtt_credentials_active
+    ON mqtt_credentials(tenant_id, username) WHERE revoked_at IS NULL;
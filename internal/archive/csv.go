@@ -0,0 +1,226 @@
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"sistem-manajemen-armada/internal/models"
+)
+
+// columns adalah header file arsip. Reader memetakan kolom berdasarkan nama header,
+// jadi kolom baru boleh ditambahkan di belakang tanpa merusak arsip lama.
+var columns = []string{"id", "vehicle_id", "latitude", "longitude", "timestamp", "device_id", "speed", "ignition", "odometer", "tenant_id"}
+
+// requiredColumns wajib ada di setiap arsip; kolom lain opsional (arsip lama).
+var requiredColumns = columns[:5]
+
+// Writer menulis baris lokasi sebagai gzip CSV ke file sementara, lalu mengunggahnya ke Store.
+// Writer memenuhi repository.ArchiveSink.
+type Writer struct {
+	store Store
+	key   string
+
+	file     *os.File
+	hash     hash.Hash
+	gz       *gzip.Writer
+	csv      *csv.Writer
+	count    int64
+	vehicles map[string]struct{}
+	// tenantVehicles berisi pasangan "{tenant}/{vehicle_id}" untuk manifest
+	tenantVehicles map[string]struct{}
+}
+
+func NewWriter(store Store, key string) (*Writer, error) {
+	f, err := os.CreateTemp("", "archive-*.csv.gz")
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(f, h))
+	w := &Writer{
+		store:          store,
+		key:            key,
+		file:           f,
+		hash:           h,
+		gz:             gz,
+		csv:            csv.NewWriter(gz),
+		vehicles:       map[string]struct{}{},
+		tenantVehicles: map[string]struct{}{},
+	}
+	if err := w.csv.Write(columns); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) Write(loc models.VehicleLocation) error {
+	w.count++
+	w.vehicles[loc.VehicleID] = struct{}{}
+	w.tenantVehicles[loc.TenantID+"/"+loc.VehicleID] = struct{}{}
+	return w.csv.Write([]string{
+		strconv.FormatInt(loc.ID, 10),
+		loc.VehicleID,
+		strconv.FormatFloat(loc.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(loc.Longitude, 'f', -1, 64),
+		strconv.FormatInt(loc.Timestamp, 10),
+		loc.DeviceID,
+		formatOptionalFloat(loc.Speed),
+		formatOptionalBool(loc.Ignition),
+		formatOptionalFloat(loc.Odometer),
+		loc.TenantID,
+	})
+}
+
+func (w *Writer) Finish(ctx context.Context) (*models.LocationArchive, error) {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return nil, err
+	}
+	if err := w.gz.Close(); err != nil {
+		return nil, err
+	}
+
+	size, err := w.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := w.store.Put(ctx, w.key, w.file, size); err != nil {
+		return nil, fmt.Errorf("upload %s: %w", w.key, err)
+	}
+
+	return &models.LocationArchive{
+		ObjectKey:        w.key,
+		RowCount:         w.count,
+		SizeBytes:        size,
+		SHA256:           hex.EncodeToString(w.hash.Sum(nil)),
+		VehicleIDs:       sortedKeys(w.vehicles),
+		TenantVehicleIDs: sortedKeys(w.tenantVehicles),
+	}, nil
+}
+
+// Close menghapus file sementara. Aman dipanggil setelah Finish.
+func (w *Writer) Close() error {
+	w.file.Close()
+	return os.Remove(w.file.Name())
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ReadCSV membaca file arsip gzip CSV dan memanggil fn untuk setiap baris. Arsip lama tanpa kolom
+// tenant_id menghasilkan TenantID kosong.
+func ReadCSV(r io.Reader, fn func(loc models.VehicleLocation) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	cr := csv.NewReader(gz)
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[name] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := index[name]; !ok {
+			return fmt.Errorf("archive missing column %q", name)
+		}
+	}
+
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var loc models.VehicleLocation
+		if loc.ID, err = strconv.ParseInt(rec[index["id"]], 10, 64); err != nil {
+			return err
+		}
+		loc.VehicleID = rec[index["vehicle_id"]]
+		if loc.Latitude, err = strconv.ParseFloat(rec[index["latitude"]], 64); err != nil {
+			return err
+		}
+		if loc.Longitude, err = strconv.ParseFloat(rec[index["longitude"]], 64); err != nil {
+			return err
+		}
+		if loc.Timestamp, err = strconv.ParseInt(rec[index["timestamp"]], 10, 64); err != nil {
+			return err
+		}
+		if i, ok := index["device_id"]; ok {
+			loc.DeviceID = rec[i]
+		}
+		if i, ok := index["tenant_id"]; ok {
+			loc.TenantID = rec[i]
+		}
+		if i, ok := index["speed"]; ok && rec[i] != "" {
+			speed, err := strconv.ParseFloat(rec[i], 64)
+			if err != nil {
+				return err
+			}
+			loc.Speed = &speed
+		}
+		if i, ok := index["odometer"]; ok && rec[i] != "" {
+			odometer, err := strconv.ParseFloat(rec[i], 64)
+			if err != nil {
+				return err
+			}
+			loc.Odometer = &odometer
+		}
+		if i, ok := index["ignition"]; ok && rec[i] != "" {
+			ignition, err := strconv.ParseBool(rec[i])
+			if err != nil {
+				return err
+			}
+			loc.Ignition = &ignition
+		}
+
+		if err := fn(loc); err != nil {
+			return err
+		}
+	}
+}
+
+// formatOptionalFloat / formatOptionalBool menulis nilai kosong untuk field yang tidak dilaporkan.
+func formatOptionalFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+func formatOptionalBool(v *bool) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatBool(*v)
+}
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// Error verifikasi token. Pesannya aman dikirim ke klien.
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// JWTConfig mengatur kunci dan klaim yang diterima JWTVerifier.
+type JWTConfig struct {
+	// HS256Secret dipakai untuk token HS256; kosong = HS256 ditolak.
+	HS256Secret string
+	// JWKSFile adalah path file JWKS lokal berisi kunci publik RSA untuk RS256; kosong = RS256 ditolak.
+	JWKSFile string
+	// Issuer / Audience yang wajib ada di token; kosong = tidak diperiksa.
+	Issuer   string
+	Audience string
+	// Leeway adalah toleransi selisih jam untuk exp / nbf.
+	Leeway time.Duration
+}
+
+// Claims adalah klaim JWT yang dipakai aplikasi.
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	IssuedAt  *float64 `json:"iat"`
+	// Scope mengikuti konvensi OAuth2: daftar scope dipisah spasi.
+	Scope string `json:"scope"`
+	// Role: admin, dispatcher, atau viewer. Kosong = viewer.
+	Role string `json:"role"`
+	// Groups membatasi grup kendaraan yang terlihat; tidak ada = semua grup.
+	Groups []string `json:"groups"`
+	// Tenant adalah perusahaan klien pemilik token; kosong = DefaultTenant.
+	Tenant string `json:"tenant"`
+}
+
+// audience menerima klaim aud berupa string maupun array string.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// JWTVerifier memverifikasi bearer token HS256 (shared secret) dan RS256 (kunci dari file JWKS).
+type JWTVerifier struct {
+	secret  []byte
+	rsaKeys map[string]*rsa.PublicKey // kid -> kunci
+	cfg     JWTConfig
+}
+
+// NewJWTVerifier membaca kunci dari cfg. Minimal satu dari HS256Secret / JWKSFile wajib diisi.
+func NewJWTVerifier(cfg JWTConfig) (*JWTVerifier, error) {
+	v := &JWTVerifier{cfg: cfg}
+	if cfg.HS256Secret != "" {
+		v.secret = []byte(cfg.HS256Secret)
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("load jwks %s: %w", cfg.JWKSFile, err)
+		}
+		v.rsaKeys = keys
+	}
+	if v.secret == nil && len(v.rsaKeys) == 0 {
+		return nil, errors.New("no JWT key configured: set an HS256 secret or a JWKS file")
+	}
+	return v, nil
+}
+
+// Verify memeriksa tanda tangan, exp / nbf, issuer dan audience token.
+func (v *JWTVerifier) Verify(token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// algoritma ditentukan oleh jenis kunci yang tersedia, bukan dipercaya mentah-mentah dari header
+	signed := []byte(parts[0] + "." + parts[1])
+	switch header.Alg {
+	case "HS256":
+		if v.secret == nil {
+			return nil, ErrInvalidToken
+		}
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, ErrInvalidToken
+		}
+	case "RS256":
+		key := v.rsaKey(header.Kid)
+		if key == nil {
+			return nil, ErrInvalidToken
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return nil, ErrInvalidToken
+		}
+	default:
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := v.validate(&claims, now); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (v *JWTVerifier) validate(c *Claims, now time.Time) error {
+	leeway := v.cfg.Leeway.Seconds()
+	unix := float64(now.Unix())
+	// exp wajib ada supaya token yang bocor tidak berlaku selamanya
+	if c.ExpiresAt == nil || unix > *c.ExpiresAt+leeway {
+		return ErrTokenExpired
+	}
+	if c.NotBefore != nil && unix < *c.NotBefore-leeway {
+		return ErrInvalidToken
+	}
+	if c.Subject == "" {
+		return ErrInvalidToken
+	}
+	if v.cfg.Issuer != "" && c.Issuer != v.cfg.Issuer {
+		return ErrInvalidToken
+	}
+	if v.cfg.Audience != "" {
+		found := false
+		for _, a := range c.Audience {
+			if a == v.cfg.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrInvalidToken
+		}
+	}
+	return nil
+}
+
+// rsaKey mencari kunci berdasarkan kid. Token tanpa kid hanya diterima bila JWKS berisi satu kunci.
+func (v *JWTVerifier) rsaKey(kid string) *rsa.PublicKey {
+	if kid != "" {
+		return v.rsaKeys[kid]
+	}
+	if len(v.rsaKeys) == 1 {
+		for _, k := range v.rsaKeys {
+			return k
+		}
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// loadJWKS membaca kunci publik RSA dari file JWKS ({"keys": [...]}). Kunci non-RSA, kunci enkripsi
+// dan kunci untuk algoritma lain dilewati.
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: invalid n: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: invalid e: %w", k.Kid, err)
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() < 3 {
+			return nil, fmt.Errorf("key %q: invalid exponent", k.Kid)
+		}
+		if _, dup := keys[k.Kid]; dup {
+			return nil, fmt.Errorf("duplicate key id %q", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no RS256 signing keys found")
+	}
+	return keys, nil
+}
+
+// SignHS256 membuat token HS256 dari klaim. Dipakai tool pengembangan untuk menerbitkan token uji.
+func SignHS256(claims any, secret string) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
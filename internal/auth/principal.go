@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Scope akses API.
+const (
+	ScopeReadLocations  = "read:locations"  // membaca lokasi, riwayat, laporan dan data armada
+	ScopeWriteLocations = "write:locations" // mengubah data armada: kendaraan, device, pengemudi, shift
+	ScopeAdminGeofences = "admin:geofences" // mengelola geofence dan batas kecepatan
+	ScopeAdminAPIKeys   = "admin:api-keys"  // mengelola API key; tidak bisa diberikan ke API key
+	ScopeAdminWebhooks  = "admin:webhooks"  // mengelola webhook event dan melihat log pengirimannya
+)
+
+// Role pengguna. Role menentukan scope maksimum; klaim scope di token hanya bisa mempersempitnya.
+const (
+	RoleAdmin      = "admin"      // semua scope, semua grup kendaraan
+	RoleDispatcher = "dispatcher" // membaca dan mengubah data armada di grupnya
+	RoleViewer     = "viewer"     // hanya membaca data armada di grupnya
+)
+
+var roleScopes = map[string][]string{
+	RoleAdmin:      {ScopeReadLocations, ScopeWriteLocations, ScopeAdminGeofences, ScopeAdminAPIKeys, ScopeAdminWebhooks},
+	RoleDispatcher: {ScopeReadLocations, ScopeWriteLocations},
+	RoleViewer:     {ScopeReadLocations},
+}
+
+// RoleScopes mengembalikan scope yang dimiliki role; ok false bila role tidak dikenal.
+func RoleScopes(role string) ([]string, bool) {
+	scopes, ok := roleScopes[role]
+	return scopes, ok
+}
+
+// DefaultTenant adalah tenant untuk token tanpa klaim tenant dan topik MQTT lama tanpa segmen tenant.
+const DefaultTenant = "default"
+
+// Principal adalah pemanggil API yang sudah terautentikasi.
+type Principal struct {
+	Subject string
+	Role    string // kosong untuk API key
+	// Tenant adalah perusahaan klien pemilik pemanggil; data tenant lain tidak pernah terlihat,
+	// termasuk oleh role admin.
+	Tenant string
+	// Scopes membatasi akses; nil = semua scope.
+	Scopes []string
+	// Groups membatasi kendaraan yang terlihat ke grup ini; nil = semua grup. Role admin selalu
+	// melihat semua grup.
+	Groups []string
+}
+
+// AllGroups melaporkan apakah principal boleh melihat kendaraan dari semua grup.
+func (p *Principal) AllGroups() bool {
+	return p == nil || p.Role == RoleAdmin || p.Groups == nil
+}
+
+// CanSeeGroup melaporkan apakah kendaraan di grup boleh dilihat principal.
+func (p *Principal) CanSeeGroup(group string) bool {
+	return p.AllGroups() || slices.Contains(p.Groups, group)
+}
+
+// TenantID mengembalikan tenant principal; kosong (semua tenant) untuk principal nil.
+func (p *Principal) TenantID() string {
+	if p == nil {
+		return ""
+	}
+	return p.Tenant
+}
+
+// HasScope melaporkan apakah principal boleh memakai scope.
+func (p *Principal) HasScope(scope string) bool {
+	return p.Scopes == nil || slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+// WithPrincipal menyimpan principal di context request.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext mengembalikan principal dari context; nil bila autentikasi dimatikan atau untuk proses
+// internal (mis. mqtt-listener), yang berarti tanpa batasan.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
@@ -5,13 +5,38 @@ import (
 	"log"
 	"time"
 
+	"sistem-manajemen-armada/internal/tlsconfig"
+
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func NewPostgresPool(url string) *pgxpool.Pool {
+// ParseConfig membaca URL Postgres lalu memasang TLS / mTLS dari opts. Bila opts diisi, koneksi
+// selalu memakai TLS dengan verifikasi sertifikat, apa pun sslmode di URL, tanpa fallback ke
+// koneksi plaintext.
+func ParseConfig(url string, opts tlsconfig.Options) (*pgxpool.Config, error) {
 	cfg, err := pgxpool.ParseConfig(url)
 	if err != nil {
-		log.Fatalf("failed to parse postgres url: %v", err)
+		return nil, err
+	}
+
+	tc, err := opts.Client()
+	if err != nil {
+		return nil, err
+	}
+	if tc != nil {
+		if tc.ServerName == "" {
+			tc.ServerName = cfg.ConnConfig.Host
+		}
+		cfg.ConnConfig.TLSConfig = tc
+		cfg.ConnConfig.Fallbacks = nil
+	}
+	return cfg, nil
+}
+
+func NewPostgresPool(url string, opts tlsconfig.Options) *pgxpool.Pool {
+	cfg, err := ParseConfig(url, opts)
+	if err != nil {
+		log.Fatalf("failed to parse postgres config: %v", err)
 	}
 
 	cfg.MaxConns = 10
@@ -0,0 +1,246 @@
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+)
+
+const appName = "sistem-manajemen-armada"
+
+// Track adalah satu jalur yang diekspor, mis. riwayat kendaraan atau satu perjalanan.
+type Track struct {
+	Name string
+	// Properties ikut ditulis sebagai properti GeoJSON / deskripsi GPX & KML.
+	Properties map[string]any
+	Points     []models.VehicleLocation
+}
+
+// GeoFormat adalah format file geospasial untuk jalur kendaraan.
+type GeoFormat struct {
+	Name        string
+	Ext         string
+	ContentType string
+	write       func(w io.Writer, title string, tracks []Track) error
+}
+
+var geoFormats = map[string]GeoFormat{
+	"geojson": {Name: "geojson", Ext: "geojson", ContentType: "application/geo+json", write: writeGeoJSON},
+	"gpx":     {Name: "gpx", Ext: "gpx", ContentType: "application/gpx+xml", write: writeGPX},
+	"kml":     {Name: "kml", Ext: "kml", ContentType: "application/vnd.google-earth.kml+xml", write: writeKML},
+}
+
+// LookupGeoFormat mengembalikan format berdasarkan nama ("geojson", "gpx", "kml").
+func LookupGeoFormat(name string) (GeoFormat, bool) {
+	f, ok := geoFormats[name]
+	return f, ok
+}
+
+// Write menulis jalur ke w. title menjadi nama dokumen GPX / KML.
+func (f GeoFormat) Write(w io.Writer, title string, tracks []Track) error {
+	return f.write(w, title, tracks)
+}
+
+// --- GeoJSON ---
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// writeGeoJSON menulis FeatureCollection dengan satu LineString per jalur (Point bila jalur hanya
+// satu titik). Waktu tiap titik disimpan di properti coordTimes, konvensi yang dipakai togeojson.
+func writeGeoJSON(w io.Writer, _ string, tracks []Track) error {
+	fc := geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+	for _, t := range tracks {
+		if len(t.Points) == 0 {
+			continue
+		}
+
+		coords := make([][2]float64, len(t.Points))
+		times := make([]string, len(t.Points))
+		for i, p := range t.Points {
+			coords[i] = [2]float64{p.Longitude, p.Latitude}
+			times[i] = isoTime(p.Timestamp)
+		}
+
+		props := map[string]any{"name": t.Name}
+		for k, v := range t.Properties {
+			props[k] = v
+		}
+		props["coordTimes"] = times
+
+		geom := geoJSONGeometry{Type: "LineString", Coordinates: coords}
+		if len(coords) == 1 {
+			geom = geoJSONGeometry{Type: "Point", Coordinates: coords[0]}
+		}
+		fc.Features = append(fc.Features, geoJSONFeature{Type: "Feature", Geometry: geom, Properties: props})
+	}
+	return json.NewEncoder(w).Encode(fc)
+}
+
+// --- GPX 1.1 ---
+
+type gpxDoc struct {
+	XMLName  xml.Name   `xml:"gpx"`
+	Version  string     `xml:"version,attr"`
+	Creator  string     `xml:"creator,attr"`
+	Xmlns    string     `xml:"xmlns,attr"`
+	Metadata gpxMeta    `xml:"metadata"`
+	Tracks   []gpxTrack `xml:"trk"`
+}
+
+type gpxMeta struct {
+	Name string `xml:"name"`
+	Time string `xml:"time"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name"`
+	Desc     string       `xml:"desc,omitempty"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time"`
+}
+
+func writeGPX(w io.Writer, title string, tracks []Track) error {
+	doc := gpxDoc{
+		Version:  "1.1",
+		Creator:  appName,
+		Xmlns:    "http://www.topografix.com/GPX/1/1",
+		Metadata: gpxMeta{Name: title, Time: time.Now().UTC().Format(time.RFC3339)},
+	}
+	for _, t := range tracks {
+		if len(t.Points) == 0 {
+			continue
+		}
+		seg := gpxSegment{Points: make([]gpxPoint, len(t.Points))}
+		for i, p := range t.Points {
+			seg.Points[i] = gpxPoint{Lat: p.Latitude, Lon: p.Longitude, Time: isoTime(p.Timestamp)}
+		}
+		doc.Tracks = append(doc.Tracks, gpxTrack{Name: t.Name, Desc: describe(t.Properties), Segments: []gpxSegment{seg}})
+	}
+	return writeXML(w, doc)
+}
+
+// --- KML 2.2 ---
+
+type kmlDoc struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	XmlnsGx  string      `xml:"xmlns:gx,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string    `xml:"name"`
+	Description string    `xml:"description,omitempty"`
+	Point       *kmlPoint `xml:"Point,omitempty"`
+	Track       *kmlTrack `xml:"gx:Track,omitempty"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// kmlTrack memakai gx:Track supaya Google Earth bisa memutar jalur dengan slider waktu.
+type kmlTrack struct {
+	When  []string `xml:"when"`
+	Coord []string `xml:"gx:coord"`
+}
+
+func writeKML(w io.Writer, title string, tracks []Track) error {
+	doc := kmlDoc{
+		Xmlns:    "http://www.opengis.net/kml/2.2",
+		XmlnsGx:  "http://www.google.com/kml/ext/2.2",
+		Document: kmlDocument{Name: title},
+	}
+	for _, t := range tracks {
+		if len(t.Points) == 0 {
+			continue
+		}
+		pm := kmlPlacemark{Name: t.Name, Description: describe(t.Properties)}
+		if len(t.Points) == 1 {
+			p := t.Points[0]
+			pm.Point = &kmlPoint{Coordinates: fmt.Sprintf("%s,%s", formatCoord(p.Longitude), formatCoord(p.Latitude))}
+		} else {
+			pm.Track = &kmlTrack{When: make([]string, len(t.Points)), Coord: make([]string, len(t.Points))}
+			for i, p := range t.Points {
+				pm.Track.When[i] = isoTime(p.Timestamp)
+				pm.Track.Coord[i] = formatCoord(p.Longitude) + " " + formatCoord(p.Latitude) + " 0"
+			}
+		}
+		doc.Document.Placemarks = append(doc.Document.Placemarks, pm)
+	}
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// describe menyusun properti jalur menjadi teks "key: value" per baris, urut nama properti.
+func describe(props map[string]any) string {
+	if len(props) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %v\n", k, props[k])
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func isoTime(ts int64) string {
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
+
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -2,6 +2,8 @@ package geofence
 
 import (
 	"math"
+
+	"sistem-manajemen-armada/internal/models"
 )
 
 type Geofence struct {
@@ -19,8 +21,8 @@ func NewGeofence(lat, lon, radius float64) *Geofence {
 	}
 }
 
-// Haversine distance (meter) antara 2 koordinat.
-func distanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+// DistanceMeters menghitung jarak Haversine (meter) antara 2 koordinat.
+func DistanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
 	const R = 6371000 // radius bumi dalam meter
 	φ1 := lat1 * math.Pi / 180
 	φ2 := lat2 * math.Pi / 180
@@ -36,5 +38,22 @@ func distanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
 
 // IsInside mengecek apakah titik berada di dalam radius geofence.
 func (g *Geofence) IsInside(lat, lon float64) bool {
-	return distanceMeters(g.Lat, g.Lon, lat, lon) <= g.RadiusM
+	return DistanceMeters(g.Lat, g.Lon, lat, lon) <= g.RadiusM
+}
+
+// BoundingBoxAround membuat bounding box yang memuat lingkaran radiusM di sekitar titik.
+// Dipakai sebagai pra-filter murah sebelum jarak Haversine dihitung.
+func BoundingBoxAround(lat, lon, radiusM float64) models.BoundingBox {
+	const R = 6371000
+	dLat := radiusM / R * 180 / math.Pi
+	dLon := 180.0
+	if c := math.Cos(lat * math.Pi / 180); c > 1e-9 {
+		dLon = math.Min(dLat/c, 180)
+	}
+	return models.BoundingBox{
+		MinLat: math.Max(lat-dLat, -90),
+		MaxLat: math.Min(lat+dLat, 90),
+		MinLon: math.Max(lon-dLon, -180),
+		MaxLon: math.Min(lon+dLon, 180),
+	}
 }
@@ -0,0 +1,77 @@
+package geofence
+
+import (
+	"math"
+
+	"sistem-manajemen-armada/internal/models"
+)
+
+// Simplify menyederhanakan jalur dengan algoritma Douglas–Peucker.
+// toleranceM adalah deviasi maksimum (meter) titik yang dibuang dari garis hasil.
+// Titik pertama dan terakhir selalu dipertahankan.
+func Simplify(points []models.VehicleLocation, toleranceM float64) []models.VehicleLocation {
+	if len(points) <= 2 || toleranceM <= 0 {
+		return points
+	}
+
+	keep := make([]bool, len(points))
+	keep[0] = true
+	keep[len(points)-1] = true
+
+	// pakai stack supaya tidak rekursif untuk jalur yang sangat panjang
+	type span struct{ first, last int }
+	stack := []span{{0, len(points) - 1}}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		maxDist := 0.0
+		index := -1
+		for i := s.first + 1; i < s.last; i++ {
+			d := crossTrackMeters(points[i], points[s.first], points[s.last])
+			if d > maxDist {
+				maxDist = d
+				index = i
+			}
+		}
+
+		if index != -1 && maxDist > toleranceM {
+			keep[index] = true
+			stack = append(stack, span{s.first, index}, span{index, s.last})
+		}
+	}
+
+	result := make([]models.VehicleLocation, 0, len(points))
+	for i, p := range points {
+		if keep[i] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// crossTrackMeters menghitung jarak titik p ke segmen a–b (meter) dengan proyeksi
+// equirectangular lokal; cukup akurat untuk segmen jalur kendaraan yang pendek.
+func crossTrackMeters(p, a, b models.VehicleLocation) float64 {
+	const R = 6371000
+	cosLat := math.Cos(a.Latitude * math.Pi / 180)
+	project := func(l models.VehicleLocation) (float64, float64) {
+		x := (l.Longitude - a.Longitude) * math.Pi / 180 * R * cosLat
+		y := (l.Latitude - a.Latitude) * math.Pi / 180 * R
+		return x, y
+	}
+
+	px, py := project(p)
+	bx, by := project(b)
+
+	segLen2 := bx*bx + by*by
+	if segLen2 == 0 {
+		return DistanceMeters(a.Latitude, a.Longitude, p.Latitude, p.Longitude)
+	}
+
+	t := (px*bx + py*by) / segLen2
+	t = math.Max(0, math.Min(1, t))
+	dx := px - t*bx
+	dy := py - t*by
+	return math.Sqrt(dx*dx + dy*dy)
+}
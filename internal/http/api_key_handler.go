@@ -0,0 +1,86 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	svc *service.APIKeyService
+}
+
+func NewAPIKeyHandler(svc *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{svc: svc}
+}
+
+type apiKeyRequest struct {
+	Name               string     `json:"name"`
+	Scopes             []string   `json:"scopes"`
+	Groups             []string   `json:"groups"`
+	RateLimitPerMinute int        `json:"rate_limit_per_minute"`
+	ExpiresAt          *time.Time `json:"expires_at"`
+}
+
+func (h *APIKeyHandler) RegisterRoutes(r *gin.Engine) {
+	k := r.Group("/api-keys")
+	{
+		k.GET("", h.List)
+		k.POST("", h.Create)
+		k.DELETE("/:key_id", h.Revoke)
+	}
+}
+
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "failed to list api keys")
+		return
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// Create menerbitkan key baru. Field "key" di respons hanya ditampilkan sekali.
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var req apiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	k, err := h.svc.Create(c.Request.Context(), models.APIKey{
+		Name:               req.Name,
+		Scopes:             req.Scopes,
+		Groups:             req.Groups,
+		RateLimitPerMinute: req.RateLimitPerMinute,
+		ExpiresAt:          req.ExpiresAt,
+	})
+	if err != nil {
+		writeError(c, err, "failed to create api key")
+		return
+	}
+	c.JSON(http.StatusCreated, k)
+}
+
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("key_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid key_id"})
+		return
+	}
+
+	k, err := h.svc.Revoke(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err, "failed to revoke api key")
+		return
+	}
+	c.JSON(http.StatusOK, k)
+}
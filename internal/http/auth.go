@@ -0,0 +1,159 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sistem-manajemen-armada/internal/auth"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// publicPaths tidak memerlukan token. Hook /mqtt/auth tidak ada di sini: hook dilayani di listener
+// internal terpisah (lihat MQTTCredentialHandler.RegisterHookRoutes).
+var publicPaths = map[string]bool{
+	"/health": true,
+}
+
+// Authenticate mewajibkan bearer token JWT atau header X-API-Key di setiap request kecuali
+// publicPaths, lalu memeriksa scope route (lihat requiredScope). Principal disimpan di context request.
+func Authenticate(jwt *auth.JWTVerifier, keys *service.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if publicPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		var p *auth.Principal
+		if key := c.GetHeader(apiKeyHeader); key != "" {
+			p = authenticateAPIKey(c, keys, key)
+		} else {
+			p = authenticateJWT(c, jwt)
+		}
+		if p == nil {
+			return
+		}
+
+		if scope := requiredScope(c.Request.Method, c.Request.URL.Path); !p.HasScope(scope) {
+			forbidden(c, "missing scope "+scope)
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), p))
+		c.Next()
+	}
+}
+
+func authenticateJWT(c *gin.Context, jwt *auth.JWTVerifier) *auth.Principal {
+	token := bearerToken(c)
+	if token == "" {
+		unauthorized(c, "missing bearer token")
+		return nil
+	}
+	claims, err := jwt.Verify(token, time.Now())
+	if err != nil {
+		msg := auth.ErrInvalidToken.Error()
+		if errors.Is(err, auth.ErrTokenExpired) {
+			msg = err.Error()
+		}
+		unauthorized(c, msg)
+		return nil
+	}
+
+	role := claims.Role
+	if role == "" {
+		role = auth.RoleViewer
+	}
+	scopes, ok := auth.RoleScopes(role)
+	if !ok {
+		forbidden(c, "unknown role "+role)
+		return nil
+	}
+	// klaim scope hanya bisa mempersempit scope role
+	if claims.Scope != "" {
+		requested := strings.Fields(claims.Scope)
+		scopes = slices.DeleteFunc(slices.Clone(scopes), func(s string) bool { return !slices.Contains(requested, s) })
+	}
+	tenant := claims.Tenant
+	if tenant == "" {
+		tenant = auth.DefaultTenant
+	}
+	return &auth.Principal{Subject: claims.Subject, Role: role, Tenant: tenant, Scopes: scopes, Groups: claims.Groups}
+}
+
+// authenticateAPIKey memverifikasi key lalu menerapkan rate limit key; request yang melewati
+// batas ditolak dengan 429 dan header Retry-After.
+func authenticateAPIKey(c *gin.Context, keys *service.APIKeyService, key string) *auth.Principal {
+	k, err := keys.Authenticate(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAPIKey) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to verify api key"})
+		}
+		return nil
+	}
+
+	if ok, wait := keys.Allow(k, time.Now()); !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+		return nil
+	}
+
+	// API key selalu dibatasi scope-nya; Scopes tidak pernah nil
+	return &auth.Principal{Subject: "api-key:" + k.Prefix, Tenant: k.TenantID, Scopes: k.Scopes, Groups: k.Groups}
+}
+
+// bearerToken membaca token dari header Authorization. Untuk stream, token juga boleh dikirim lewat
+// query access_token karena EventSource dan WebSocket di browser tidak bisa mengatur header.
+func bearerToken(c *gin.Context) string {
+	if h := c.GetHeader("Authorization"); h != "" {
+		scheme, token, ok := strings.Cut(h, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	if strings.HasPrefix(c.Request.URL.Path, "/stream/") {
+		return c.Query("access_token")
+	}
+	return ""
+}
+
+// requiredScope memetakan route ke scope: pengelolaan API key, kredensial MQTT dan kunci tanda
+// tangan device butuh admin:api-keys, semua route webhook dan aturan notifikasi butuh
+// admin:webhooks, GET lain cukup read:locations, perubahan geofence dan batas kecepatan butuh
+// admin:geofences, perubahan lain butuh write:locations.
+func requiredScope(method, path string) string {
+	if strings.HasPrefix(path, "/api-keys") || strings.HasPrefix(path, "/mqtt-credentials") ||
+		strings.HasSuffix(path, "/signing-key") {
+		return auth.ScopeAdminAPIKeys
+	}
+	if strings.HasPrefix(path, "/webhooks") || strings.HasPrefix(path, "/notification-rules") {
+		return auth.ScopeAdminWebhooks
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return auth.ScopeReadLocations
+	}
+	if strings.HasPrefix(path, "/geofences") || strings.HasPrefix(path, "/speed-limits") {
+		return auth.ScopeAdminGeofences
+	}
+	return auth.ScopeWriteLocations
+}
+
+func unauthorized(c *gin.Context, msg string) {
+	c.Header("WWW-Authenticate", `Bearer realm="fleet"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": msg})
+}
+
+func forbidden(c *gin.Context, msg string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": msg})
+}
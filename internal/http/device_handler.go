@@ -0,0 +1,204 @@
+package http
+
+import (
+	"net/http"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeviceHandler struct {
+	svc *service.DeviceService
+}
+
+func NewDeviceHandler(svc *service.DeviceService) *DeviceHandler {
+	return &DeviceHandler{svc: svc}
+}
+
+type deviceRequest struct {
+	ID     string `json:"id"`
+	IMEI   string `json:"imei"`
+	Model  string `json:"model"`
+	Active *bool  `json:"active"`
+}
+
+func (req deviceRequest) toModel() models.Device {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return models.Device{
+		ID:     req.ID,
+		IMEI:   req.IMEI,
+		Model:  req.Model,
+		Active: active,
+	}
+}
+
+type assignRequest struct {
+	VehicleID string `json:"vehicle_id"`
+	Start     int64  `json:"start"`
+	End       *int64 `json:"end"`
+}
+
+type unassignRequest struct {
+	At int64 `json:"at"`
+}
+
+type signingKeyRequest struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"` // hanya untuk ed25519
+}
+
+func (h *DeviceHandler) RegisterRoutes(r *gin.Engine) {
+	d := r.Group("/devices")
+	{
+		d.GET("", h.List)
+		d.POST("", h.Create)
+		d.GET("/:device_id", h.Get)
+		d.PUT("/:device_id", h.Update)
+		d.DELETE("/:device_id", h.Delete)
+		d.GET("/:device_id/assignments", h.ListAssignments)
+		d.POST("/:device_id/assignments", h.Assign)
+		d.POST("/:device_id/unassign", h.Unassign)
+		d.PUT("/:device_id/signing-key", h.SetSigningKey)
+		d.DELETE("/:device_id/signing-key", h.DeleteSigningKey)
+	}
+}
+
+func (h *DeviceHandler) List(c *gin.Context) {
+	devices, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "failed to list devices")
+		return
+	}
+	if devices == nil {
+		devices = []models.Device{}
+	}
+	c.JSON(http.StatusOK, devices)
+}
+
+func (h *DeviceHandler) Create(c *gin.Context) {
+	var req deviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	d, err := h.svc.Create(c.Request.Context(), req.toModel())
+	if err != nil {
+		writeError(c, err, "failed to create device")
+		return
+	}
+	c.JSON(http.StatusCreated, d)
+}
+
+func (h *DeviceHandler) Get(c *gin.Context) {
+	d, err := h.svc.Get(c.Request.Context(), c.Param("device_id"))
+	if err != nil {
+		writeError(c, err, "failed to get device")
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+func (h *DeviceHandler) Update(c *gin.Context) {
+	var req deviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	req.ID = c.Param("device_id")
+
+	d, err := h.svc.Update(c.Request.Context(), req.toModel())
+	if err != nil {
+		writeError(c, err, "failed to update device")
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+func (h *DeviceHandler) Delete(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), c.Param("device_id")); err != nil {
+		writeError(c, err, "failed to delete device")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *DeviceHandler) ListAssignments(c *gin.Context) {
+	assignments, err := h.svc.ListAssignments(c.Request.Context(), c.Param("device_id"))
+	if err != nil {
+		writeError(c, err, "failed to list assignments")
+		return
+	}
+	if assignments == nil {
+		assignments = []models.DeviceAssignment{}
+	}
+	c.JSON(http.StatusOK, assignments)
+}
+
+func (h *DeviceHandler) Assign(c *gin.Context) {
+	var req assignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	a, err := h.svc.Assign(c.Request.Context(), models.DeviceAssignment{
+		DeviceID:  c.Param("device_id"),
+		VehicleID: req.VehicleID,
+		Start:     req.Start,
+		End:       req.End,
+	})
+	if err != nil {
+		writeError(c, err, "failed to assign device")
+		return
+	}
+	c.JSON(http.StatusCreated, a)
+}
+
+func (h *DeviceHandler) Unassign(c *gin.Context) {
+	// body opsional; tanpa body device dilepas sekarang
+	var req unassignRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+			return
+		}
+	}
+
+	a, err := h.svc.Unassign(c.Request.Context(), c.Param("device_id"), req.At)
+	if err != nil {
+		writeError(c, err, "failed to unassign device")
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// SetSigningKey mewajibkan payload device ditandatangani. Secret HMAC di respons hanya ditampilkan
+// sekali.
+func (h *DeviceHandler) SetSigningKey(c *gin.Context) {
+	var req signingKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	k, err := h.svc.SetSigningKey(c.Request.Context(), c.Param("device_id"), req.Algorithm, req.PublicKey)
+	if err != nil {
+		writeError(c, err, "failed to set signing key")
+		return
+	}
+	c.JSON(http.StatusOK, k)
+}
+
+func (h *DeviceHandler) DeleteSigningKey(c *gin.Context) {
+	if err := h.svc.DeleteSigningKey(c.Request.Context(), c.Param("device_id")); err != nil {
+		writeError(c, err, "failed to delete signing key")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
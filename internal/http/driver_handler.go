@@ -0,0 +1,245 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DriverHandler struct {
+	svc *service.DriverService
+}
+
+func NewDriverHandler(svc *service.DriverService) *DriverHandler {
+	return &DriverHandler{svc: svc}
+}
+
+type driverRequest struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	LicenseNumber string `json:"license_number"`
+	LicenseExpiry string `json:"license_expiry"`
+	Phone         string `json:"phone"`
+	Email         string `json:"email"`
+	Active        *bool  `json:"active"`
+}
+
+func (req driverRequest) toModel() models.Driver {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return models.Driver{
+		ID:            req.ID,
+		Name:          req.Name,
+		LicenseNumber: req.LicenseNumber,
+		LicenseExpiry: req.LicenseExpiry,
+		Phone:         req.Phone,
+		Email:         req.Email,
+		Active:        active,
+	}
+}
+
+type shiftRequest struct {
+	DriverID  string `json:"driver_id"`
+	VehicleID string `json:"vehicle_id"`
+	Start     int64  `json:"start"`
+	End       *int64 `json:"end"`
+}
+
+type endShiftRequest struct {
+	At int64 `json:"at"`
+}
+
+func (h *DriverHandler) RegisterRoutes(r *gin.Engine) {
+	d := r.Group("/drivers")
+	{
+		d.GET("", h.List)
+		d.POST("", h.Create)
+		d.GET("/:driver_id", h.Get)
+		d.PUT("/:driver_id", h.Update)
+		d.DELETE("/:driver_id", h.Delete)
+	}
+
+	s := r.Group("/shifts")
+	{
+		s.GET("", h.ListShifts)
+		s.POST("", h.StartShift)
+		s.GET("/:shift_id", h.GetShift)
+		s.POST("/:shift_id/end", h.EndShift)
+		s.DELETE("/:shift_id", h.DeleteShift)
+	}
+}
+
+func (h *DriverHandler) List(c *gin.Context) {
+	drivers, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "failed to list drivers")
+		return
+	}
+	if drivers == nil {
+		drivers = []models.Driver{}
+	}
+	c.JSON(http.StatusOK, drivers)
+}
+
+func (h *DriverHandler) Create(c *gin.Context) {
+	var req driverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	d, err := h.svc.Create(c.Request.Context(), req.toModel())
+	if err != nil {
+		writeError(c, err, "failed to create driver")
+		return
+	}
+	c.JSON(http.StatusCreated, d)
+}
+
+func (h *DriverHandler) Get(c *gin.Context) {
+	d, err := h.svc.Get(c.Request.Context(), c.Param("driver_id"))
+	if err != nil {
+		writeError(c, err, "failed to get driver")
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+func (h *DriverHandler) Update(c *gin.Context) {
+	var req driverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	req.ID = c.Param("driver_id")
+
+	d, err := h.svc.Update(c.Request.Context(), req.toModel())
+	if err != nil {
+		writeError(c, err, "failed to update driver")
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+func (h *DriverHandler) Delete(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), c.Param("driver_id")); err != nil {
+		writeError(c, err, "failed to delete driver")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *DriverHandler) ListShifts(c *gin.Context) {
+	filter := models.ShiftFilter{
+		DriverID:  c.Query("driver_id"),
+		VehicleID: c.Query("vehicle_id"),
+	}
+	if v := c.Query("start"); v != "" {
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ts < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start query param"})
+			return
+		}
+		filter.Start = ts
+	}
+	if v := c.Query("end"); v != "" {
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ts < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end query param"})
+			return
+		}
+		filter.End = ts
+	}
+
+	shifts, err := h.svc.ListShifts(c.Request.Context(), filter)
+	if err != nil {
+		writeError(c, err, "failed to list shifts")
+		return
+	}
+	if shifts == nil {
+		shifts = []models.DriverShift{}
+	}
+	c.JSON(http.StatusOK, shifts)
+}
+
+func (h *DriverHandler) StartShift(c *gin.Context) {
+	var req shiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	sh, err := h.svc.StartShift(c.Request.Context(), models.DriverShift{
+		DriverID:  req.DriverID,
+		VehicleID: req.VehicleID,
+		Start:     req.Start,
+		End:       req.End,
+	})
+	if err != nil {
+		writeError(c, err, "failed to start shift")
+		return
+	}
+	c.JSON(http.StatusCreated, sh)
+}
+
+func (h *DriverHandler) GetShift(c *gin.Context) {
+	id, ok := shiftID(c)
+	if !ok {
+		return
+	}
+	sh, err := h.svc.GetShift(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err, "failed to get shift")
+		return
+	}
+	c.JSON(http.StatusOK, sh)
+}
+
+func (h *DriverHandler) EndShift(c *gin.Context) {
+	id, ok := shiftID(c)
+	if !ok {
+		return
+	}
+	// body opsional; tanpa body shift ditutup sekarang
+	var req endShiftRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+			return
+		}
+	}
+
+	sh, err := h.svc.EndShift(c.Request.Context(), id, req.At)
+	if err != nil {
+		writeError(c, err, "failed to end shift")
+		return
+	}
+	c.JSON(http.StatusOK, sh)
+}
+
+func (h *DriverHandler) DeleteShift(c *gin.Context) {
+	id, ok := shiftID(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.DeleteShift(c.Request.Context(), id); err != nil {
+		writeError(c, err, "failed to delete shift")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func shiftID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("shift_id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeError memetakan error service ke status HTTP. msg dipakai untuk error internal
+// supaya detail database tidak bocor ke client.
+func writeError(c *gin.Context, err error, msg string) {
+	var verr *service.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": verr.Msg})
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, service.ErrReferenceNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "referenced resource does not exist"})
+	case errors.Is(err, service.ErrConflict):
+		// pesan ErrConflict hanya berisi konteks dari service, bukan detail database
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
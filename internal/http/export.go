@@ -0,0 +1,92 @@
+package http
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"sistem-manajemen-armada/internal/export"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportFilename menyusun nama file unduhan, mis. B1234XYZ-history-20241111T000000Z-20241112T000000Z.gpx.
+// vehicleID kosong (laporan seluruh armada) menjadi "fleet".
+func exportFilename(vehicleID, kind string, start, end int64, ext string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, vehicleID)
+	const layout = "20060102T150405Z"
+	if safe == "" {
+		safe = "fleet"
+	}
+	return fmt.Sprintf("%s-%s-%s-%s.%s", safe, kind,
+		time.Unix(start, 0).UTC().Format(layout), time.Unix(end, 0).UTC().Format(layout), ext)
+}
+
+// attachment menandai respons sebagai file unduhan.
+func attachment(c *gin.Context, contentType, filename string) {
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+}
+
+// writeTracks menulis jalur sebagai file GeoJSON / GPX / KML.
+func writeTracks(c *gin.Context, format export.GeoFormat, filename, title string, tracks []export.Track) {
+	attachment(c, format.ContentType, filename)
+	c.Status(http.StatusOK)
+	if err := format.Write(c.Writer, title, tracks); err != nil {
+		// status sudah terkirim; klien hanya bisa mendeteksi file yang terpotong
+		log.Printf("failed to write %s export %s: %v", format.Name, filename, err)
+	}
+}
+
+// exportTimeLayout adalah format waktu lokal pada laporan CSV / XLSX.
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+// exportLocation mengembalikan zona waktu laporan: query tz (mis. Asia/Makassar) atau def.
+func exportLocation(c *gin.Context, def *time.Location) (*time.Location, bool) {
+	name := c.Query("tz")
+	if name == "" {
+		return def, true
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tz query param"})
+		return nil, false
+	}
+	return loc, true
+}
+
+// localTime memformat timestamp unix sebagai waktu lokal yang mudah dibaca.
+func localTime(ts int64, tz *time.Location) string {
+	return time.Unix(ts, 0).In(tz).Format(exportTimeLayout)
+}
+
+// writeTable menulis laporan CSV / XLSX. rows dipanggil setelah header ditulis dan menulis baris
+// langsung ke respons. Bila rows gagal di tengah jalan, status sudah terkirim sehingga file
+// ditutup apa adanya (terpotong).
+func writeTable(c *gin.Context, format export.TableFormat, filename, sheet string, header []any, rows func(w export.TableWriter) error) {
+	attachment(c, format.ContentType, filename)
+	c.Status(http.StatusOK)
+
+	w, err := format.Open(c.Writer, sheet)
+	if err != nil {
+		log.Printf("failed to start %s export %s: %v", format.Name, filename, err)
+		return
+	}
+	err = w.WriteRow(header...)
+	if err == nil {
+		err = rows(w)
+	}
+	if err != nil {
+		log.Printf("%s export %s aborted: %v", format.Name, filename, err)
+	}
+	if err := w.Close(); err != nil {
+		log.Printf("failed to finish %s export %s: %v", format.Name, filename, err)
+	}
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"sistem-manajemen-armada/internal/export"
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GeofenceEventHandler struct {
+	svc *service.LocationService
+	tz  *time.Location
+}
+
+func NewGeofenceEventHandler(svc *service.LocationService, tz *time.Location) *GeofenceEventHandler {
+	return &GeofenceEventHandler{svc: svc, tz: tz}
+}
+
+func (h *GeofenceEventHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/geofence-events", h.List)
+}
+
+// List mengembalikan event geofence dalam [start, end] (filter vehicle_id opsional) sebagai JSON,
+// atau file CSV / XLSX dengan format=csv|xlsx.
+func (h *GeofenceEventHandler) List(c *gin.Context) {
+	start, err1 := strconv.ParseInt(c.Query("start"), 10, 64)
+	end, err2 := strconv.ParseInt(c.Query("end"), 10, 64)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start/end query param"})
+		return
+	}
+	if end < start {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end must not be before start"})
+		return
+	}
+	vehicleID := c.Query("vehicle_id")
+	if vehicleID != "" {
+		// dicek sebelum ekspor di-stream supaya kendaraan di luar akses tetap dijawab 404
+		if err := h.svc.CheckAccess(c.Request.Context(), vehicleID); err != nil {
+			writeError(c, err, "failed to list geofence events")
+			return
+		}
+	}
+
+	format := c.Query("format")
+	if table, ok := export.LookupTableFormat(format); ok {
+		tz, ok := exportLocation(c, h.tz)
+		if !ok {
+			return
+		}
+		header := []any{"vehicle_id", "driver_id", "event", "local_time", "timestamp", "latitude", "longitude"}
+		writeTable(c, table, exportFilename(vehicleID, "geofence-events", start, end, table.Ext), "Geofence events", header,
+			func(w export.TableWriter) error {
+				return h.svc.StreamGeofenceEvents(c.Request.Context(), vehicleID, start, end, func(e models.GeofenceEvent) error {
+					return w.WriteRow(e.VehicleID, e.DriverID, e.Event, localTime(e.Timestamp, tz), e.Timestamp,
+						e.Location.Latitude, e.Location.Longitude)
+				})
+			})
+		return
+	}
+	if format != "" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format query param"})
+		return
+	}
+
+	events := []models.GeofenceEvent{}
+	err := h.svc.StreamGeofenceEvents(c.Request.Context(), vehicleID, start, end, func(e models.GeofenceEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		writeError(c, err, "failed to list geofence events")
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
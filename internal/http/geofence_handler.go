@@ -0,0 +1,132 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GeofenceHandler struct {
+	svc *service.GeofenceService
+}
+
+func NewGeofenceHandler(svc *service.GeofenceService) *GeofenceHandler {
+	return &GeofenceHandler{svc: svc}
+}
+
+type geofenceRequest struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	RadiusM   float64 `json:"radius_m"`
+
+	SpeedLimitKmh *float64 `json:"speed_limit_kmh"`
+}
+
+func (req geofenceRequest) toModel() models.Geofence {
+	return models.Geofence{
+		Name:      req.Name,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		RadiusM:   req.RadiusM,
+
+		SpeedLimitKmh: req.SpeedLimitKmh,
+	}
+}
+
+func (h *GeofenceHandler) RegisterRoutes(r *gin.Engine) {
+	g := r.Group("/geofences")
+	{
+		g.GET("", h.List)
+		g.POST("", h.Create)
+		g.GET("/:geofence_id", h.Get)
+		g.PUT("/:geofence_id", h.Update)
+		g.DELETE("/:geofence_id", h.Delete)
+	}
+}
+
+func (h *GeofenceHandler) List(c *gin.Context) {
+	geofences, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "failed to list geofences")
+		return
+	}
+	if geofences == nil {
+		geofences = []models.Geofence{}
+	}
+	c.JSON(http.StatusOK, geofences)
+}
+
+func (h *GeofenceHandler) Create(c *gin.Context) {
+	var req geofenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	g, err := h.svc.Create(c.Request.Context(), req.toModel())
+	if err != nil {
+		writeError(c, err, "failed to create geofence")
+		return
+	}
+	c.JSON(http.StatusCreated, g)
+}
+
+func (h *GeofenceHandler) Get(c *gin.Context) {
+	id, ok := geofenceID(c)
+	if !ok {
+		return
+	}
+	g, err := h.svc.Get(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err, "failed to get geofence")
+		return
+	}
+	c.JSON(http.StatusOK, g)
+}
+
+func (h *GeofenceHandler) Update(c *gin.Context) {
+	id, ok := geofenceID(c)
+	if !ok {
+		return
+	}
+	var req geofenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	g := req.toModel()
+	g.ID = id
+	updated, err := h.svc.Update(c.Request.Context(), g)
+	if err != nil {
+		writeError(c, err, "failed to update geofence")
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *GeofenceHandler) Delete(c *gin.Context) {
+	id, ok := geofenceID(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+		writeError(c, err, "failed to delete geofence")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func geofenceID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("geofence_id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return 0, false
+	}
+	return id, true
+}
@@ -1,20 +1,39 @@
 package http
 
 import (
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"sistem-manajemen-armada/internal/export"
+	"sistem-manajemen-armada/internal/models"
 	"sistem-manajemen-armada/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	defaultHistoryLimit = 1000
+	maxHistoryLimit     = 10000
+	ndjsonContentType   = "application/x-ndjson"
+	ndjsonFlushEvery    = 500
+	nextCursorHeader    = "X-Next-Cursor"
+
+	defaultStaleThreshold = 10 * time.Minute
+	maxNearbyRadiusM      = 500000
+)
+
 type Handler struct {
 	svc *service.LocationService
+	tz  *time.Location // zona waktu default laporan CSV / XLSX
 }
 
-func NewHandler(svc *service.LocationService) *Handler {
-	return &Handler{svc: svc}
+func NewHandler(svc *service.LocationService, tz *time.Location) *Handler {
+	return &Handler{svc: svc, tz: tz}
 }
 
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
@@ -24,6 +43,9 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 
 	v := r.Group("/vehicles")
 	{
+		v.GET("/locations/latest", h.ListLatestLocations)
+		v.GET("/nearby", h.Nearby)
+		v.GET("/within", h.Within)
 		v.GET("/:vehicle_id/location", h.GetLatestLocation)
 		v.GET("/:vehicle_id/history", h.GetHistory)
 	}
@@ -40,6 +62,96 @@ func (h *Handler) GetLatestLocation(c *gin.Context) {
 	c.JSON(http.StatusOK, loc)
 }
 
+func (h *Handler) ListLatestLocations(c *gin.Context) {
+	filter := models.LatestFilter{Group: c.Query("group")}
+	if v := c.Query("bbox"); v != "" {
+		bbox, err := parseBBox(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bbox query param"})
+			return
+		}
+		filter.BBox = bbox
+	}
+
+	staleAfter := defaultStaleThreshold
+	if v := c.Query("stale_threshold"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid stale_threshold query param"})
+			return
+		}
+		staleAfter = d
+	}
+
+	if v := c.Query("stale"); v != "" {
+		stale, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid stale query param"})
+			return
+		}
+		threshold := time.Now().Add(-staleAfter).Unix()
+		if stale {
+			filter.UpdatedBefore = threshold
+		} else {
+			filter.UpdatedSince = threshold
+		}
+	}
+
+	locations, err := h.svc.ListLatest(c.Request.Context(), filter, staleAfter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query latest locations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, locations)
+}
+
+func (h *Handler) Nearby(c *gin.Context) {
+	lat, err1 := strconv.ParseFloat(c.Query("lat"), 64)
+	lon, err2 := strconv.ParseFloat(c.Query("lon"), 64)
+	radius, err3 := strconv.ParseFloat(c.Query("radius"), 64)
+	if err1 != nil || err2 != nil || err3 != nil ||
+		lat < -90 || lat > 90 || lon < -180 || lon > 180 ||
+		radius <= 0 || radius > maxNearbyRadiusM {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid lat/lon/radius query param"})
+		return
+	}
+
+	limit := 0
+	if v := c.Query("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit query param"})
+			return
+		}
+		limit = l
+	}
+
+	vehicles, err := h.svc.Nearby(c.Request.Context(), lat, lon, radius, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search nearby vehicles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, vehicles)
+}
+
+func (h *Handler) Within(c *gin.Context) {
+	bbox, err := parseBBox(c.Query("bbox"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bbox query param"})
+		return
+	}
+
+	vehicles, err := h.svc.Within(c.Request.Context(), *bbox)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search vehicles in bbox"})
+		return
+	}
+
+	c.JSON(http.StatusOK, vehicles)
+}
+
 func (h *Handler) GetHistory(c *gin.Context) {
 	vehicleID := c.Param("vehicle_id")
 	startStr := c.Query("start")
@@ -52,11 +164,229 @@ func (h *Handler) GetHistory(c *gin.Context) {
 		return
 	}
 
-	locations, err := h.svc.GetHistory(c.Request.Context(), vehicleID, start, end)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query history"})
+	var opts service.HistoryOptions
+	if v := c.Query("interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Second || d%time.Second != 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid interval query param"})
+			return
+		}
+		opts.Interval = int64(d / time.Second)
+	}
+	if v := c.Query("tolerance"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tolerance query param"})
+			return
+		}
+		opts.Tolerance = t
+	}
+
+	var page service.PageOptions
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxHistoryLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit query param"})
+			return
+		}
+		page.Limit = limit
+	}
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := service.DecodeCursor(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor query param"})
+			return
+		}
+		page.After = cursor
+	}
+
+	paged := page.Limit > 0 || page.After != nil
+	downsampled := opts.Interval > 0 || opts.Tolerance > 0
+	if paged && downsampled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit/cursor cannot be combined with interval/tolerance"})
+		return
+	}
+
+	format := c.Query("format")
+	geo, isGeo := export.LookupGeoFormat(format)
+	table, isTable := export.LookupTableFormat(format)
+	if format != "" && format != "json" && format != "ndjson" && !isGeo && !isTable {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format query param"})
 		return
 	}
 
+	// dicek sebelum stream dimulai supaya kendaraan di luar akses tetap dijawab 404
+	if err := h.svc.CheckAccess(c.Request.Context(), vehicleID); err != nil {
+		writeError(c, err, "failed to query history")
+		return
+	}
+
+	if isTable {
+		tz, ok := exportLocation(c, h.tz)
+		if !ok {
+			return
+		}
+		h.writeHistoryTable(c, table, tz, vehicleID, start, end, opts, page)
+		return
+	}
+
+	if format == "ndjson" || format == "" && c.GetHeader("Accept") == ndjsonContentType {
+		if downsampled {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ndjson format does not support interval/tolerance"})
+			return
+		}
+		h.streamHistoryNDJSON(c, vehicleID, start, end, page)
+		return
+	}
+
+	// riwayat mentah dalam array JSON selalu berhalaman supaya respons tidak tumbuh tanpa batas
+	if !downsampled && !isGeo && page.Limit == 0 {
+		page.Limit = defaultHistoryLimit
+		paged = true
+	}
+
+	if paged {
+		var locations []models.VehicleLocation
+		next, err := h.svc.StreamHistory(c.Request.Context(), vehicleID, start, end, page, func(loc models.VehicleLocation) error {
+			locations = append(locations, loc)
+			return nil
+		})
+		if err != nil {
+			writeError(c, err, "failed to query history")
+			return
+		}
+		if next != nil {
+			c.Header(nextCursorHeader, service.EncodeCursor(*next))
+		}
+		if isGeo {
+			h.writeHistoryTrack(c, geo, vehicleID, start, end, locations)
+			return
+		}
+		if locations == nil {
+			locations = []models.VehicleLocation{}
+		}
+		c.JSON(http.StatusOK, locations)
+		return
+	}
+
+	locations, err := h.svc.GetHistory(c.Request.Context(), vehicleID, start, end, opts)
+	if err != nil {
+		writeError(c, err, "failed to query history")
+		return
+	}
+	if isGeo {
+		h.writeHistoryTrack(c, geo, vehicleID, start, end, locations)
+		return
+	}
+	if locations == nil {
+		locations = []models.VehicleLocation{}
+	}
 	c.JSON(http.StatusOK, locations)
 }
+
+// writeHistoryTable menulis riwayat sebagai CSV / XLSX dengan waktu lokal. Riwayat mentah di-stream
+// langsung dari query; cursor halaman berikutnya (bila memakai limit) dikirim sebagai trailer X-Next-Cursor.
+func (h *Handler) writeHistoryTable(c *gin.Context, format export.TableFormat, tz *time.Location, vehicleID string, start, end int64, opts service.HistoryOptions, page service.PageOptions) {
+	header := []any{"vehicle_id", "device_id", "driver_id", "local_time", "timestamp",
+		"latitude", "longitude", "speed_kmh", "ignition", "odometer_km"}
+	row := func(w export.TableWriter, loc models.VehicleLocation) error {
+		return w.WriteRow(loc.VehicleID, loc.DeviceID, loc.DriverID, localTime(loc.Timestamp, tz), loc.Timestamp,
+			loc.Latitude, loc.Longitude, loc.Speed, loc.Ignition, loc.Odometer)
+	}
+	filename := exportFilename(vehicleID, "history", start, end, format.Ext)
+
+	if opts.Interval > 0 || opts.Tolerance > 0 {
+		locations, err := h.svc.GetHistory(c.Request.Context(), vehicleID, start, end, opts)
+		if err != nil {
+			writeError(c, err, "failed to query history")
+			return
+		}
+		writeTable(c, format, filename, "History", header, func(w export.TableWriter) error {
+			for _, loc := range locations {
+				if err := row(w, loc); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return
+	}
+
+	c.Header("Trailer", nextCursorHeader)
+	writeTable(c, format, filename, "History", header, func(w export.TableWriter) error {
+		next, err := h.svc.StreamHistory(c.Request.Context(), vehicleID, start, end, page, func(loc models.VehicleLocation) error {
+			return row(w, loc)
+		})
+		if next != nil {
+			c.Writer.Header().Set(nextCursorHeader, service.EncodeCursor(*next))
+		}
+		return err
+	})
+}
+
+// writeHistoryTrack menulis riwayat sebagai satu jalur GeoJSON / GPX / KML.
+func (h *Handler) writeHistoryTrack(c *gin.Context, format export.GeoFormat, vehicleID string, start, end int64, locations []models.VehicleLocation) {
+	track := export.Track{
+		Name:       vehicleID,
+		Properties: map[string]any{"vehicle_id": vehicleID},
+		Points:     locations,
+	}
+	writeTracks(c, format, exportFilename(vehicleID, "history", start, end, format.Ext),
+		vehicleID+" history", []export.Track{track})
+}
+
+// streamHistoryNDJSON menulis satu objek JSON per baris langsung dari hasil query.
+// Cursor halaman berikutnya (bila ada) dikirim sebagai HTTP trailer X-Next-Cursor.
+func (h *Handler) streamHistoryNDJSON(c *gin.Context, vehicleID string, start, end int64, page service.PageOptions) {
+	c.Header("Content-Type", ndjsonContentType)
+	c.Header("Trailer", nextCursorHeader)
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	written := 0
+	next, err := h.svc.StreamHistory(c.Request.Context(), vehicleID, start, end, page, func(loc models.VehicleLocation) error {
+		if err := enc.Encode(loc); err != nil {
+			return err
+		}
+		written++
+		if written%ndjsonFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// status sudah terkirim; baris error terakhir menandakan hasil tidak lengkap
+		log.Printf("history stream for %s aborted: %v", vehicleID, err)
+		_ = enc.Encode(gin.H{"error": "failed to query history"})
+		c.Writer.Flush()
+		return
+	}
+	if next != nil {
+		c.Writer.Header().Set(nextCursorHeader, service.EncodeCursor(*next))
+	}
+	c.Writer.Flush()
+}
+
+// parseBBox membaca bbox dengan urutan GeoJSON: minLon,minLat,maxLon,maxLat.
+func parseBBox(v string) (*models.BoundingBox, error) {
+	parts := strings.Split(v, ",")
+	if len(parts) != 4 {
+		return nil, errors.New("bbox must have 4 values")
+	}
+
+	var vals [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = f
+	}
+
+	b := &models.BoundingBox{MinLon: vals[0], MinLat: vals[1], MaxLon: vals[2], MaxLat: vals[3]}
+	if b.MinLat > b.MaxLat || b.MinLon > b.MaxLon ||
+		b.MinLat < -90 || b.MaxLat > 90 || b.MinLon < -180 || b.MaxLon > 180 {
+		return nil, errors.New("bbox out of range")
+	}
+	return b, nil
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MQTTCredentialHandler mengelola kredensial broker untuk tracker dan melayani hook backend HTTP
+// plugin auth Mosquitto (mosquitto-go-auth, params_mode json, response_mode status).
+type MQTTCredentialHandler struct {
+	svc *service.MQTTCredentialService
+}
+
+func NewMQTTCredentialHandler(svc *service.MQTTCredentialService) *MQTTCredentialHandler {
+	return &MQTTCredentialHandler{svc: svc}
+}
+
+type mqttCredentialRequest struct {
+	Username string `json:"username"`
+}
+
+// mqttAuthRequest adalah body yang dikirim plugin auth broker ke hook.
+type mqttAuthRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	ClientID string `json:"clientid"`
+	Topic    string `json:"topic"`
+	Acc      int    `json:"acc"`
+}
+
+func (h *MQTTCredentialHandler) RegisterRoutes(r *gin.Engine) {
+	m := r.Group("/mqtt-credentials")
+	{
+		m.GET("", h.List)
+		m.POST("", h.Create)
+		m.DELETE("/:credential_id", h.Revoke)
+	}
+}
+
+// RegisterHookRoutes mendaftarkan hook plugin auth broker: 200 = izinkan, 403 = tolak. Hook tidak
+// memakai token API, jadi r harus engine di listener internal yang hanya terjangkau broker, bukan
+// engine API publik.
+func (h *MQTTCredentialHandler) RegisterHookRoutes(r *gin.Engine) {
+	hook := r.Group("/mqtt/auth")
+	{
+		hook.POST("/user", h.AuthUser)
+		hook.POST("/superuser", h.AuthSuperuser)
+		hook.POST("/acl", h.AuthACL)
+	}
+}
+
+func (h *MQTTCredentialHandler) List(c *gin.Context) {
+	creds, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "failed to list mqtt credentials")
+		return
+	}
+	if creds == nil {
+		creds = []models.MQTTCredential{}
+	}
+	c.JSON(http.StatusOK, creds)
+}
+
+// Create menerbitkan kredensial baru. Field "password" di respons hanya ditampilkan sekali.
+func (h *MQTTCredentialHandler) Create(c *gin.Context) {
+	var req mqttCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	cred, err := h.svc.Create(c.Request.Context(), req.Username)
+	if err != nil {
+		writeError(c, err, "failed to create mqtt credential")
+		return
+	}
+	c.JSON(http.StatusCreated, cred)
+}
+
+func (h *MQTTCredentialHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("credential_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credential_id"})
+		return
+	}
+
+	cred, err := h.svc.Revoke(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err, "failed to revoke mqtt credential")
+		return
+	}
+	c.JSON(http.StatusOK, cred)
+}
+
+func (h *MQTTCredentialHandler) AuthUser(c *gin.Context) {
+	var req mqttAuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	ok, err := h.svc.Authenticate(c.Request.Context(), req.Username, req.Password)
+	h.answer(c, ok, err, "auth", req)
+}
+
+func (h *MQTTCredentialHandler) AuthSuperuser(c *gin.Context) {
+	var req mqttAuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	h.answer(c, h.svc.IsSuperuser(req.Username), nil, "superuser", req)
+}
+
+func (h *MQTTCredentialHandler) AuthACL(c *gin.Context) {
+	var req mqttAuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	ok, err := h.svc.CheckACL(c.Request.Context(), req.Username, req.Topic, req.Acc)
+	h.answer(c, ok, err, "acl", req)
+}
+
+// answer menulis jawaban hook. Error internal dijawab 500 supaya plugin tidak meng-cache penolakan.
+func (h *MQTTCredentialHandler) answer(c *gin.Context, ok bool, err error, check string, req mqttAuthRequest) {
+	switch {
+	case err != nil:
+		log.Printf("mqtt %s check for %s failed: %v", check, req.Username, err)
+		c.Status(http.StatusInternalServerError)
+	case ok:
+		c.Status(http.StatusOK)
+	default:
+		log.Printf("mqtt %s denied for %s (client %s, topic %q)", check, req.Username, req.ClientID, req.Topic)
+		c.Status(http.StatusForbidden)
+	}
+}
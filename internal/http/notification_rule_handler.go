@@ -0,0 +1,202 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationRuleHandler struct {
+	svc *service.NotificationRuleService
+}
+
+func NewNotificationRuleHandler(svc *service.NotificationRuleService) *NotificationRuleHandler {
+	return &NotificationRuleHandler{svc: svc}
+}
+
+type notificationRuleRequest struct {
+	Name          string                      `json:"name"`
+	Active        *bool                       `json:"active"`
+	EventTypes    []string                    `json:"event_types"`
+	GeofenceIDs   []int64                     `json:"geofence_ids"`
+	VehicleGroups []string                    `json:"vehicle_groups"`
+	TimeStart     string                      `json:"time_start"`
+	TimeEnd       string                      `json:"time_end"`
+	Timezone      string                      `json:"timezone"`
+	MinSpeedKmh   *float64                    `json:"min_speed_kmh"`
+	MinDurationS  *int64                      `json:"min_duration_s"`
+	CooldownS     int64                       `json:"cooldown_s"`
+	Actions       []models.NotificationAction `json:"actions"`
+}
+
+func (req notificationRuleRequest) toModel() models.NotificationRule {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return models.NotificationRule{
+		Name:          req.Name,
+		Active:        active,
+		EventTypes:    req.EventTypes,
+		GeofenceIDs:   req.GeofenceIDs,
+		VehicleGroups: req.VehicleGroups,
+		TimeStart:     req.TimeStart,
+		TimeEnd:       req.TimeEnd,
+		Timezone:      req.Timezone,
+		MinSpeedKmh:   req.MinSpeedKmh,
+		MinDurationS:  req.MinDurationS,
+		CooldownS:     req.CooldownS,
+		Actions:       req.Actions,
+	}
+}
+
+// evaluateRequest: event wajib; rule (aturan belum disimpan) atau rule_id opsional.
+type evaluateRequest struct {
+	Event  json.RawMessage          `json:"event"`
+	RuleID int64                    `json:"rule_id"`
+	Rule   *notificationRuleRequest `json:"rule"`
+}
+
+func (h *NotificationRuleHandler) RegisterRoutes(r *gin.Engine) {
+	n := r.Group("/notification-rules")
+	{
+		n.GET("", h.List)
+		n.POST("", h.Create)
+		n.POST("/evaluate", h.Evaluate)
+		n.GET("/:rule_id", h.Get)
+		n.PUT("/:rule_id", h.Update)
+		n.DELETE("/:rule_id", h.Delete)
+		n.GET("/:rule_id/notifications", h.ListNotifications)
+	}
+}
+
+func (h *NotificationRuleHandler) List(c *gin.Context) {
+	rules, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "failed to list notification rules")
+		return
+	}
+	if rules == nil {
+		rules = []models.NotificationRule{}
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *NotificationRuleHandler) Create(c *gin.Context) {
+	var req notificationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	rule, err := h.svc.Create(c.Request.Context(), req.toModel())
+	if err != nil {
+		writeError(c, err, "failed to create notification rule")
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *NotificationRuleHandler) Get(c *gin.Context) {
+	id, ok := ruleID(c)
+	if !ok {
+		return
+	}
+
+	rule, err := h.svc.Get(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err, "failed to get notification rule")
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *NotificationRuleHandler) Update(c *gin.Context) {
+	id, ok := ruleID(c)
+	if !ok {
+		return
+	}
+	var req notificationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	rule := req.toModel()
+	rule.ID = id
+
+	updated, err := h.svc.Update(c.Request.Context(), rule)
+	if err != nil {
+		writeError(c, err, "failed to update notification rule")
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *NotificationRuleHandler) Delete(c *gin.Context) {
+	id, ok := ruleID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+		writeError(c, err, "failed to delete notification rule")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *NotificationRuleHandler) ListNotifications(c *gin.Context) {
+	id, ok := ruleID(c)
+	if !ok {
+		return
+	}
+
+	notifications, err := h.svc.ListNotifications(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err, "failed to list notifications")
+		return
+	}
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+	c.JSON(http.StatusOK, notifications)
+}
+
+// Evaluate menjalankan dry-run aturan terhadap contoh event. Tidak ada notifikasi yang dikirim.
+func (h *NotificationRuleHandler) Evaluate(c *gin.Context) {
+	var req evaluateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	if len(req.Event) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event is required"})
+		return
+	}
+	var rule *models.NotificationRule
+	if req.Rule != nil {
+		r := req.Rule.toModel()
+		rule = &r
+	}
+
+	result, err := h.svc.Evaluate(c.Request.Context(), req.Event, req.RuleID, rule)
+	if err != nil {
+		writeError(c, err, "failed to evaluate notification rules")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func ruleID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("rule_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule_id"})
+		return 0, false
+	}
+	return id, true
+}
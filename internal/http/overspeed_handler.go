@@ -0,0 +1,107 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OverspeedHandler struct {
+	svc *service.OverspeedService
+}
+
+func NewOverspeedHandler(svc *service.OverspeedService) *OverspeedHandler {
+	return &OverspeedHandler{svc: svc}
+}
+
+type speedLimitRequest struct {
+	LimitKmh float64 `json:"limit_kmh"`
+}
+
+func (h *OverspeedHandler) RegisterRoutes(r *gin.Engine) {
+	l := r.Group("/speed-limits")
+	{
+		l.GET("", h.ListLimits)
+		l.PUT("/:vehicle_type", h.SetLimit)
+		l.DELETE("/:vehicle_type", h.DeleteLimit)
+	}
+
+	r.GET("/overspeeds", h.List)
+	r.GET("/vehicles/:vehicle_id/overspeeds", h.List)
+}
+
+func (h *OverspeedHandler) ListLimits(c *gin.Context) {
+	limits, err := h.svc.ListLimits(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "failed to list speed limits")
+		return
+	}
+	if limits == nil {
+		limits = []models.SpeedLimit{}
+	}
+	c.JSON(http.StatusOK, limits)
+}
+
+func (h *OverspeedHandler) SetLimit(c *gin.Context) {
+	var req speedLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	l, err := h.svc.SetLimit(c.Request.Context(), models.SpeedLimit{
+		VehicleType: c.Param("vehicle_type"),
+		LimitKmh:    req.LimitKmh,
+	})
+	if err != nil {
+		writeError(c, err, "failed to set speed limit")
+		return
+	}
+	c.JSON(http.StatusOK, l)
+}
+
+func (h *OverspeedHandler) DeleteLimit(c *gin.Context) {
+	if err := h.svc.DeleteLimit(c.Request.Context(), c.Param("vehicle_type")); err != nil {
+		writeError(c, err, "failed to delete speed limit")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// List melayani log overspeed seluruh armada (filter vehicle_id opsional) maupun per kendaraan.
+func (h *OverspeedHandler) List(c *gin.Context) {
+	start, err1 := strconv.ParseInt(c.Query("start"), 10, 64)
+	end, err2 := strconv.ParseInt(c.Query("end"), 10, 64)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start/end query param"})
+		return
+	}
+
+	filter := models.OverspeedFilter{VehicleID: c.Query("vehicle_id")}
+	if id := c.Param("vehicle_id"); id != "" {
+		filter.VehicleID = id
+	}
+	if v := c.Query("min_duration"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid min_duration query param"})
+			return
+		}
+		filter.MinDuration = int64(d / time.Second)
+	}
+
+	events, err := h.svc.List(c.Request.Context(), start, end, filter)
+	if err != nil {
+		writeError(c, err, "failed to list overspeed events")
+		return
+	}
+	if events == nil {
+		events = []models.Overspeed{}
+	}
+	c.JSON(http.StatusOK, events)
+}
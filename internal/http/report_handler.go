@@ -0,0 +1,33 @@
+package http
+
+import (
+	"net/http"
+
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportHandler struct {
+	distance *service.DistanceService
+}
+
+func NewReportHandler(distance *service.DistanceService) *ReportHandler {
+	return &ReportHandler{distance: distance}
+}
+
+func (h *ReportHandler) RegisterRoutes(r *gin.Engine) {
+	rep := r.Group("/reports")
+	{
+		rep.GET("/distance", h.Distance)
+	}
+}
+
+func (h *ReportHandler) Distance(c *gin.Context) {
+	report, err := h.distance.Report(c.Request.Context(), c.Query("from"), c.Query("to"), c.Query("group"))
+	if err != nil {
+		writeError(c, err, "failed to build distance report")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StopHandler struct {
+	svc *service.StopService
+}
+
+func NewStopHandler(svc *service.StopService) *StopHandler {
+	return &StopHandler{svc: svc}
+}
+
+func (h *StopHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/vehicles/:vehicle_id/stops", h.List)
+}
+
+func (h *StopHandler) List(c *gin.Context) {
+	start, err1 := strconv.ParseInt(c.Query("start"), 10, 64)
+	end, err2 := strconv.ParseInt(c.Query("end"), 10, 64)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start/end query param"})
+		return
+	}
+
+	filter := models.StopFilter{Type: c.Query("type")}
+	if v := c.Query("min_duration"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid min_duration query param"})
+			return
+		}
+		filter.MinDuration = int64(d / time.Second)
+	}
+
+	stops, err := h.svc.List(c.Request.Context(), c.Param("vehicle_id"), start, end, filter)
+	if err != nil {
+		writeError(c, err, "failed to list stops")
+		return
+	}
+	if stops == nil {
+		stops = []models.Stop{}
+	}
+	c.JSON(http.StatusOK, stops)
+}
@@ -0,0 +1,171 @@
+package http
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// streamHeartbeat menjaga koneksi idle tetap hidup melewati proxy / load balancer.
+	streamHeartbeat = 15 * time.Second
+	wsWriteTimeout  = 10 * time.Second
+)
+
+type StreamHandler struct {
+	svc      *service.StreamService
+	upgrader websocket.Upgrader
+}
+
+// NewStreamHandler membuat handler stream. allowedOrigins membatasi Origin untuk WebSocket;
+// kosong = semua origin diizinkan.
+func NewStreamHandler(svc *service.StreamService, allowedOrigins []string) *StreamHandler {
+	return &StreamHandler{
+		svc: svc,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				return len(allowedOrigins) == 0 || slices.Contains(allowedOrigins, r.Header.Get("Origin"))
+			},
+		},
+	}
+}
+
+func (h *StreamHandler) RegisterRoutes(r *gin.Engine) {
+	s := r.Group("/stream")
+	{
+		s.GET("/locations", h.SSE)
+		s.GET("/locations/ws", h.WebSocket)
+	}
+}
+
+// SSE mengirim pesan sebagai Server-Sent Events: nama event "location" atau nama event armada,
+// data berisi payload JSON aslinya.
+func (h *StreamHandler) SSE(c *gin.Context) {
+	sub, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer h.svc.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // matikan buffering nginx
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				return false
+			}
+			c.SSEvent(msg.Event, msg.Data)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+// WebSocket mengirim setiap pesan sebagai satu frame JSON {type, event, data}. Pesan dari klien diabaikan.
+func (h *StreamHandler) WebSocket(c *gin.Context) {
+	sub, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer h.svc.Unsubscribe(sub)
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade sudah menulis respons error ke klien
+		log.Printf("websocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// pembaca diperlukan untuk memproses frame close / pong dari klien
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// subscribe membaca filter dari query (vehicle_id, group, bbox, types; daftar dipisah koma atau
+// parameter berulang) dan mendaftarkan koneksi.
+func (h *StreamHandler) subscribe(c *gin.Context) (*service.StreamSubscription, bool) {
+	filter := service.StreamFilter{
+		VehicleIDs: queryList(c, "vehicle_id"),
+		Groups:     queryList(c, "group"),
+		Types:      queryList(c, "types"),
+	}
+	if v := c.Query("bbox"); v != "" {
+		bbox, err := parseBBox(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid bbox query param"})
+			return nil, false
+		}
+		filter.BBox = bbox
+	}
+
+	sub, err := h.svc.Subscribe(c.Request.Context(), filter)
+	if err != nil {
+		writeError(c, err, "failed to open stream")
+		return nil, false
+	}
+	return sub, true
+}
+
+// queryList menggabungkan parameter query berulang dan nilai yang dipisah koma.
+func queryList(c *gin.Context, key string) []string {
+	var result []string
+	for _, v := range c.QueryArray(key) {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
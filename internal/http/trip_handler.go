@@ -0,0 +1,167 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sistem-manajemen-armada/internal/export"
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TripHandler struct {
+	svc       *service.TripService
+	locations *service.LocationService
+	tz        *time.Location
+}
+
+// NewTripHandler membuat handler perjalanan. locations dipakai untuk jalur perjalanan pada
+// ekspor GeoJSON / GPX / KML, tz untuk waktu lokal pada ekspor CSV / XLSX.
+func NewTripHandler(svc *service.TripService, locations *service.LocationService, tz *time.Location) *TripHandler {
+	return &TripHandler{svc: svc, locations: locations, tz: tz}
+}
+
+func (h *TripHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/trips", h.List)
+	r.GET("/vehicles/:vehicle_id/trips", h.List)
+}
+
+func (h *TripHandler) List(c *gin.Context) {
+	start, err1 := strconv.ParseInt(c.Query("start"), 10, 64)
+	end, err2 := strconv.ParseInt(c.Query("end"), 10, 64)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start/end query param"})
+		return
+	}
+
+	// /trips melayani seluruh armada (filter vehicle_id opsional)
+	vehicleID := c.Param("vehicle_id")
+	if vehicleID == "" {
+		vehicleID = c.Query("vehicle_id")
+	}
+
+	format := c.Query("format")
+	geo, isGeo := export.LookupGeoFormat(format)
+	table, isTable := export.LookupTableFormat(format)
+	switch {
+	case format != "" && format != "json" && !isGeo && !isTable:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format query param"})
+		return
+	case isGeo && vehicleID == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "vehicle_id is required for geojson/gpx/kml format"})
+		return
+	}
+	trips, err := h.svc.List(c.Request.Context(), vehicleID, start, end)
+	if err != nil {
+		writeError(c, err, "failed to list trips")
+		return
+	}
+	if isGeo {
+		h.writeTripTracks(c, geo, vehicleID, start, end, trips)
+		return
+	}
+	if isTable {
+		tz, ok := exportLocation(c, h.tz)
+		if !ok {
+			return
+		}
+		writeTripTable(c, table, tz, vehicleID, start, end, trips)
+		return
+	}
+	if trips == nil {
+		trips = []models.Trip{}
+	}
+	c.JSON(http.StatusOK, trips)
+}
+
+// writeTripTracks menulis satu jalur per perjalanan. Titik riwayat dibaca sekali untuk seluruh
+// rentang perjalanan lalu dibagi per perjalanan.
+func (h *TripHandler) writeTripTracks(c *gin.Context, format export.GeoFormat, vehicleID string, start, end int64, trips []models.Trip) {
+	var points []models.VehicleLocation
+	if len(trips) > 0 {
+		var err error
+		points, err = h.locations.GetHistory(c.Request.Context(), vehicleID, trips[0].Start, tripEnd(trips[len(trips)-1]), service.HistoryOptions{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query history"})
+			return
+		}
+	}
+
+	tracks := make([]export.Track, 0, len(trips))
+	i := 0
+	for _, t := range trips {
+		for i < len(points) && points[i].Timestamp < t.Start {
+			i++
+		}
+		j := i
+		for j < len(points) && points[j].Timestamp <= tripEnd(t) {
+			j++
+		}
+
+		props := map[string]any{
+			"trip_id":       t.ID,
+			"vehicle_id":    t.VehicleID,
+			"start":         time.Unix(t.Start, 0).UTC().Format(time.RFC3339),
+			"distance_m":    t.DistanceM,
+			"duration_s":    t.DurationS,
+			"max_speed_kmh": t.MaxSpeedKmh,
+			"avg_speed_kmh": t.AvgSpeedKmh,
+		}
+		if t.End != nil {
+			props["end"] = time.Unix(*t.End, 0).UTC().Format(time.RFC3339)
+		}
+		if t.DriverID != "" {
+			props["driver_id"] = t.DriverID
+		}
+		tracks = append(tracks, export.Track{
+			Name:       fmt.Sprintf("Trip %d", t.ID),
+			Properties: props,
+			Points:     points[i:j],
+		})
+		i = j
+	}
+
+	writeTracks(c, format, exportFilename(vehicleID, "trips", start, end, format.Ext), vehicleID+" trips", tracks)
+}
+
+// writeTripTable menulis ringkasan perjalanan sebagai CSV / XLSX dengan waktu lokal.
+func writeTripTable(c *gin.Context, format export.TableFormat, tz *time.Location, vehicleID string, start, end int64, trips []models.Trip) {
+	header := []any{"trip_id", "vehicle_id", "driver_id", "start_local", "end_local", "start", "end",
+		"duration", "duration_s", "distance_km", "max_speed_kmh", "avg_speed_kmh",
+		"start_latitude", "start_longitude", "end_latitude", "end_longitude"}
+
+	writeTable(c, format, exportFilename(vehicleID, "trips", start, end, format.Ext), "Trips", header, func(w export.TableWriter) error {
+		for _, t := range trips {
+			var endLocal, endTs, endLat, endLon any
+			if t.End != nil {
+				endLocal, endTs = localTime(*t.End, tz), *t.End
+			}
+			if t.EndLocation != nil {
+				endLat, endLon = t.EndLocation.Latitude, t.EndLocation.Longitude
+			}
+			if err := w.WriteRow(t.ID, t.VehicleID, t.DriverID, localTime(t.Start, tz), endLocal, t.Start, endTs,
+				formatDuration(t.DurationS), t.DurationS, t.DistanceM/1000, t.MaxSpeedKmh, t.AvgSpeedKmh,
+				t.StartLocation.Latitude, t.StartLocation.Longitude, endLat, endLon); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// formatDuration memformat durasi detik sebagai jam:menit:detik, mis. 1:05:09.
+func formatDuration(s int64) string {
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+}
+
+// tripEnd mengembalikan akhir perjalanan, atau titik terakhirnya bila masih berjalan.
+func tripEnd(t models.Trip) int64 {
+	if t.End != nil {
+		return *t.End
+	}
+	return t.Start + t.DurationS
+}
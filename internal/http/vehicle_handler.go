@@ -0,0 +1,129 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VehicleHandler struct {
+	svc *service.VehicleService
+}
+
+func NewVehicleHandler(svc *service.VehicleService) *VehicleHandler {
+	return &VehicleHandler{svc: svc}
+}
+
+type vehicleRequest struct {
+	ID          string  `json:"id"`
+	PlateNumber string  `json:"plate_number"`
+	VIN         string  `json:"vin"`
+	Type        string  `json:"type"`
+	CapacityKg  float64 `json:"capacity_kg"`
+	DeviceIMEI  string  `json:"device_imei"`
+	Group       string  `json:"group"`
+	Active      *bool   `json:"active"`
+}
+
+func (req vehicleRequest) toModel() models.Vehicle {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return models.Vehicle{
+		ID:          req.ID,
+		PlateNumber: req.PlateNumber,
+		VIN:         req.VIN,
+		Type:        req.Type,
+		CapacityKg:  req.CapacityKg,
+		DeviceIMEI:  req.DeviceIMEI,
+		Group:       req.Group,
+		Active:      active,
+	}
+}
+
+func (h *VehicleHandler) RegisterRoutes(r *gin.Engine) {
+	v := r.Group("/vehicles")
+	{
+		v.GET("", h.List)
+		v.POST("", h.Create)
+		v.GET("/:vehicle_id", h.Get)
+		v.PUT("/:vehicle_id", h.Update)
+		v.DELETE("/:vehicle_id", h.Delete)
+	}
+}
+
+func (h *VehicleHandler) List(c *gin.Context) {
+	filter := models.VehicleFilter{Group: c.Query("group")}
+	if v := c.Query("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid active query param"})
+			return
+		}
+		filter.Active = &active
+	}
+
+	vehicles, err := h.svc.List(c.Request.Context(), filter)
+	if err != nil {
+		writeError(c, err, "failed to list vehicles")
+		return
+	}
+	if vehicles == nil {
+		vehicles = []models.Vehicle{}
+	}
+	c.JSON(http.StatusOK, vehicles)
+}
+
+func (h *VehicleHandler) Create(c *gin.Context) {
+	var req vehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	v, err := h.svc.Create(c.Request.Context(), req.toModel())
+	if err != nil {
+		writeError(c, err, "failed to create vehicle")
+		return
+	}
+	c.JSON(http.StatusCreated, v)
+}
+
+func (h *VehicleHandler) Get(c *gin.Context) {
+	v, err := h.svc.Get(c.Request.Context(), c.Param("vehicle_id"))
+	if err != nil {
+		writeError(c, err, "failed to get vehicle")
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+func (h *VehicleHandler) Update(c *gin.Context) {
+	var req vehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	// ID diambil dari path, bukan body
+	req.ID = c.Param("vehicle_id")
+
+	v, err := h.svc.Update(c.Request.Context(), req.toModel())
+	if err != nil {
+		writeError(c, err, "failed to update vehicle")
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+func (h *VehicleHandler) Delete(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), c.Param("vehicle_id")); err != nil {
+		writeError(c, err, "failed to delete vehicle")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,172 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	svc *service.WebhookService
+}
+
+func NewWebhookHandler(svc *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{svc: svc}
+}
+
+type webhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Active     *bool    `json:"active"`
+}
+
+func (req webhookRequest) toModel() models.Webhook {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return models.Webhook{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Active:     active,
+	}
+}
+
+func (h *WebhookHandler) RegisterRoutes(r *gin.Engine) {
+	w := r.Group("/webhooks")
+	{
+		w.GET("", h.List)
+		w.POST("", h.Create)
+		w.GET("/:webhook_id", h.Get)
+		w.PUT("/:webhook_id", h.Update)
+		w.DELETE("/:webhook_id", h.Delete)
+		w.GET("/:webhook_id/deliveries", h.ListDeliveries)
+		w.POST("/:webhook_id/deliveries/:delivery_id/redeliver", h.Redeliver)
+	}
+}
+
+func (h *WebhookHandler) List(c *gin.Context) {
+	hooks, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		writeError(c, err, "failed to list webhooks")
+		return
+	}
+	if hooks == nil {
+		hooks = []models.Webhook{}
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+// Create mendaftarkan webhook baru. Field "secret" di respons hanya ditampilkan sekali.
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+
+	w, err := h.svc.Create(c.Request.Context(), req.toModel())
+	if err != nil {
+		writeError(c, err, "failed to create webhook")
+		return
+	}
+	c.JSON(http.StatusCreated, w)
+}
+
+func (h *WebhookHandler) Get(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+
+	w, err := h.svc.Get(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err, "failed to get webhook")
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
+
+func (h *WebhookHandler) Update(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON body"})
+		return
+	}
+	w := req.toModel()
+	w.ID = id
+
+	updated, err := h.svc.Update(c.Request.Context(), w)
+	if err != nil {
+		writeError(c, err, "failed to update webhook")
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+		writeError(c, err, "failed to delete webhook")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.svc.ListDeliveries(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err, "failed to list webhook deliveries")
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// Redeliver menjadwalkan ulang payload pengiriman lama. Pengiriman dilakukan geofence worker.
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery_id"})
+		return
+	}
+
+	d, err := h.svc.Redeliver(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		writeError(c, err, "failed to redeliver webhook")
+		return
+	}
+	c.JSON(http.StatusAccepted, d)
+}
+
+func webhookID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("webhook_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook_id"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,119 @@
+package migrate
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"sistem-manajemen-armada/db"
+)
+
+func TestLoad(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []Migration
+		wantErr string
+	}{
+		{
+			name: "valid",
+			files: fstest.MapFS{
+				"0002_add_index.up.sql":      file("CREATE INDEX"),
+				"0001_create_items.up.sql":   file("CREATE TABLE"),
+				"0001_create_items.down.sql": file("DROP TABLE"),
+				"README.md":                  file("bukan migrasi"),
+				"old/0003_skip.up.sql":       file("diabaikan"),
+			},
+			want: []Migration{
+				{Version: 1, Name: "create_items", Up: "CREATE TABLE", Down: "DROP TABLE"},
+				{Version: 2, Name: "add_index", Up: "CREATE INDEX"},
+			},
+		},
+		{
+			name:    "missing direction",
+			files:   fstest.MapFS{"0001_create_items.sql": file("CREATE TABLE")},
+			wantErr: "missing .up/.down suffix",
+		},
+		{
+			name:    "missing name",
+			files:   fstest.MapFS{"0001.up.sql": file("CREATE TABLE")},
+			wantErr: "expected NNNN_name",
+		},
+		{
+			name:    "invalid version",
+			files:   fstest.MapFS{"v1_create_items.up.sql": file("CREATE TABLE")},
+			wantErr: "invalid version",
+		},
+		{
+			name:    "down without up",
+			files:   fstest.MapFS{"0001_create_items.down.sql": file("DROP TABLE")},
+			wantErr: "missing up file",
+		},
+		{
+			name: "up and down with different names",
+			files: fstest.MapFS{
+				"0001_create_items.up.sql":    file("CREATE TABLE"),
+				"0001_create_things.down.sql": file("DROP TABLE"),
+			},
+			wantErr: "conflicting names",
+		},
+		{
+			name: "duplicate version",
+			files: fstest.MapFS{
+				"0001_create_items.up.sql": file("CREATE TABLE"),
+				"001_create_items.up.sql":  file("CREATE TABLE"),
+			},
+			wantErr: "duplicate up file",
+		},
+		{
+			name: "gap",
+			files: fstest.MapFS{
+				"0001_create_items.up.sql": file("CREATE TABLE"),
+				"0003_add_index.up.sql":    file("CREATE INDEX"),
+			},
+			wantErr: "versions must have no gaps",
+		},
+		{
+			name:    "not starting at 1",
+			files:   fstest.MapFS{"0002_create_items.up.sql": file("CREATE TABLE")},
+			wantErr: "versions must have no gaps",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadEmbedded(t *testing.T) {
+	sub, err := fs.Sub(db.Migrations, "migrations")
+	if err != nil {
+		t.Fatalf("fs.Sub: %v", err)
+	}
+	migrations, err := Load(sub)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, m := range migrations {
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
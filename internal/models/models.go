@@ -1,15 +1,304 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type VehicleLocation struct {
-	ID        int64   `json:"id,omitempty"`
-	VehicleID string  `json:"vehicle_id"`
+	ID        int64    `json:"id,omitempty"`
+	TenantID  string   `json:"tenant_id,omitempty"`
+	VehicleID string   `json:"vehicle_id"`
+	DeviceID  string   `json:"device_id,omitempty"`
+	DriverID  string   `json:"driver_id,omitempty"` // dari shift yang aktif pada timestamp titik
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Timestamp int64    `json:"timestamp"`
+	Speed     *float64 `json:"speed,omitempty"`    // km/jam, bila dilaporkan tracker
+	Ignition  *bool    `json:"ignition,omitempty"` // status kunci kontak, bila dilaporkan tracker
+	Odometer  *float64 `json:"odometer,omitempty"` // km, bila dilaporkan tracker
+}
+
+type Vehicle struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id"`
+	PlateNumber string    `json:"plate_number"`
+	VIN         string    `json:"vin,omitempty"`
+	Type        string    `json:"type"`
+	CapacityKg  float64   `json:"capacity_kg"`
+	DeviceIMEI  string    `json:"device_imei,omitempty"`
+	Group       string    `json:"group"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// VehicleFilter memfilter daftar kendaraan. Nilai kosong berarti tidak difilter.
+type VehicleFilter struct {
+	Group  string
+	Active *bool
+	// TenantID membatasi ke kendaraan milik tenant ini; kosong = semua tenant.
+	TenantID string
+	// Groups membatasi ke salah satu grup ini; nil = tidak difilter, kosong = tidak ada kendaraan.
+	Groups []string
+}
+
+type Device struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id"`
+	IMEI     string `json:"imei,omitempty"`
+	Model    string `json:"model"`
+	Active   bool   `json:"active"`
+	// SigningAlgorithm: "hmac-sha256" atau "ed25519" bila payload device wajib ditandatangani.
+	SigningAlgorithm string    `json:"signing_algorithm,omitempty"`
+	SigningKey       []byte    `json:"-"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// DeviceSigningKey adalah hasil pemasangan kunci tanda tangan. Secret HMAC hanya dikembalikan
+// sekali saat dibuat.
+type DeviceSigningKey struct {
+	DeviceID  string `json:"device_id"`
+	Algorithm string `json:"algorithm"`
+	Secret    string `json:"secret,omitempty"`
+}
+
+// DeviceAssignment memasangkan device ke kendaraan dalam rentang [Start, End). End nil = masih terpasang.
+type DeviceAssignment struct {
+	ID        int64     `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	DeviceID  string    `json:"device_id"`
+	VehicleID string    `json:"vehicle_id"`
+	Start     int64     `json:"start"`
+	End       *int64    `json:"end,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Driver struct {
+	ID            string    `json:"id"`
+	TenantID      string    `json:"tenant_id"`
+	Name          string    `json:"name"`
+	LicenseNumber string    `json:"license_number"`
+	LicenseExpiry string    `json:"license_expiry"` // YYYY-MM-DD
+	Phone         string    `json:"phone,omitempty"`
+	Email         string    `json:"email,omitempty"`
+	Active        bool      `json:"active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// DriverShift menugaskan pengemudi ke kendaraan dalam rentang [Start, End). End nil = shift masih berjalan.
+type DriverShift struct {
+	ID        int64     `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	DriverID  string    `json:"driver_id"`
+	VehicleID string    `json:"vehicle_id"`
+	Start     int64     `json:"start"`
+	End       *int64    `json:"end,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ShiftFilter memfilter daftar shift. Start/End (epoch detik, 0 = abaikan) memilih shift
+// yang beririsan dengan rentang tersebut.
+type ShiftFilter struct {
+	// TenantID membatasi ke shift milik tenant ini; kosong = semua tenant.
+	TenantID  string
+	DriverID  string
+	VehicleID string
+	Start     int64
+	End       int64
+}
+
+// Coordinate adalah satu titik lintang/bujur.
+type Coordinate struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
-	Timestamp int64   `json:"timestamp"`
+}
+
+// Trip adalah satu perjalanan kendaraan hasil segmentasi riwayat lokasi. End nil = masih berjalan;
+// Duration dan jarak untuk perjalanan yang masih berjalan dihitung sampai titik terakhir.
+type Trip struct {
+	ID            int64       `json:"id"`
+	TenantID      string      `json:"tenant_id"`
+	VehicleID     string      `json:"vehicle_id"`
+	DriverID      string      `json:"driver_id,omitempty"`
+	Start         int64       `json:"start"`
+	End           *int64      `json:"end,omitempty"`
+	StartLocation Coordinate  `json:"start_location"`
+	EndLocation   *Coordinate `json:"end_location,omitempty"`
+	DistanceM     float64     `json:"distance_m"`
+	DurationS     int64       `json:"duration_s"`
+	MaxSpeedKmh   float64     `json:"max_speed_kmh"`
+	AvgSpeedKmh   float64     `json:"avg_speed_kmh"`
+	PointCount    int         `json:"point_count"`
+
+	// State segmentasi untuk perjalanan yang masih terbuka.
+	Last    VehicleLocation  `json:"-"`
+	Stopped *VehicleLocation `json:"-"` // titik pertama sejak kendaraan berhenti
+}
+
+// Geofence adalah area lingkaran bernama, mis. lokasi pelanggan atau gudang.
+type Geofence struct {
+	ID            int64     `json:"id"`
+	TenantID      string    `json:"tenant_id"`
+	Name          string    `json:"name"`
+	Latitude      float64   `json:"latitude"`
+	Longitude     float64   `json:"longitude"`
+	RadiusM       float64   `json:"radius_m"`
+	SpeedLimitKmh *float64  `json:"speed_limit_kmh,omitempty"` // batas kecepatan zona, nil = tidak ada
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Stop adalah periode kendaraan diam. Type "idle" bila kunci kontak on, "stop" bila off / tidak
+// dilaporkan. End nil = kendaraan masih diam; DurationS dihitung sampai titik terakhir.
+type Stop struct {
+	ID           int64      `json:"id"`
+	TenantID     string     `json:"tenant_id"`
+	VehicleID    string     `json:"vehicle_id"`
+	DriverID     string     `json:"driver_id,omitempty"`
+	Type         string     `json:"type"`
+	Start        int64      `json:"start"`
+	End          *int64     `json:"end,omitempty"`
+	DurationS    int64      `json:"duration_s"`
+	Location     Coordinate `json:"location"`
+	GeofenceID   *int64     `json:"geofence_id,omitempty"`
+	GeofenceName string     `json:"geofence_name,omitempty"`
+
+	Last int64 `json:"-"` // timestamp titik diam terakhir yang sudah diproses
+}
+
+// StopFilter memfilter daftar periode diam. Nilai kosong berarti tidak difilter.
+type StopFilter struct {
+	Type        string
+	MinDuration int64 // detik
+}
+
+// SpeedLimit adalah batas kecepatan untuk satu jenis kendaraan (Vehicle.Type).
+type SpeedLimit struct {
+	TenantID    string    `json:"tenant_id"`
+	VehicleType string    `json:"vehicle_type"`
+	LimitKmh    float64   `json:"limit_kmh"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Overspeed adalah periode kendaraan melebihi batas kecepatan yang berlaku. LimitKmh adalah batas
+// terendah dari batas global, jenis kendaraan, dan zona; GeofenceID terisi bila batas zona yang berlaku.
+// End nil = kendaraan masih melebihi batas; DurationS dihitung sampai titik terakhir.
+type Overspeed struct {
+	ID            int64      `json:"id"`
+	TenantID      string     `json:"tenant_id"`
+	VehicleID     string     `json:"vehicle_id"`
+	DriverID      string     `json:"driver_id,omitempty"`
+	Start         int64      `json:"start"`
+	End           *int64     `json:"end,omitempty"`
+	DurationS     int64      `json:"duration_s"`
+	LimitKmh      float64    `json:"limit_kmh"`
+	PeakSpeedKmh  float64    `json:"peak_speed_kmh"`
+	PeakAt        int64      `json:"peak_at"`
+	StartLocation Coordinate `json:"start_location"`
+	PeakLocation  Coordinate `json:"peak_location"`
+	GeofenceID    *int64     `json:"geofence_id,omitempty"`
+	GeofenceName  string     `json:"geofence_name,omitempty"`
+
+	Last int64 `json:"-"` // timestamp titik terakhir yang sudah diproses
+}
+
+// OverspeedFilter memfilter log overspeed. Nilai kosong berarti tidak difilter.
+type OverspeedFilter struct {
+	// TenantID membatasi ke kendaraan milik tenant ini; kosong = semua tenant.
+	TenantID    string
+	VehicleID   string
+	MinDuration int64 // detik
+}
+
+// DailyDistance adalah akumulasi jarak satu kendaraan pada satu hari (zona waktu laporan).
+type DailyDistance struct {
+	VehicleID        string
+	Group            string
+	Date             string // YYYY-MM-DD
+	GPSDistanceM     float64
+	PointCount       int
+	RejectedSegments int
+	OdometerStart    *float64 // km
+	OdometerEnd      *float64 // km
+}
+
+// DistanceDay adalah jarak tempuh satu kendaraan pada satu hari. DistanceM diambil dari odometer
+// bila bacaannya tersedia dan masuk akal (Source "odometer"), selain itu dari GPS.
+type DistanceDay struct {
+	Date              string   `json:"date"`
+	DistanceM         float64  `json:"distance_m"`
+	Source            string   `json:"source"`
+	GPSDistanceM      float64  `json:"gps_distance_m"`
+	OdometerDistanceM *float64 `json:"odometer_distance_m,omitempty"`
+	PointCount        int      `json:"point_count"`
+	RejectedSegments  int      `json:"rejected_segments"`
+}
+
+// VehicleDistanceReport adalah total jarak satu kendaraan beserta rincian per hari.
+type VehicleDistanceReport struct {
+	VehicleID string        `json:"vehicle_id"`
+	Group     string        `json:"group,omitempty"`
+	DistanceM float64       `json:"distance_m"`
+	Days      []DistanceDay `json:"days"`
+}
+
+// DistanceTotal adalah total jarak seluruh kendaraan pada satu hari.
+type DistanceTotal struct {
+	Date      string  `json:"date"`
+	DistanceM float64 `json:"distance_m"`
+}
+
+type DistanceReport struct {
+	From           string                  `json:"from"`
+	To             string                  `json:"to"`
+	Timezone       string                  `json:"timezone"`
+	Group          string                  `json:"group,omitempty"`
+	TotalDistanceM float64                 `json:"total_distance_m"`
+	Days           []DistanceTotal         `json:"days"`
+	Vehicles       []VehicleDistanceReport `json:"vehicles"`
+}
+
+// TripEvent dipublish ke RabbitMQ saat perjalanan dimulai / selesai. Field dasarnya sama
+// dengan GeofenceEvent supaya consumer yang sama bisa membacanya.
+type TripEvent struct {
+	TenantID  string     `json:"tenant_id"`
+	VehicleID string     `json:"vehicle_id"`
+	DriverID  string     `json:"driver_id,omitempty"`
+	Event     string     `json:"event"` // "trip_started" / "trip_ended"
+	Location  Coordinate `json:"location"`
+	Timestamp int64      `json:"timestamp"`
+	Trip      Trip       `json:"trip"`
+}
+
+// OverspeedEvent dipublish ke RabbitMQ saat kendaraan mulai / berhenti melebihi batas kecepatan.
+type OverspeedEvent struct {
+	TenantID  string     `json:"tenant_id"`
+	VehicleID string     `json:"vehicle_id"`
+	DriverID  string     `json:"driver_id,omitempty"`
+	Event     string     `json:"event"` // "overspeed_started" / "overspeed_ended"
+	Location  Coordinate `json:"location"`
+	Timestamp int64      `json:"timestamp"`
+	Overspeed Overspeed  `json:"overspeed"`
+}
+
+// TamperEvent dikirim saat payload device ditolak karena tanda tangan tidak ada atau tidak valid.
+type TamperEvent struct {
+	TenantID  string     `json:"tenant_id"`
+	VehicleID string     `json:"vehicle_id"`
+	DeviceID  string     `json:"device_id,omitempty"`
+	Event     string     `json:"event"` // "tamper_suspected"
+	Reason    string     `json:"reason"`
+	Location  Coordinate `json:"location"`
+	Timestamp int64      `json:"timestamp"`
 }
 
 type GeofenceEvent struct {
+	TenantID  string `json:"tenant_id"`
 	VehicleID string `json:"vehicle_id"`
+	DriverID  string `json:"driver_id,omitempty"`
 	Event     string `json:"event"` // "geofence_entry"
 
 	Location struct {
@@ -19,3 +308,219 @@ type GeofenceEvent struct {
 
 	Timestamp int64 `json:"timestamp"`
 }
+
+// LocationArchive adalah entri manifest untuk satu file arsip vehicle_locations.
+type LocationArchive struct {
+	ID         int64    `json:"id"`
+	RangeStart int64    `json:"range_start"`
+	RangeEnd   int64    `json:"range_end"` // eksklusif
+	ObjectKey  string   `json:"object_key"`
+	RowCount   int64    `json:"row_count"`
+	SizeBytes  int64    `json:"size_bytes"`
+	SHA256     string   `json:"sha256"`
+	VehicleIDs []string `json:"vehicle_ids"`
+	// TenantVehicleIDs berisi pasangan "{tenant}/{vehicle_id}" yang ada di file arsip.
+	TenantVehicleIDs []string  `json:"tenant_vehicle_ids"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// HistoryCursor menandai titik terakhir yang sudah diterima client, urut (timestamp, id).
+type HistoryCursor struct {
+	Timestamp int64
+	ID        int64
+}
+
+// BoundingBox adalah area peta persegi dalam derajat.
+type BoundingBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+// Contains mengecek apakah koordinat berada di dalam bounding box.
+func (b BoundingBox) Contains(lat, lon float64) bool {
+	return lat >= b.MinLat && lat <= b.MaxLat && lon >= b.MinLon && lon <= b.MaxLon
+}
+
+// LatestFilter memfilter posisi terakhir armada.
+type LatestFilter struct {
+	BBox  *BoundingBox
+	Group string
+	// TenantID membatasi ke kendaraan milik tenant ini; kosong = semua tenant.
+	TenantID string
+	// Groups membatasi ke salah satu grup ini; nil = tidak difilter, kosong = tidak ada kendaraan.
+	Groups []string
+	// UpdatedBefore / UpdatedSince (epoch detik, 0 = abaikan) memfilter berdasarkan timestamp posisi.
+	UpdatedBefore int64
+	UpdatedSince  int64
+}
+
+// LatestLocation adalah posisi terakhir kendaraan beserta status stale.
+type LatestLocation struct {
+	VehicleLocation
+	Stale bool `json:"stale"`
+}
+
+// VehicleDistance adalah posisi terakhir kendaraan beserta jaraknya ke titik acuan.
+type VehicleDistance struct {
+	VehicleLocation
+	DistanceM float64 `json:"distance_m"`
+}
+
+// APIKey adalah API key sistem partner. Rahasia key tidak pernah disimpan; Prefix menjadi
+// identitas key yang aman ditampilkan.
+type APIKey struct {
+	ID                 int64      `json:"id"`
+	TenantID           string     `json:"tenant_id"`
+	Name               string     `json:"name"`
+	Prefix             string     `json:"prefix"`
+	Scopes             []string   `json:"scopes"`
+	Groups             []string   `json:"groups"` // null = semua grup kendaraan
+	RateLimitPerMinute int        `json:"rate_limit_per_minute"`
+	CreatedBy          string     `json:"created_by"`
+	CreatedAt          time.Time  `json:"created_at"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt         *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt          *time.Time `json:"revoked_at,omitempty"`
+	Hash               []byte     `json:"-"`
+}
+
+// CreatedAPIKey dikembalikan sekali saat key dibuat; Key tidak bisa dilihat lagi setelahnya.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// MQTTCredential adalah kredensial broker MQTT untuk satu tracker. Username sama dengan ID di topik
+// lokasi, sehingga tracker hanya bisa memakai topiknya sendiri. Password tidak pernah disimpan.
+type MQTTCredential struct {
+	ID        int64      `json:"id"`
+	TenantID  string     `json:"tenant_id"`
+	Username  string     `json:"username"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	Hash      []byte     `json:"-"`
+}
+
+// CreatedMQTTCredential dikembalikan sekali saat kredensial dibuat; Password tidak bisa dilihat lagi.
+type CreatedMQTTCredential struct {
+	MQTTCredential
+	Password string `json:"password"`
+	// Login adalah username yang dipakai tracker saat CONNECT: "{tenant}/{username}".
+	Login string `json:"login"`
+	// Topic adalah topik lokasi yang boleh dipakai tracker.
+	Topic string `json:"topic"`
+}
+
+// Webhook mengirim event armada milik tenant ke URL HTTP. EventTypes kosong = semua event.
+type Webhook struct {
+	ID         int64     `json:"id"`
+	TenantID   string    `json:"tenant_id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	Secret     []byte    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CreatedWebhook dikembalikan sekali saat webhook dibuat, berisi secret HMAC dalam bentuk teks.
+type CreatedWebhook struct {
+	Webhook
+	SigningSecret string `json:"secret"`
+}
+
+// Status pengiriman webhook dan notifikasi.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery adalah satu event yang dikirim (atau akan dikirim ulang) ke satu webhook.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhook_id"`
+	TenantID       string          `json:"tenant_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	RedeliveryOf   *int64          `json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// Jenis aksi aturan notifikasi.
+const (
+	ActionWebhook = "webhook"
+	ActionEmail   = "email"
+	ActionSMS     = "sms"
+	ActionChat    = "chat"
+)
+
+// NotificationAction adalah tujuan notifikasi sebuah aturan.
+type NotificationAction struct {
+	Type   string   `json:"type"`              // webhook, email, sms, chat
+	URL    string   `json:"url,omitempty"`     // webhook dan chat
+	Secret string   `json:"secret,omitempty"`  // webhook: kunci HMAC opsional untuk X-Fleet-Signature
+	ChatID string   `json:"chat_id,omitempty"` // chat: chat_id untuk Bot API Telegram
+	To     []string `json:"to,omitempty"`      // email: alamat email, sms: nomor telepon
+}
+
+// NotificationRule mencocokkan event armada milik tenant dengan kriteria lalu menjalankan Actions.
+// Kriteria kosong / nil = tidak dibatasi.
+type NotificationRule struct {
+	ID            int64    `json:"id"`
+	TenantID      string   `json:"tenant_id"`
+	Name          string   `json:"name"`
+	Active        bool     `json:"active"`
+	EventTypes    []string `json:"event_types"`
+	GeofenceIDs   []int64  `json:"geofence_ids"`   // lokasi event berada di salah satu geofence ini
+	VehicleGroups []string `json:"vehicle_groups"` // kendaraan berada di salah satu grup ini
+	TimeStart     string   `json:"time_start"`     // "HH:MM", jam lokal; boleh melewati tengah malam
+	TimeEnd       string   `json:"time_end"`
+	Timezone      string   `json:"timezone"` // kosong = TIMEZONE server
+	MinSpeedKmh   *float64 `json:"min_speed_kmh,omitempty"`
+	MinDurationS  *int64   `json:"min_duration_s,omitempty"`
+	// CooldownS: notifikasi aturan ini untuk kendaraan yang sama tidak diulang selama ini (detik).
+	CooldownS int64                `json:"cooldown_s"`
+	Actions   []NotificationAction `json:"actions"`
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// Notification adalah satu notifikasi ke satu penerima, dipicu sebuah aturan. Action berisi tepat
+// satu penerima.
+type Notification struct {
+	ID            int64              `json:"id"`
+	RuleID        int64              `json:"rule_id"`
+	TenantID      string             `json:"tenant_id"`
+	VehicleID     string             `json:"vehicle_id"`
+	EventType     string             `json:"event_type"`
+	Action        NotificationAction `json:"action"`
+	Subject       string             `json:"subject,omitempty"`
+	Message       string             `json:"message"`
+	Payload       json.RawMessage    `json:"payload"`
+	Status        string             `json:"status"`
+	Attempts      int                `json:"attempts"`
+	NextAttemptAt time.Time          `json:"next_attempt_at"`
+	LastError     string             `json:"last_error,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+	DeliveredAt   *time.Time         `json:"delivered_at,omitempty"`
+}
+
+// RuleEvaluation adalah hasil dry-run satu aturan terhadap satu event. Reason menjelaskan kriteria
+// yang tidak cocok; Notifications berisi notifikasi yang akan dikirim bila cocok.
+type RuleEvaluation struct {
+	RuleID        int64          `json:"rule_id"`
+	RuleName      string         `json:"rule_name"`
+	Matched       bool           `json:"matched"`
+	Reason        string         `json:"reason,omitempty"`
+	Notifications []Notification `json:"notifications,omitempty"`
+}
@@ -1,7 +1,9 @@
 package mqttclient
 
 import (
+	"fmt"
 	"log"
+	"net/url"
 	"time"
 
 	"sistem-manajemen-armada/internal/config"
@@ -16,6 +18,9 @@ func NewClient(cfg *config.Config) mqtt.Client {
 		SetAutoReconnect(true).
 		SetConnectRetry(true).
 		SetConnectRetryInterval(3 * time.Second)
+	if err := Configure(opts, cfg); err != nil {
+		log.Fatalf("invalid MQTT config: %v", err)
+	}
 
 	client := mqtt.NewClient(opts)
 	if token := client.Connect(); token.Wait() && token.Error() != nil {
@@ -25,3 +30,31 @@ func NewClient(cfg *config.Config) mqtt.Client {
 
 	return client
 }
+
+// Configure mengisi akun layanan dan TLS / mTLS broker dari cfg ke opts. Dipakai juga oleh
+// listener dan mock publisher yang membangun opsi klien sendiri.
+func Configure(opts *mqtt.ClientOptions, cfg *config.Config) error {
+	if cfg.MQTTUsername != "" {
+		opts.SetUsername(cfg.MQTTUsername).SetPassword(cfg.MQTTPassword)
+	}
+
+	tc, err := cfg.MQTTTLS.Client()
+	if err != nil {
+		return err
+	}
+	if tc == nil {
+		return nil
+	}
+	// paho hanya memakai TLS untuk skema ini; dengan tcp:// konfigurasi TLS diam-diam diabaikan
+	u, err := url.Parse(cfg.MQTTBrokerURL)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "ssl", "tls", "tcps", "mqtts", "mqtt+ssl", "wss":
+	default:
+		return fmt.Errorf("MQTT_TLS_* is set but MQTT_BROKER_URL uses %s://, expected ssl:// or tls://", u.Scheme)
+	}
+	opts.SetTLSConfig(tc)
+	return nil
+}
@@ -3,15 +3,49 @@ package rabbitmq
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"net/url"
 	"time"
 
 	"sistem-manajemen-armada/internal/config"
 	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/tlsconfig"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// RoutingKey menambahkan tenant sebagai segmen pertama routing key event, mis.
+// "acme.geofence.entry", supaya consumer bisa memilih event per tenant.
+func RoutingKey(tenantID, key string) string {
+	return tenantID + "." + key
+}
+
+// AllTenants mengembalikan pola binding untuk event key dari semua tenant, mis. "*.geofence.entry".
+func AllTenants(key string) string {
+	return RoutingKey("*", key)
+}
+
+// Dial membuka koneksi ke RabbitMQ, dengan TLS / mTLS bila opsi TLS diisi. amqp091 hanya memakai
+// TLS untuk skema amqps://, jadi opsi TLS dengan URL amqp:// ditolak alih-alih diabaikan.
+func Dial(rawURL string, opts tlsconfig.Options) (*amqp.Connection, error) {
+	tc, err := opts.Client()
+	if err != nil {
+		return nil, err
+	}
+	if tc == nil {
+		return amqp.Dial(rawURL)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "amqps" {
+		return nil, fmt.Errorf("RABBIT_TLS_* is set but RABBIT_URL uses %s://, expected amqps://", u.Scheme)
+	}
+	return amqp.DialTLS(rawURL, tc)
+}
+
 type Client struct {
 	conn    *amqp.Connection
 	channel *amqp.Channel
@@ -27,7 +61,7 @@ func NewClient(cfg *config.Config) *Client {
 
 	// Retry connect ke RabbitMQ (maks 10 kali dengan backoff)
 	for i := 1; i <= 10; i++ {
-		conn, err = amqp.Dial(cfg.RabbitURL)
+		conn, err = Dial(cfg.RabbitURL, cfg.RabbitTLS)
 		if err == nil {
 			ch, err = conn.Channel()
 			if err == nil {
@@ -61,6 +95,19 @@ func NewClient(cfg *config.Config) *Client {
 		log.Fatalf("failed to declare exchange: %v", err)
 	}
 
+	// Exchange lokasi: setiap titik baru di-fanout ke semua consumer (mis. stream di tiap replika API)
+	if err := ch.ExchangeDeclare(
+		cfg.RabbitLocationExchange,
+		"fanout",
+		true,  // durable
+		false, // auto-delete
+		false,
+		false,
+		nil,
+	); err != nil {
+		log.Fatalf("failed to declare location exchange: %v", err)
+	}
+
 	if _, err := ch.QueueDeclare(
 		cfg.RabbitQueue,
 		true,  // durable
@@ -74,7 +121,7 @@ func NewClient(cfg *config.Config) *Client {
 
 	if err := ch.QueueBind(
 		cfg.RabbitQueue,
-		cfg.RabbitRoutingKey,
+		AllTenants(cfg.RabbitRoutingKey),
 		cfg.RabbitExchange,
 		false,
 		nil,
@@ -92,15 +139,29 @@ func NewClient(cfg *config.Config) *Client {
 }
 
 func (c *Client) PublishGeofenceEvent(ctx context.Context, event models.GeofenceEvent) error {
-	body, err := json.Marshal(event)
+	return c.Publish(ctx, event.TenantID, c.cfg.RabbitRoutingKey, event)
+}
+
+// PublishLocation mengirim titik lokasi baru ke exchange lokasi.
+func (c *Client) PublishLocation(ctx context.Context, loc models.VehicleLocation) error {
+	return c.publish(ctx, c.cfg.RabbitLocationExchange, RoutingKey(loc.TenantID, loc.VehicleID), loc)
+}
+
+// Publish mengirim v sebagai JSON ke exchange event dengan routing key tenant (lihat RoutingKey).
+func (c *Client) Publish(ctx context.Context, tenantID, key string, v any) error {
+	return c.publish(ctx, c.cfg.RabbitExchange, RoutingKey(tenantID, key), v)
+}
+
+func (c *Client) publish(ctx context.Context, exchange, routingKey string, v any) error {
+	body, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
 
 	return c.channel.PublishWithContext(
 		ctx,
-		c.cfg.RabbitExchange,
-		c.cfg.RabbitRoutingKey,
+		exchange,
+		routingKey,
 		false,
 		false,
 		amqp.Publishing{
@@ -110,6 +171,60 @@ func (c *Client) PublishGeofenceEvent(ctx context.Context, event models.Geofence
 	)
 }
 
+// Subscribe membuat queue sementara (exclusive, auto-delete) yang menerima semua titik lokasi dan
+// semua event armada. Setiap pemanggil mendapat salinan sendiri; channel ditutup saat ctx selesai
+// atau koneksi terputus.
+func (c *Client) Subscribe(ctx context.Context) (<-chan amqp.Delivery, error) {
+	ch, err := c.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := ch.QueueDeclare(
+		"",    // nama dari server
+		false, // durable
+		true,  // auto-delete
+		true,  // exclusive
+		false,
+		nil,
+	)
+	if err != nil {
+		_ = ch.Close()
+		return nil, err
+	}
+
+	bindings := []struct{ exchange, key string }{
+		{c.cfg.RabbitLocationExchange, ""},
+		{c.cfg.RabbitExchange, "#"},
+	}
+	for _, b := range bindings {
+		if err := ch.QueueBind(q.Name, b.key, b.exchange, false, nil); err != nil {
+			_ = ch.Close()
+			return nil, err
+		}
+	}
+
+	msgs, err := ch.Consume(
+		q.Name,
+		"",
+		true, // auto-ack
+		true, // exclusive
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		_ = ch.Close()
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = ch.Close()
+	}()
+	return msgs, nil
+}
+
 func (c *Client) Channel() *amqp.Channel {
 	return c.channel
 }
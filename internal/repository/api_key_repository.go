@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, k models.APIKey) (*models.APIKey, error)
+	// GetByPrefix mengembalikan key (termasuk yang sudah dicabut) berdasarkan prefix, atau ErrNotFound.
+	GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	// List mengembalikan key milik tenant; tenantID kosong = semua tenant.
+	List(ctx context.Context, tenantID string) ([]models.APIKey, error)
+	// Revoke mencabut key milik tenant (kosong = tenant mana pun); ErrNotFound bila key tidak ada,
+	// milik tenant lain, atau sudah dicabut.
+	Revoke(ctx context.Context, tenantID string, id int64) (*models.APIKey, error)
+	TouchLastUsed(ctx context.Context, id int64, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, tenant_id, name, prefix, key_hash, scopes, groups, rate_limit_per_minute, created_by,
+	created_at, expires_at, last_used_at, revoked_at`
+
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	var k models.APIKey
+	err := row.Scan(&k.ID, &k.TenantID, &k.Name, &k.Prefix, &k.Hash, &k.Scopes, &k.Groups, &k.RateLimitPerMinute, &k.CreatedBy,
+		&k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &k, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, k models.APIKey) (*models.APIKey, error) {
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO api_keys (name, prefix, key_hash, scopes, groups, rate_limit_per_minute, created_by, expires_at, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING `+apiKeyColumns,
+		k.Name, k.Prefix, k.Hash, k.Scopes, k.Groups, k.RateLimitPerMinute, k.CreatedBy, k.ExpiresAt, k.TenantID,
+	)
+	return scanAPIKey(row)
+}
+
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = $1`, prefix)
+	return scanAPIKey(row)
+}
+
+func (r *apiKeyRepository) List(ctx context.Context, tenantID string) ([]models.APIKey, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE ($1 = '' OR tenant_id = $1) ORDER BY id ASC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *k)
+	}
+	return result, rows.Err()
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, tenantID string, id int64) (*models.APIKey, error) {
+	row := r.db.QueryRow(ctx,
+		`UPDATE api_keys SET revoked_at = now()
+		 WHERE id = $1 AND revoked_at IS NULL AND ($2 = '' OR tenant_id = $2)
+		 RETURNING `+apiKeyColumns,
+		id, tenantID,
+	)
+	return scanAPIKey(row)
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE api_keys SET last_used_at = $2 WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2)`,
+		id, at,
+	)
+	return err
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ArchiveSink menerima baris yang diarsipkan lalu menyimpannya ke storage saat Finish.
+type ArchiveSink interface {
+	Write(loc models.VehicleLocation) error
+	// Finish mengunggah file arsip dan mengembalikan entri manifest (tanpa ID).
+	Finish(ctx context.Context) (*models.LocationArchive, error)
+}
+
+type ArchiveRepository interface {
+	// OldestBefore mengembalikan timestamp tertua di vehicle_locations yang < cutoff.
+	OldestBefore(ctx context.Context, cutoff int64) (int64, bool, error)
+	// ArchiveRange mengekspor [start, end) ke sink, mencatat manifest, lalu menghapus baris yang diekspor.
+	// Mengembalikan nil bila rentang kosong.
+	ArchiveRange(ctx context.Context, start, end int64, sink ArchiveSink) (*models.LocationArchive, error)
+	// ListOverlapping mengembalikan arsip yang beririsan dengan [start, end] dan memuat vehicleID milik
+	// tenantID (kosong = semua tenant, hanya untuk proses internal).
+	ListOverlapping(ctx context.Context, tenantID, vehicleID string, start, end int64) ([]models.LocationArchive, error)
+}
+
+type archiveRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewArchiveRepository(db *pgxpool.Pool) ArchiveRepository {
+	return &archiveRepository{db: db}
+}
+
+func (r *archiveRepository) OldestBefore(ctx context.Context, cutoff int64) (int64, bool, error) {
+	var oldest *int64
+	if err := r.db.QueryRow(ctx,
+		`SELECT MIN(timestamp) FROM vehicle_locations WHERE timestamp < $1`,
+		cutoff,
+	).Scan(&oldest); err != nil {
+		return 0, false, err
+	}
+	if oldest == nil {
+		return 0, false, nil
+	}
+	return *oldest, true, nil
+}
+
+func (r *archiveRepository) ArchiveRange(ctx context.Context, start, end int64, sink ArchiveSink) (*models.LocationArchive, error) {
+	// REPEATABLE READ: DELETE di akhir hanya menyentuh baris yang terlihat di snapshot export,
+	// sehingga data terlambat yang masuk selama proses tidak ikut terhapus tanpa diarsipkan.
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx,
+		`SELECT id, tenant_id, vehicle_id, COALESCE(device_id, ''), latitude, longitude, timestamp, speed, ignition, odometer
+		 FROM vehicle_locations
+		 WHERE timestamp >= $1 AND timestamp < $2
+		 ORDER BY timestamp ASC, id ASC`,
+		start, end,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	count := 0
+	for rows.Next() {
+		var loc models.VehicleLocation
+		if err := rows.Scan(&loc.ID, &loc.TenantID, &loc.VehicleID, &loc.DeviceID, &loc.Latitude, &loc.Longitude, &loc.Timestamp, &loc.Speed, &loc.Ignition, &loc.Odometer); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if err := sink.Write(loc); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		count++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	entry, err := sink.Finish(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entry.RangeStart = start
+	entry.RangeEnd = end
+
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO location_archives (range_start, range_end, object_key, row_count, size_bytes, sha256, vehicle_ids,
+			tenant_vehicle_ids)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING id, created_at`,
+		entry.RangeStart, entry.RangeEnd, entry.ObjectKey, entry.RowCount, entry.SizeBytes, entry.SHA256, entry.VehicleIDs,
+		entry.TenantVehicleIDs,
+	).Scan(&entry.ID, &entry.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM vehicle_locations WHERE timestamp >= $1 AND timestamp < $2`,
+		start, end,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (r *archiveRepository) ListOverlapping(ctx context.Context, tenantID, vehicleID string, start, end int64) ([]models.LocationArchive, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id, range_start, range_end, object_key, row_count, size_bytes, sha256, vehicle_ids,
+			tenant_vehicle_ids, created_at
+		 FROM location_archives
+		 WHERE range_start <= $3 AND range_end > $2
+			AND CASE WHEN $4 = '' THEN vehicle_ids @> ARRAY[$1::TEXT]
+				ELSE tenant_vehicle_ids @> ARRAY[$4 || '/' || $1] END
+		 ORDER BY range_start ASC, id ASC`,
+		vehicleID, start, end, tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.LocationArchive
+	for rows.Next() {
+		var a models.LocationArchive
+		if err := rows.Scan(&a.ID, &a.RangeStart, &a.RangeEnd, &a.ObjectKey, &a.RowCount, &a.SizeBytes, &a.SHA256, &a.VehicleIDs,
+			&a.TenantVehicleIDs, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"context"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DeviceResolution adalah hasil pencarian device untuk satu titik lokasi.
+type DeviceResolution struct {
+	Active bool
+	// VehicleID kosong bila device tidak terpasang ke kendaraan pada timestamp tersebut.
+	VehicleID string
+}
+
+// DeviceRepository menyimpan device dan pemasangannya ke kendaraan. Parameter tenantID membatasi
+// ke data milik tenant tersebut; kosong = semua tenant (hanya untuk proses internal).
+type DeviceRepository interface {
+	// Create menyimpan device milik d.TenantID.
+	Create(ctx context.Context, d models.Device) (*models.Device, error)
+	Get(ctx context.Context, tenantID, id string) (*models.Device, error)
+	List(ctx context.Context, tenantID string) ([]models.Device, error)
+	// Update mengubah device d.ID milik d.TenantID.
+	Update(ctx context.Context, d models.Device) (*models.Device, error)
+	Delete(ctx context.Context, tenantID, id string) error
+	// SetSigningKey memasang kunci tanda tangan payload; algorithm kosong dan key nil = lepas kunci.
+	SetSigningKey(ctx context.Context, tenantID, id, algorithm string, key []byte) (*models.Device, error)
+
+	// Resolve mencari device dan kendaraan yang dipasangi pada timestamp ts.
+	// Mengembalikan ErrNotFound bila id bukan device terdaftar di tenant.
+	Resolve(ctx context.Context, tenantID, id string, ts int64) (*DeviceResolution, error)
+	ListAssignments(ctx context.Context, tenantID, deviceID string) ([]models.DeviceAssignment, error)
+	// Assign memasang device ke kendaraan di a.TenantID, menutup pemasangan terbuka sebelumnya, dan
+	// memindahkan titik yang sudah tercatat dalam rentang tersebut ke kendaraan baru.
+	Assign(ctx context.Context, a models.DeviceAssignment) (*models.DeviceAssignment, error)
+	// Unassign menutup pemasangan yang masih terbuka pada waktu `at`.
+	Unassign(ctx context.Context, tenantID, deviceID string, at int64) (*models.DeviceAssignment, error)
+}
+
+type deviceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDeviceRepository(db *pgxpool.Pool) DeviceRepository {
+	return &deviceRepository{db: db}
+}
+
+const deviceColumns = `id, tenant_id, COALESCE(imei, ''), model, active, COALESCE(signing_algorithm, ''), signing_key,
+	created_at, updated_at`
+
+func scanDevice(row pgx.Row) (*models.Device, error) {
+	var d models.Device
+	if err := row.Scan(&d.ID, &d.TenantID, &d.IMEI, &d.Model, &d.Active, &d.SigningAlgorithm, &d.SigningKey,
+		&d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, translateError(err)
+	}
+	return &d, nil
+}
+
+const assignmentColumns = `id, tenant_id, device_id, vehicle_id, start_ts, end_ts, created_at`
+
+func scanAssignment(row pgx.Row) (*models.DeviceAssignment, error) {
+	var a models.DeviceAssignment
+	if err := row.Scan(&a.ID, &a.TenantID, &a.DeviceID, &a.VehicleID, &a.Start, &a.End, &a.CreatedAt); err != nil {
+		return nil, translateError(err)
+	}
+	return &a, nil
+}
+
+func (r *deviceRepository) Create(ctx context.Context, d models.Device) (*models.Device, error) {
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO devices (id, imei, model, active, tenant_id)
+		 VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+		 RETURNING `+deviceColumns,
+		d.ID, d.IMEI, d.Model, d.Active, d.TenantID,
+	)
+	return scanDevice(row)
+}
+
+func (r *deviceRepository) Get(ctx context.Context, tenantID, id string) (*models.Device, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+deviceColumns+` FROM devices WHERE id = $1 AND ($2 = '' OR tenant_id = $2)`,
+		id, tenantID,
+	)
+	return scanDevice(row)
+}
+
+func (r *deviceRepository) List(ctx context.Context, tenantID string) ([]models.Device, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+deviceColumns+` FROM devices WHERE ($1 = '' OR tenant_id = $1) ORDER BY id ASC, tenant_id ASC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Device
+	for rows.Next() {
+		d, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *d)
+	}
+	return result, rows.Err()
+}
+
+func (r *deviceRepository) Update(ctx context.Context, d models.Device) (*models.Device, error) {
+	row := r.db.QueryRow(ctx,
+		`UPDATE devices SET imei = NULLIF($2, ''), model = $3, active = $4, updated_at = now()
+		 WHERE id = $1 AND tenant_id = $5
+		 RETURNING `+deviceColumns,
+		d.ID, d.IMEI, d.Model, d.Active, d.TenantID,
+	)
+	return scanDevice(row)
+}
+
+func (r *deviceRepository) Delete(ctx context.Context, tenantID, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM devices WHERE id = $1 AND ($2 = '' OR tenant_id = $2)`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *deviceRepository) SetSigningKey(ctx context.Context, tenantID, id, algorithm string, key []byte) (*models.Device, error) {
+	row := r.db.QueryRow(ctx,
+		`UPDATE devices SET signing_algorithm = NULLIF($2, ''), signing_key = $3, updated_at = now()
+		 WHERE id = $1 AND ($4 = '' OR tenant_id = $4)
+		 RETURNING `+deviceColumns,
+		id, algorithm, key, tenantID,
+	)
+	return scanDevice(row)
+}
+
+func (r *deviceRepository) Resolve(ctx context.Context, tenantID, id string, ts int64) (*DeviceResolution, error) {
+	var (
+		res       DeviceResolution
+		vehicleID *string
+	)
+	err := r.db.QueryRow(ctx,
+		`SELECT d.active, a.vehicle_id
+		 FROM devices d
+		 LEFT JOIN device_assignments a
+			ON a.tenant_id = d.tenant_id AND a.device_id = d.id
+			AND a.start_ts <= $2 AND (a.end_ts IS NULL OR a.end_ts > $2)
+		 WHERE d.id = $1 AND ($3 = '' OR d.tenant_id = $3)`,
+		id, ts, tenantID,
+	).Scan(&res.Active, &vehicleID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if vehicleID != nil {
+		res.VehicleID = *vehicleID
+	}
+	return &res, nil
+}
+
+func (r *deviceRepository) ListAssignments(ctx context.Context, tenantID, deviceID string) ([]models.DeviceAssignment, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+assignmentColumns+`
+		 FROM device_assignments
+		 WHERE device_id = $1 AND ($2 = '' OR tenant_id = $2)
+		 ORDER BY start_ts DESC`,
+		deviceID, tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.DeviceAssignment
+	for rows.Next() {
+		a, err := scanAssignment(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *a)
+	}
+	return result, rows.Err()
+}
+
+func (r *deviceRepository) Assign(ctx context.Context, a models.DeviceAssignment) (*models.DeviceAssignment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Tutup pemasangan terbuka yang dimulai sebelum pemasangan baru (device dipindah ke truk lain)
+	if _, err := tx.Exec(ctx,
+		`UPDATE device_assignments SET end_ts = $2
+		 WHERE tenant_id = $3 AND device_id = $1 AND end_ts IS NULL AND start_ts < $2`,
+		a.DeviceID, a.Start, a.TenantID,
+	); err != nil {
+		return nil, err
+	}
+
+	created, err := scanAssignment(tx.QueryRow(ctx,
+		`INSERT INTO device_assignments (device_id, vehicle_id, start_ts, end_ts, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING `+assignmentColumns,
+		a.DeviceID, a.VehicleID, a.Start, a.End, a.TenantID,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := reattribute(ctx, tx, *created); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// reattribute memindahkan titik dari device dalam rentang pemasangan ke kendaraan yang benar,
+// lalu menyegarkan vehicle_latest dan rollup untuk kendaraan yang terdampak. Hanya titik milik
+// tenant pemasangan yang disentuh.
+func reattribute(ctx context.Context, tx pgx.Tx, a models.DeviceAssignment) error {
+	var affected []string
+	if err := tx.QueryRow(ctx,
+		`SELECT COALESCE(ARRAY_AGG(DISTINCT vehicle_id), '{}')
+		 FROM vehicle_locations
+		 WHERE tenant_id = $5 AND device_id = $1 AND timestamp >= $2 AND ($3::BIGINT IS NULL OR timestamp < $3)
+			AND vehicle_id <> $4`,
+		a.DeviceID, a.Start, a.End, a.VehicleID, a.TenantID,
+	).Scan(&affected); err != nil {
+		return err
+	}
+	if len(affected) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE vehicle_locations SET vehicle_id = $4
+		 WHERE tenant_id = $5 AND device_id = $1 AND timestamp >= $2 AND ($3::BIGINT IS NULL OR timestamp < $3)
+			AND vehicle_id <> $4`,
+		a.DeviceID, a.Start, a.End, a.VehicleID, a.TenantID,
+	); err != nil {
+		return err
+	}
+
+	affected = append(affected, a.VehicleID)
+
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM vehicle_latest WHERE tenant_id = $2 AND vehicle_id = ANY($1)`,
+		affected, a.TenantID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO vehicle_latest (vehicle_id, location_id, latitude, longitude, timestamp, speed, ignition, odometer, tenant_id)
+		 SELECT DISTINCT ON (vehicle_id) vehicle_id, id, latitude, longitude, timestamp, speed, ignition, odometer, tenant_id
+		 FROM vehicle_locations
+		 WHERE tenant_id = $2 AND vehicle_id = ANY($1)
+		 ORDER BY vehicle_id, timestamp DESC, id DESC`,
+		affected, a.TenantID,
+	); err != nil {
+		return err
+	}
+
+	// Rollup dihitung ulang oleh RollupService mulai dari awal rentang yang berubah
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM vehicle_location_rollups
+		 WHERE tenant_id = $4 AND vehicle_id = ANY($1) AND bucket_start + resolution > $2
+			AND ($3::BIGINT IS NULL OR bucket_start < $3)`,
+		affected, a.Start, a.End, a.TenantID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE rollup_watermarks SET completed_until = LEAST(completed_until, $1::BIGINT - $1::BIGINT % resolution)`,
+		a.Start,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *deviceRepository) Unassign(ctx context.Context, tenantID, deviceID string, at int64) (*models.DeviceAssignment, error) {
+	return scanAssignment(r.db.QueryRow(ctx,
+		`UPDATE device_assignments SET end_ts = $2
+		 WHERE device_id = $1 AND ($3 = '' OR tenant_id = $3) AND end_ts IS NULL AND start_ts < $2
+		 RETURNING `+assignmentColumns,
+		deviceID, at, tenantID,
+	))
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DistanceIncrement adalah kontribusi satu titik baru ke jarak harian kendaraan.
+type DistanceIncrement struct {
+	TenantID  string
+	VehicleID string
+	Date      string // YYYY-MM-DD pada zona waktu laporan
+	Timestamp int64
+	DistanceM float64
+	// Rejected menandai segmen yang dibuang sebagai lompatan GPS.
+	Rejected bool
+	// OdometerStart dipakai bila hari tersebut belum punya bacaan odometer awal.
+	OdometerStart *float64
+	Odometer      *float64
+}
+
+type DistanceRepository interface {
+	Add(ctx context.Context, inc DistanceIncrement) error
+	// List mengembalikan jarak harian dalam rentang tanggal [from, to], urut kendaraan lalu tanggal.
+	// tenantID kosong = semua tenant (hanya untuk proses internal).
+	List(ctx context.Context, tenantID, from, to, group string) ([]models.DailyDistance, error)
+}
+
+type distanceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDistanceRepository(db *pgxpool.Pool) DistanceRepository {
+	return &distanceRepository{db: db}
+}
+
+func (r *distanceRepository) Add(ctx context.Context, inc DistanceIncrement) error {
+	rejected := 0
+	if inc.Rejected {
+		rejected = 1
+	}
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO vehicle_daily_distance AS d (vehicle_id, day, gps_distance_m, point_count, rejected_segments,
+			odometer_start, odometer_end, first_ts, last_ts, tenant_id)
+		 VALUES ($1, $2::DATE, $3, 1, $4, $5, $6, $7, $7, $8)
+		 ON CONFLICT (tenant_id, vehicle_id, day) DO UPDATE SET
+			gps_distance_m = d.gps_distance_m + EXCLUDED.gps_distance_m,
+			point_count = d.point_count + 1,
+			rejected_segments = d.rejected_segments + EXCLUDED.rejected_segments,
+			odometer_start = COALESCE(d.odometer_start, EXCLUDED.odometer_start),
+			odometer_end = COALESCE(EXCLUDED.odometer_end, d.odometer_end),
+			first_ts = LEAST(d.first_ts, EXCLUDED.first_ts),
+			last_ts = GREATEST(d.last_ts, EXCLUDED.last_ts)`,
+		inc.VehicleID, inc.Date, inc.DistanceM, rejected, inc.OdometerStart, inc.Odometer, inc.Timestamp, inc.TenantID,
+	)
+	return err
+}
+
+func (r *distanceRepository) List(ctx context.Context, tenantID, from, to, group string) ([]models.DailyDistance, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT d.vehicle_id, COALESCE(v.group_name, ''), d.day::TEXT, d.gps_distance_m, d.point_count,
+			d.rejected_segments, d.odometer_start, d.odometer_end
+		 FROM vehicle_daily_distance d
+		 LEFT JOIN vehicles v ON v.id = d.vehicle_id AND v.tenant_id = d.tenant_id
+		 WHERE d.day BETWEEN $1::DATE AND $2::DATE AND ($3 = '' OR v.group_name = $3)
+			AND ($4 = '' OR d.tenant_id = $4)
+		 ORDER BY d.vehicle_id ASC, d.tenant_id ASC, d.day ASC`,
+		from, to, group, tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.DailyDistance
+	for rows.Next() {
+		var d models.DailyDistance
+		if err := rows.Scan(&d.VehicleID, &d.Group, &d.Date, &d.GPSDistanceM, &d.PointCount,
+			&d.RejectedSegments, &d.OdometerStart, &d.OdometerEnd); err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DriverRepository menyimpan pengemudi dan shift-nya. Parameter tenantID membatasi ke data milik
+// tenant tersebut; kosong = semua tenant (hanya untuk proses internal).
+type DriverRepository interface {
+	// Create menyimpan pengemudi milik d.TenantID.
+	Create(ctx context.Context, d models.Driver) (*models.Driver, error)
+	Get(ctx context.Context, tenantID, id string) (*models.Driver, error)
+	List(ctx context.Context, tenantID string) ([]models.Driver, error)
+	// Update mengubah pengemudi d.ID milik d.TenantID.
+	Update(ctx context.Context, d models.Driver) (*models.Driver, error)
+	Delete(ctx context.Context, tenantID, id string) error
+
+	// CreateShift menyimpan shift milik s.TenantID.
+	CreateShift(ctx context.Context, s models.DriverShift) (*models.DriverShift, error)
+	GetShift(ctx context.Context, tenantID string, id int64) (*models.DriverShift, error)
+	// ListShifts mengembalikan shift urut waktu mulai (terlama dulu).
+	ListShifts(ctx context.Context, filter models.ShiftFilter) ([]models.DriverShift, error)
+	// EndShift menutup shift yang masih berjalan pada waktu `at`.
+	EndShift(ctx context.Context, tenantID string, id int64, at int64) (*models.DriverShift, error)
+	DeleteShift(ctx context.Context, tenantID string, id int64) error
+}
+
+type driverRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDriverRepository(db *pgxpool.Pool) DriverRepository {
+	return &driverRepository{db: db}
+}
+
+const driverColumns = `id, tenant_id, name, license_number, license_expiry::TEXT, phone, email, active, created_at, updated_at`
+
+func scanDriver(row pgx.Row) (*models.Driver, error) {
+	var d models.Driver
+	if err := row.Scan(&d.ID, &d.TenantID, &d.Name, &d.LicenseNumber, &d.LicenseExpiry, &d.Phone, &d.Email,
+		&d.Active, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, translateError(err)
+	}
+	return &d, nil
+}
+
+const shiftColumns = `id, tenant_id, driver_id, vehicle_id, start_ts, end_ts, created_at`
+
+func scanShift(row pgx.Row) (*models.DriverShift, error) {
+	var s models.DriverShift
+	if err := row.Scan(&s.ID, &s.TenantID, &s.DriverID, &s.VehicleID, &s.Start, &s.End, &s.CreatedAt); err != nil {
+		return nil, translateError(err)
+	}
+	return &s, nil
+}
+
+func (r *driverRepository) Create(ctx context.Context, d models.Driver) (*models.Driver, error) {
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO drivers (id, name, license_number, license_expiry, phone, email, active, tenant_id)
+		 VALUES ($1, $2, $3, $4::DATE, $5, $6, $7, $8)
+		 RETURNING `+driverColumns,
+		d.ID, d.Name, d.LicenseNumber, d.LicenseExpiry, d.Phone, d.Email, d.Active, d.TenantID,
+	)
+	return scanDriver(row)
+}
+
+func (r *driverRepository) Get(ctx context.Context, tenantID, id string) (*models.Driver, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+driverColumns+` FROM drivers WHERE id = $1 AND ($2 = '' OR tenant_id = $2)`,
+		id, tenantID,
+	)
+	return scanDriver(row)
+}
+
+func (r *driverRepository) List(ctx context.Context, tenantID string) ([]models.Driver, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+driverColumns+` FROM drivers WHERE ($1 = '' OR tenant_id = $1) ORDER BY id ASC, tenant_id ASC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Driver
+	for rows.Next() {
+		d, err := scanDriver(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *d)
+	}
+	return result, rows.Err()
+}
+
+func (r *driverRepository) Update(ctx context.Context, d models.Driver) (*models.Driver, error) {
+	row := r.db.QueryRow(ctx,
+		`UPDATE drivers SET
+			name = $2,
+			license_number = $3,
+			license_expiry = $4::DATE,
+			phone = $5,
+			email = $6,
+			active = $7,
+			updated_at = now()
+		 WHERE id = $1 AND tenant_id = $8
+		 RETURNING `+driverColumns,
+		d.ID, d.Name, d.LicenseNumber, d.LicenseExpiry, d.Phone, d.Email, d.Active, d.TenantID,
+	)
+	return scanDriver(row)
+}
+
+func (r *driverRepository) Delete(ctx context.Context, tenantID, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM drivers WHERE id = $1 AND ($2 = '' OR tenant_id = $2)`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *driverRepository) CreateShift(ctx context.Context, s models.DriverShift) (*models.DriverShift, error) {
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO driver_shifts (driver_id, vehicle_id, start_ts, end_ts, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING `+shiftColumns,
+		s.DriverID, s.VehicleID, s.Start, s.End, s.TenantID,
+	)
+	return scanShift(row)
+}
+
+func (r *driverRepository) GetShift(ctx context.Context, tenantID string, id int64) (*models.DriverShift, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+shiftColumns+` FROM driver_shifts WHERE id = $1 AND ($2 = '' OR tenant_id = $2)`,
+		id, tenantID,
+	)
+	return scanShift(row)
+}
+
+func (r *driverRepository) ListShifts(ctx context.Context, filter models.ShiftFilter) ([]models.DriverShift, error) {
+	query := `SELECT ` + shiftColumns + ` FROM driver_shifts WHERE TRUE`
+	var args []any
+	if filter.TenantID != "" {
+		args = append(args, filter.TenantID)
+		query += fmt.Sprintf(` AND tenant_id = $%d`, len(args))
+	}
+	if filter.DriverID != "" {
+		args = append(args, filter.DriverID)
+		query += fmt.Sprintf(` AND driver_id = $%d`, len(args))
+	}
+	if filter.VehicleID != "" {
+		args = append(args, filter.VehicleID)
+		query += fmt.Sprintf(` AND vehicle_id = $%d`, len(args))
+	}
+	if filter.Start > 0 || filter.End > 0 {
+		// bentuk int8range sama dengan exclusion constraint supaya index gist terpakai;
+		// End inklusif seperti rentang riwayat, batas 0 berarti tanpa batas
+		args = append(args, filter.Start, filter.End)
+		query += fmt.Sprintf(` AND int8range(start_ts, end_ts) && int8range(NULLIF($%d::BIGINT, 0), NULLIF($%d::BIGINT, 0), '[]')`,
+			len(args)-1, len(args))
+	}
+	query += ` ORDER BY start_ts ASC, id ASC`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.DriverShift
+	for rows.Next() {
+		s, err := scanShift(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *s)
+	}
+	return result, rows.Err()
+}
+
+func (r *driverRepository) EndShift(ctx context.Context, tenantID string, id int64, at int64) (*models.DriverShift, error) {
+	return scanShift(r.db.QueryRow(ctx,
+		`UPDATE driver_shifts SET end_ts = $2
+		 WHERE id = $1 AND ($3 = '' OR tenant_id = $3) AND end_ts IS NULL AND start_ts < $2
+		 RETURNING `+shiftColumns,
+		id, at, tenantID,
+	))
+}
+
+func (r *driverRepository) DeleteShift(ctx context.Context, tenantID string, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM driver_shifts WHERE id = $1 AND ($2 = '' OR tenant_id = $2)`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
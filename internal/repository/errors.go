@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("already exists")
+	// ErrReferenceNotFound: data yang dirujuk (mis. vehicle_id) tidak ada.
+	ErrReferenceNotFound = errors.New("referenced record not found")
+)
+
+// translateError memetakan error pgx ke error repository yang dipahami service/handler.
+func translateError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505", "23P01": // unique_violation, exclusion_violation
+			return ErrConflict
+		case "23503": // foreign_key_violation
+			return ErrReferenceNotFound
+		}
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GeofenceEventRepository interface {
+	Insert(ctx context.Context, e models.GeofenceEvent) error
+	// Stream memanggil fn untuk setiap event dalam [start, end] (urut waktu) tanpa menampung
+	// seluruh hasil di memori. tenantID kosong = semua tenant, vehicleID kosong = seluruh armada.
+	Stream(ctx context.Context, tenantID, vehicleID string, start, end int64, fn func(models.GeofenceEvent) error) error
+}
+
+type geofenceEventRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGeofenceEventRepository(db *pgxpool.Pool) GeofenceEventRepository {
+	return &geofenceEventRepository{db: db}
+}
+
+func (r *geofenceEventRepository) Insert(ctx context.Context, e models.GeofenceEvent) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO geofence_events (vehicle_id, driver_id, event, latitude, longitude, timestamp, tenant_id)
+		 VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7)`,
+		e.VehicleID, e.DriverID, e.Event, e.Location.Latitude, e.Location.Longitude, e.Timestamp, e.TenantID,
+	)
+	return err
+}
+
+func (r *geofenceEventRepository) Stream(ctx context.Context, tenantID, vehicleID string, start, end int64, fn func(models.GeofenceEvent) error) error {
+	rows, err := r.db.Query(ctx,
+		`SELECT tenant_id, vehicle_id, COALESCE(driver_id, ''), event, latitude, longitude, timestamp
+		 FROM geofence_events
+		 WHERE timestamp BETWEEN $1 AND $2 AND ($3 = '' OR vehicle_id = $3) AND ($4 = '' OR tenant_id = $4)
+		 ORDER BY timestamp ASC, id ASC`,
+		start, end, vehicleID, tenantID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.GeofenceEvent
+		if err := rows.Scan(&e.TenantID, &e.VehicleID, &e.DriverID, &e.Event, &e.Location.Latitude, &e.Location.Longitude, &e.Timestamp); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
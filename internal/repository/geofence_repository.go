@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GeofenceRepository interface {
+	Create(ctx context.Context, g models.Geofence) (*models.Geofence, error)
+	Get(ctx context.Context, id int64) (*models.Geofence, error)
+	// List mengembalikan geofence milik tenant; tenantID kosong = semua tenant.
+	List(ctx context.Context, tenantID string) ([]models.Geofence, error)
+	// ListInBox mengembalikan geofence milik tenant yang lingkarannya mungkin beririsan dengan
+	// bounding box.
+	ListInBox(ctx context.Context, tenantID string, box models.BoundingBox) ([]models.Geofence, error)
+	Update(ctx context.Context, g models.Geofence) (*models.Geofence, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type geofenceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGeofenceRepository(db *pgxpool.Pool) GeofenceRepository {
+	return &geofenceRepository{db: db}
+}
+
+const geofenceColumns = `id, tenant_id, name, latitude, longitude, radius_m, speed_limit_kmh, created_at, updated_at`
+
+func scanGeofence(row pgx.Row) (*models.Geofence, error) {
+	var g models.Geofence
+	if err := row.Scan(&g.ID, &g.TenantID, &g.Name, &g.Latitude, &g.Longitude, &g.RadiusM, &g.SpeedLimitKmh, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		return nil, translateError(err)
+	}
+	return &g, nil
+}
+
+func collectGeofences(rows pgx.Rows) ([]models.Geofence, error) {
+	defer rows.Close()
+
+	var result []models.Geofence
+	for rows.Next() {
+		g, err := scanGeofence(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *g)
+	}
+	return result, rows.Err()
+}
+
+func (r *geofenceRepository) Create(ctx context.Context, g models.Geofence) (*models.Geofence, error) {
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO geofences (name, latitude, longitude, radius_m, speed_limit_kmh, tenant_id)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING `+geofenceColumns,
+		g.Name, g.Latitude, g.Longitude, g.RadiusM, g.SpeedLimitKmh, g.TenantID,
+	)
+	return scanGeofence(row)
+}
+
+func (r *geofenceRepository) Get(ctx context.Context, id int64) (*models.Geofence, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+geofenceColumns+` FROM geofences WHERE id = $1`, id)
+	return scanGeofence(row)
+}
+
+func (r *geofenceRepository) List(ctx context.Context, tenantID string) ([]models.Geofence, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+geofenceColumns+` FROM geofences WHERE ($1 = '' OR tenant_id = $1) ORDER BY name ASC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectGeofences(rows)
+}
+
+func (r *geofenceRepository) ListInBox(ctx context.Context, tenantID string, box models.BoundingBox) ([]models.Geofence, error) {
+	// pra-filter kasar berdasarkan pusat + radius (derajat lintang ~111 km); jarak pastinya dihitung di service
+	rows, err := r.db.Query(ctx,
+		`SELECT `+geofenceColumns+`
+		 FROM geofences
+		 WHERE latitude + radius_m / 111000 >= $1 AND latitude - radius_m / 111000 <= $2
+			AND longitude + radius_m / (111000 * GREATEST(cos(radians(latitude)), 0.01)) >= $3
+			AND longitude - radius_m / (111000 * GREATEST(cos(radians(latitude)), 0.01)) <= $4
+			AND ($5 = '' OR tenant_id = $5)`,
+		box.MinLat, box.MaxLat, box.MinLon, box.MaxLon, tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return collectGeofences(rows)
+}
+
+func (r *geofenceRepository) Update(ctx context.Context, g models.Geofence) (*models.Geofence, error) {
+	row := r.db.QueryRow(ctx,
+		`UPDATE geofences SET name = $2, latitude = $3, longitude = $4, radius_m = $5, speed_limit_kmh = $6,
+			updated_at = now()
+		 WHERE id = $1
+		 RETURNING `+geofenceColumns,
+		g.ID, g.Name, g.Latitude, g.Longitude, g.RadiusM, g.SpeedLimitKmh,
+	)
+	return scanGeofence(row)
+}
+
+func (r *geofenceRepository) Delete(ctx context.Context, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM geofences WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
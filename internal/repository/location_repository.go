@@ -2,16 +2,25 @@ package repository
 
 import (
 	"context"
+	"fmt"
 
 	"sistem-manajemen-armada/internal/models"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// LocationRepository menyimpan dan membaca lokasi kendaraan. Parameter tenantID membatasi hasil ke
+// data milik tenant tersebut; kosong = semua tenant (hanya untuk proses internal).
 type LocationRepository interface {
 	Insert(ctx context.Context, loc models.VehicleLocation) error
-	GetLatest(ctx context.Context, vehicleID string) (*models.VehicleLocation, error)
-	GetHistory(ctx context.Context, vehicleID string, start, end int64) ([]models.VehicleLocation, error)
+	GetLatest(ctx context.Context, tenantID, vehicleID string) (*models.VehicleLocation, error)
+	ListLatest(ctx context.Context, filter models.LatestFilter) ([]models.VehicleLocation, error)
+	GetHistory(ctx context.Context, tenantID, vehicleID string, start, end int64) ([]models.VehicleLocation, error)
+	// StreamHistory memanggil fn untuk setiap titik setelah `after` (opsional) tanpa menampung
+	// seluruh hasil di memori. limit <= 0 berarti tanpa batas.
+	StreamHistory(ctx context.Context, tenantID, vehicleID string, start, end int64, after *models.HistoryCursor, limit int, fn func(loc models.VehicleLocation) error) error
+	// GetHistoryDownsampled mengembalikan titik terakhir di setiap bucket `step` detik.
+	GetHistoryDownsampled(ctx context.Context, tenantID, vehicleID string, start, end, step int64) ([]models.VehicleLocation, error)
 }
 
 type locationRepository struct {
@@ -22,39 +31,160 @@ func NewLocationRepository(db *pgxpool.Pool) LocationRepository {
 	return &locationRepository{db: db}
 }
 
+// Insert menyimpan lokasi dan memperbarui vehicle_latest dalam satu statement.
+// vehicle_latest (per tenant dan kendaraan) hanya ditimpa bila titik baru tidak lebih lama dari yang
+// tersimpan.
 func (r *locationRepository) Insert(ctx context.Context, loc models.VehicleLocation) error {
 	_, err := r.db.Exec(ctx,
-		`INSERT INTO vehicle_locations (vehicle_id, latitude, longitude, timestamp)
-		 VALUES ($1, $2, $3, $4)`,
-		loc.VehicleID, loc.Latitude, loc.Longitude, loc.Timestamp,
+		`WITH inserted AS (
+			INSERT INTO vehicle_locations (vehicle_id, latitude, longitude, timestamp, device_id, speed, ignition, odometer, tenant_id)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9)
+			RETURNING id, vehicle_id, latitude, longitude, timestamp, speed, ignition, odometer, tenant_id
+		),
+		-- titik untuk bucket yang sudah di-rollup ditandai supaya digabung RollupService. Semua
+		-- watermark dikunci FOR SHARE: Refresh memegang FOR UPDATE sampai commit, jadi insert yang
+		-- berjalan bersamaan menunggu dan membaca watermark barunya, bukan lolos dari keduanya.
+		late AS (
+			INSERT INTO rollup_late_locations (resolution, location_id, bucket_start)
+			SELECT w.resolution, i.id, i.timestamp - i.timestamp % w.resolution
+			FROM inserted i
+			JOIN (SELECT resolution, completed_until FROM rollup_watermarks FOR SHARE) w
+				ON i.timestamp < w.completed_until
+		)
+		INSERT INTO vehicle_latest (vehicle_id, location_id, latitude, longitude, timestamp, speed, ignition, odometer, tenant_id)
+		SELECT vehicle_id, id, latitude, longitude, timestamp, speed, ignition, odometer, tenant_id FROM inserted
+		ON CONFLICT (tenant_id, vehicle_id) DO UPDATE SET
+			location_id = EXCLUDED.location_id,
+			latitude = EXCLUDED.latitude,
+			longitude = EXCLUDED.longitude,
+			timestamp = EXCLUDED.timestamp,
+			speed = EXCLUDED.speed,
+			ignition = EXCLUDED.ignition,
+			odometer = EXCLUDED.odometer,
+			updated_at = now()
+		WHERE vehicle_latest.timestamp <= EXCLUDED.timestamp`,
+		loc.VehicleID, loc.Latitude, loc.Longitude, loc.Timestamp, loc.DeviceID, loc.Speed, loc.Ignition, loc.Odometer, loc.TenantID,
 	)
 	return err
 }
 
-func (r *locationRepository) GetLatest(ctx context.Context, vehicleID string) (*models.VehicleLocation, error) {
+func (r *locationRepository) GetLatest(ctx context.Context, tenantID, vehicleID string) (*models.VehicleLocation, error) {
 	row := r.db.QueryRow(ctx,
-		`SELECT id, vehicle_id, latitude, longitude, timestamp
-		 FROM vehicle_locations
-		 WHERE vehicle_id = $1
-		 ORDER BY timestamp DESC
-		 LIMIT 1`,
-		vehicleID,
+		`SELECT location_id, tenant_id, vehicle_id, latitude, longitude, timestamp, speed, ignition, odometer
+		 FROM vehicle_latest
+		 WHERE vehicle_id = $1 AND ($2 = '' OR tenant_id = $2)`,
+		vehicleID, tenantID,
 	)
 
 	var loc models.VehicleLocation
-	if err := row.Scan(&loc.ID, &loc.VehicleID, &loc.Latitude, &loc.Longitude, &loc.Timestamp); err != nil {
-		return nil, err
+	if err := row.Scan(&loc.ID, &loc.TenantID, &loc.VehicleID, &loc.Latitude, &loc.Longitude, &loc.Timestamp, &loc.Speed, &loc.Ignition, &loc.Odometer); err != nil {
+		return nil, translateError(err)
 	}
 	return &loc, nil
 }
 
-func (r *locationRepository) GetHistory(ctx context.Context, vehicleID string, start, end int64) ([]models.VehicleLocation, error) {
+func (r *locationRepository) ListLatest(ctx context.Context, filter models.LatestFilter) ([]models.VehicleLocation, error) {
+	query := `SELECT l.location_id, l.tenant_id, l.vehicle_id, l.latitude, l.longitude, l.timestamp, l.speed, l.ignition, l.odometer
+		 FROM vehicle_latest l
+		 LEFT JOIN vehicles v ON v.id = l.vehicle_id AND v.tenant_id = l.tenant_id
+		 WHERE TRUE`
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if b := filter.BBox; b != nil {
+		query += ` AND l.latitude BETWEEN ` + arg(b.MinLat) + ` AND ` + arg(b.MaxLat) +
+			` AND l.longitude BETWEEN ` + arg(b.MinLon) + ` AND ` + arg(b.MaxLon)
+	}
+	if filter.TenantID != "" {
+		query += ` AND l.tenant_id = ` + arg(filter.TenantID)
+	}
+	if filter.Group != "" {
+		query += ` AND v.group_name = ` + arg(filter.Group)
+	}
+	if filter.Groups != nil {
+		query += ` AND v.group_name = ANY(` + arg(filter.Groups) + `)`
+	}
+	if filter.UpdatedBefore > 0 {
+		query += ` AND l.timestamp < ` + arg(filter.UpdatedBefore)
+	}
+	if filter.UpdatedSince > 0 {
+		query += ` AND l.timestamp >= ` + arg(filter.UpdatedSince)
+	}
+	query += ` ORDER BY l.vehicle_id ASC`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.VehicleLocation
+	for rows.Next() {
+		var loc models.VehicleLocation
+		if err := rows.Scan(&loc.ID, &loc.TenantID, &loc.VehicleID, &loc.Latitude, &loc.Longitude, &loc.Timestamp, &loc.Speed, &loc.Ignition, &loc.Odometer); err != nil {
+			return nil, err
+		}
+		result = append(result, loc)
+	}
+	return result, rows.Err()
+}
+
+func (r *locationRepository) GetHistory(ctx context.Context, tenantID, vehicleID string, start, end int64) ([]models.VehicleLocation, error) {
+	var result []models.VehicleLocation
+	err := r.StreamHistory(ctx, tenantID, vehicleID, start, end, nil, 0, func(loc models.VehicleLocation) error {
+		result = append(result, loc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *locationRepository) StreamHistory(ctx context.Context, tenantID, vehicleID string, start, end int64, after *models.HistoryCursor, limit int, fn func(loc models.VehicleLocation) error) error {
+	query := `SELECT id, tenant_id, vehicle_id, COALESCE(device_id, ''), latitude, longitude, timestamp, speed, ignition, odometer
+		 FROM vehicle_locations
+		 WHERE vehicle_id = $1 AND timestamp BETWEEN $2 AND $3 AND ($4 = '' OR tenant_id = $4)`
+	args := []any{vehicleID, start, end, tenantID}
+
+	if after != nil {
+		query += ` AND (timestamp, id) > ($5, $6)`
+		args = append(args, after.Timestamp, after.ID)
+	}
+	query += ` ORDER BY timestamp ASC, id ASC`
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var loc models.VehicleLocation
+		if err := rows.Scan(&loc.ID, &loc.TenantID, &loc.VehicleID, &loc.DeviceID, &loc.Latitude, &loc.Longitude, &loc.Timestamp, &loc.Speed, &loc.Ignition, &loc.Odometer); err != nil {
+			return err
+		}
+		if err := fn(loc); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *locationRepository) GetHistoryDownsampled(ctx context.Context, tenantID, vehicleID string, start, end, step int64) ([]models.VehicleLocation, error) {
 	rows, err := r.db.Query(ctx,
-		`SELECT id, vehicle_id, latitude, longitude, timestamp
+		`SELECT DISTINCT ON (timestamp / $4) id, vehicle_id, latitude, longitude, timestamp
 		 FROM vehicle_locations
-		 WHERE vehicle_id = $1 AND timestamp BETWEEN $2 AND $3
-		 ORDER BY timestamp ASC`,
-		vehicleID, start, end,
+		 WHERE vehicle_id = $1 AND timestamp BETWEEN $2 AND $3 AND ($5 = '' OR tenant_id = $5)
+		 ORDER BY timestamp / $4 ASC, timestamp DESC, id DESC`,
+		vehicleID, start, end, step, tenantID,
 	)
 	if err != nil {
 		return nil, err
@@ -69,5 +199,5 @@ func (r *locationRepository) GetHistory(ctx context.Context, vehicleID string, s
 		}
 		result = append(result, loc)
 	}
-	return result, nil
+	return result, rows.Err()
 }
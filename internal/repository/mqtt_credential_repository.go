@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MQTTCredentialRepository interface {
+	// Create menyimpan kredensial baru; ErrConflict bila username masih punya kredensial aktif di
+	// tenant yang sama.
+	Create(ctx context.Context, c models.MQTTCredential) (*models.MQTTCredential, error)
+	// GetActive mengembalikan kredensial aktif untuk username milik tenantID, atau ErrNotFound.
+	// tenantID selalu dicocokkan, kosong tidak berarti semua tenant.
+	GetActive(ctx context.Context, tenantID, username string) (*models.MQTTCredential, error)
+	// List mengembalikan kredensial milik tenant; tenantID kosong = semua tenant.
+	List(ctx context.Context, tenantID string) ([]models.MQTTCredential, error)
+	// Revoke mencabut kredensial milik tenant (kosong = tenant mana pun); ErrNotFound bila tidak
+	// ada, milik tenant lain, atau sudah dicabut.
+	Revoke(ctx context.Context, tenantID string, id int64) (*models.MQTTCredential, error)
+}
+
+type mqttCredentialRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMQTTCredentialRepository(db *pgxpool.Pool) MQTTCredentialRepository {
+	return &mqttCredentialRepository{db: db}
+}
+
+const mqttCredentialColumns = `id, tenant_id, username, password_hash, created_by, created_at, revoked_at`
+
+func scanMQTTCredential(row pgx.Row) (*models.MQTTCredential, error) {
+	var c models.MQTTCredential
+	if err := row.Scan(&c.ID, &c.TenantID, &c.Username, &c.Hash, &c.CreatedBy, &c.CreatedAt, &c.RevokedAt); err != nil {
+		return nil, translateError(err)
+	}
+	return &c, nil
+}
+
+func (r *mqttCredentialRepository) Create(ctx context.Context, c models.MQTTCredential) (*models.MQTTCredential, error) {
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO mqtt_credentials (tenant_id, username, password_hash, created_by)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING `+mqttCredentialColumns,
+		c.TenantID, c.Username, c.Hash, c.CreatedBy,
+	)
+	return scanMQTTCredential(row)
+}
+
+func (r *mqttCredentialRepository) GetActive(ctx context.Context, tenantID, username string) (*models.MQTTCredential, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+mqttCredentialColumns+` FROM mqtt_credentials
+		 WHERE tenant_id = $1 AND username = $2 AND revoked_at IS NULL`,
+		tenantID, username,
+	)
+	return scanMQTTCredential(row)
+}
+
+func (r *mqttCredentialRepository) List(ctx context.Context, tenantID string) ([]models.MQTTCredential, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+mqttCredentialColumns+` FROM mqtt_credentials WHERE ($1 = '' OR tenant_id = $1) ORDER BY id ASC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.MQTTCredential
+	for rows.Next() {
+		c, err := scanMQTTCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *c)
+	}
+	return result, rows.Err()
+}
+
+func (r *mqttCredentialRepository) Revoke(ctx context.Context, tenantID string, id int64) (*models.MQTTCredential, error) {
+	row := r.db.QueryRow(ctx,
+		`UPDATE mqtt_credentials SET revoked_at = now()
+		 WHERE id = $1 AND revoked_at IS NULL AND ($2 = '' OR tenant_id = $2)
+		 RETURNING `+mqttCredentialColumns,
+		id, tenantID,
+	)
+	return scanMQTTCredential(row)
+}
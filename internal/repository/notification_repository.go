@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NotificationRepository menyimpan notifikasi aturan, sekaligus antrean pengiriman ulang.
+type NotificationRepository interface {
+	// CreateAll menyimpan semua notifikasi event eventKey dalam satu transaksi. Notifikasi yang sudah
+	// tersimpan untuk aturan dan eventKey yang sama dilewati, sehingga event aman diproses ulang.
+	CreateAll(ctx context.Context, eventKey string, notifications []models.Notification) error
+	// LastCreatedAt mengembalikan waktu notifikasi terakhir aturan untuk kendaraan, nil bila belum ada.
+	LastCreatedAt(ctx context.Context, ruleID int64, vehicleID string) (*time.Time, error)
+	// List mengembalikan notifikasi terbaru sebuah aturan, paling baru dulu.
+	List(ctx context.Context, tenantID string, ruleID int64, limit int) ([]models.Notification, error)
+	// ClaimDue mengambil maksimal limit notifikasi pending yang sudah jatuh tempo dan menunda
+	// next_attempt_at-nya sebesar lease, supaya worker lain tidak mengirim notifikasi yang sama.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.Notification, error)
+	// RecordAttempt menyimpan hasil percobaan: status, attempts, next_attempt_at dan error.
+	RecordAttempt(ctx context.Context, n models.Notification) error
+}
+
+type notificationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationRepository(db *pgxpool.Pool) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+const notificationColumns = `id, rule_id, tenant_id, vehicle_id, event_type, action, subject, message, payload, status, attempts,
+	next_attempt_at, last_error, created_at, delivered_at`
+
+func scanNotification(row pgx.Row) (*models.Notification, error) {
+	var n models.Notification
+	err := row.Scan(&n.ID, &n.RuleID, &n.TenantID, &n.VehicleID, &n.EventType, &n.Action, &n.Subject, &n.Message, &n.Payload,
+		&n.Status, &n.Attempts, &n.NextAttemptAt, &n.LastError, &n.CreatedAt, &n.DeliveredAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &n, nil
+}
+
+func (r *notificationRepository) CreateAll(ctx context.Context, eventKey string, notifications []models.Notification) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// urutan notifikasi per aturan; deterministik selama aturan tidak berubah
+	seq := make(map[int64]int)
+	for _, n := range notifications {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO notifications (rule_id, tenant_id, vehicle_id, event_type, action, subject, message, payload, event_key, event_seq)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
+			 ON CONFLICT (rule_id, event_key, event_seq) WHERE event_key IS NOT NULL DO NOTHING`,
+			n.RuleID, n.TenantID, n.VehicleID, n.EventType, n.Action, n.Subject, n.Message, string(n.Payload),
+			eventKey, seq[n.RuleID],
+		); err != nil {
+			return translateError(err)
+		}
+		seq[n.RuleID]++
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *notificationRepository) LastCreatedAt(ctx context.Context, ruleID int64, vehicleID string) (*time.Time, error) {
+	var last *time.Time
+	err := r.db.QueryRow(ctx,
+		`SELECT max(created_at) FROM notifications WHERE rule_id = $1 AND vehicle_id = $2`,
+		ruleID, vehicleID,
+	).Scan(&last)
+	if err != nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+func (r *notificationRepository) List(ctx context.Context, tenantID string, ruleID int64, limit int) ([]models.Notification, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+notificationColumns+` FROM notifications
+		 WHERE rule_id = $1 AND ($2 = '' OR tenant_id = $2)
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $3`,
+		ruleID, tenantID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *n)
+	}
+	return result, rows.Err()
+}
+
+func (r *notificationRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.Notification, error) {
+	rows, err := r.db.Query(ctx,
+		`UPDATE notifications SET next_attempt_at = now() + $2::INTERVAL
+		 WHERE id IN (
+			SELECT id FROM notifications
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING `+notificationColumns,
+		limit, lease,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *n)
+	}
+	return result, rows.Err()
+}
+
+func (r *notificationRepository) RecordAttempt(ctx context.Context, n models.Notification) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE notifications
+		 SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, delivered_at = $6
+		 WHERE id = $1`,
+		n.ID, n.Status, n.Attempts, n.NextAttemptAt, n.LastError, n.DeliveredAt,
+	)
+	return err
+}
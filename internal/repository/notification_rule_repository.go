@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NotificationRuleRepository menyimpan aturan notifikasi. Parameter tenantID membatasi ke aturan
+// milik tenant tersebut; kosong = semua tenant (hanya untuk proses internal).
+type NotificationRuleRepository interface {
+	Create(ctx context.Context, rule models.NotificationRule) (*models.NotificationRule, error)
+	Get(ctx context.Context, tenantID string, id int64) (*models.NotificationRule, error)
+	List(ctx context.Context, tenantID string) ([]models.NotificationRule, error)
+	// ListActive mengembalikan aturan aktif milik tenant yang berlaku untuk eventType.
+	ListActive(ctx context.Context, tenantID, eventType string) ([]models.NotificationRule, error)
+	// Update mengganti seluruh isi aturan milik rule.TenantID.
+	Update(ctx context.Context, rule models.NotificationRule) (*models.NotificationRule, error)
+	Delete(ctx context.Context, tenantID string, id int64) error
+}
+
+type notificationRuleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationRuleRepository(db *pgxpool.Pool) NotificationRuleRepository {
+	return &notificationRuleRepository{db: db}
+}
+
+const notificationRuleColumns = `id, tenant_id, name, active, event_types, geofence_ids, vehicle_groups, time_start, time_end,
+	timezone, min_speed_kmh, min_duration_s, cooldown_s, actions, created_at, updated_at`
+
+func scanNotificationRule(row pgx.Row) (*models.NotificationRule, error) {
+	var rule models.NotificationRule
+	err := row.Scan(&rule.ID, &rule.TenantID, &rule.Name, &rule.Active, &rule.EventTypes, &rule.GeofenceIDs, &rule.VehicleGroups,
+		&rule.TimeStart, &rule.TimeEnd, &rule.Timezone, &rule.MinSpeedKmh, &rule.MinDurationS, &rule.CooldownS, &rule.Actions,
+		&rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &rule, nil
+}
+
+func (r *notificationRuleRepository) Create(ctx context.Context, rule models.NotificationRule) (*models.NotificationRule, error) {
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO notification_rules (tenant_id, name, active, event_types, geofence_ids, vehicle_groups, time_start, time_end,
+			timezone, min_speed_kmh, min_duration_s, cooldown_s, actions)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		 RETURNING `+notificationRuleColumns,
+		rule.TenantID, rule.Name, rule.Active, rule.EventTypes, rule.GeofenceIDs, rule.VehicleGroups, rule.TimeStart, rule.TimeEnd,
+		rule.Timezone, rule.MinSpeedKmh, rule.MinDurationS, rule.CooldownS, rule.Actions,
+	)
+	return scanNotificationRule(row)
+}
+
+func (r *notificationRuleRepository) Get(ctx context.Context, tenantID string, id int64) (*models.NotificationRule, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+notificationRuleColumns+` FROM notification_rules WHERE id = $1 AND ($2 = '' OR tenant_id = $2)`,
+		id, tenantID,
+	)
+	return scanNotificationRule(row)
+}
+
+func (r *notificationRuleRepository) List(ctx context.Context, tenantID string) ([]models.NotificationRule, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+notificationRuleColumns+` FROM notification_rules WHERE ($1 = '' OR tenant_id = $1) ORDER BY id ASC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.NotificationRule
+	for rows.Next() {
+		rule, err := scanNotificationRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *rule)
+	}
+	return result, rows.Err()
+}
+
+func (r *notificationRuleRepository) ListActive(ctx context.Context, tenantID, eventType string) ([]models.NotificationRule, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+notificationRuleColumns+` FROM notification_rules
+		 WHERE tenant_id = $1 AND active AND (cardinality(event_types) = 0 OR $2 = ANY(event_types))
+		 ORDER BY id ASC`,
+		tenantID, eventType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.NotificationRule
+	for rows.Next() {
+		rule, err := scanNotificationRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *rule)
+	}
+	return result, rows.Err()
+}
+
+func (r *notificationRuleRepository) Update(ctx context.Context, rule models.NotificationRule) (*models.NotificationRule, error) {
+	row := r.db.QueryRow(ctx,
+		`UPDATE notification_rules SET name = $3, active = $4, event_types = $5, geofence_ids = $6, vehicle_groups = $7,
+			time_start = $8, time_end = $9, timezone = $10, min_speed_kmh = $11, min_duration_s = $12, cooldown_s = $13,
+			actions = $14, updated_at = now()
+		 WHERE id = $1 AND ($2 = '' OR tenant_id = $2)
+		 RETURNING `+notificationRuleColumns,
+		rule.ID, rule.TenantID, rule.Name, rule.Active, rule.EventTypes, rule.GeofenceIDs, rule.VehicleGroups,
+		rule.TimeStart, rule.TimeEnd, rule.Timezone, rule.MinSpeedKmh, rule.MinDurationS, rule.CooldownS, rule.Actions,
+	)
+	return scanNotificationRule(row)
+}
+
+func (r *notificationRuleRepository) Delete(ctx context.Context, tenantID string, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM notification_rules WHERE id = $1 AND ($2 = '' OR tenant_id = $2)`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
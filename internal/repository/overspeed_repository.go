@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OverspeedRepository interface {
+	// GetOpen mengembalikan periode overspeed kendaraan yang masih berjalan, atau ErrNotFound.
+	GetOpen(ctx context.Context, tenantID, vehicleID string) (*models.Overspeed, error)
+	Create(ctx context.Context, o models.Overspeed) (*models.Overspeed, error)
+	// Update menyimpan titik terakhir dan kecepatan puncak periode yang masih berjalan.
+	Update(ctx context.Context, o models.Overspeed) error
+	// Close menutup periode overspeed pada waktu end; ErrNotFound bila sudah ditutup.
+	Close(ctx context.Context, id int64, end int64) (*models.Overspeed, error)
+	// List mengembalikan periode overspeed yang beririsan dengan [start, end], urut waktu mulai.
+	List(ctx context.Context, start, end int64, filter models.OverspeedFilter) ([]models.Overspeed, error)
+}
+
+type overspeedRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOverspeedRepository(db *pgxpool.Pool) OverspeedRepository {
+	return &overspeedRepository{db: db}
+}
+
+const overspeedColumns = `o.id, o.tenant_id, o.vehicle_id, COALESCE(o.driver_id, ''), o.start_ts, o.end_ts, o.last_ts,
+	o.limit_kmh, o.peak_speed_kmh, o.peak_ts, o.start_latitude, o.start_longitude,
+	o.peak_latitude, o.peak_longitude, o.geofence_id, COALESCE(g.name, '')`
+
+const overspeedFrom = ` FROM overspeed_events o LEFT JOIN geofences g ON g.id = o.geofence_id`
+
+func scanOverspeed(row pgx.Row) (*models.Overspeed, error) {
+	var o models.Overspeed
+	if err := row.Scan(&o.ID, &o.TenantID, &o.VehicleID, &o.DriverID, &o.Start, &o.End, &o.Last,
+		&o.LimitKmh, &o.PeakSpeedKmh, &o.PeakAt, &o.StartLocation.Latitude, &o.StartLocation.Longitude,
+		&o.PeakLocation.Latitude, &o.PeakLocation.Longitude, &o.GeofenceID, &o.GeofenceName); err != nil {
+		return nil, translateError(err)
+	}
+
+	until := o.Last
+	if o.End != nil {
+		until = *o.End
+	}
+	o.DurationS = until - o.Start
+	return &o, nil
+}
+
+func (r *overspeedRepository) GetOpen(ctx context.Context, tenantID, vehicleID string) (*models.Overspeed, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+overspeedColumns+overspeedFrom+` WHERE o.tenant_id = $1 AND o.vehicle_id = $2 AND o.end_ts IS NULL`,
+		tenantID, vehicleID,
+	)
+	return scanOverspeed(row)
+}
+
+func (r *overspeedRepository) Create(ctx context.Context, o models.Overspeed) (*models.Overspeed, error) {
+	var id int64
+	if err := r.db.QueryRow(ctx,
+		`INSERT INTO overspeed_events (vehicle_id, driver_id, start_ts, last_ts, limit_kmh, peak_speed_kmh, peak_ts,
+			start_latitude, start_longitude, peak_latitude, peak_longitude, geofence_id, tenant_id)
+		 VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		 RETURNING id`,
+		o.VehicleID, o.DriverID, o.Start, o.Last, o.LimitKmh, o.PeakSpeedKmh, o.PeakAt,
+		o.StartLocation.Latitude, o.StartLocation.Longitude, o.PeakLocation.Latitude, o.PeakLocation.Longitude, o.GeofenceID,
+		o.TenantID,
+	).Scan(&id); err != nil {
+		return nil, translateError(err)
+	}
+
+	row := r.db.QueryRow(ctx, `SELECT `+overspeedColumns+overspeedFrom+` WHERE o.id = $1`, id)
+	return scanOverspeed(row)
+}
+
+func (r *overspeedRepository) Update(ctx context.Context, o models.Overspeed) error {
+	tag, err := r.db.Exec(ctx,
+		`UPDATE overspeed_events SET
+			last_ts = $2, peak_speed_kmh = $3, peak_ts = $4, peak_latitude = $5, peak_longitude = $6
+		 WHERE id = $1 AND end_ts IS NULL`,
+		o.ID, o.Last, o.PeakSpeedKmh, o.PeakAt, o.PeakLocation.Latitude, o.PeakLocation.Longitude,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *overspeedRepository) Close(ctx context.Context, id int64, end int64) (*models.Overspeed, error) {
+	if err := r.db.QueryRow(ctx,
+		`UPDATE overspeed_events SET end_ts = $2, last_ts = GREATEST(last_ts, $2)
+		 WHERE id = $1 AND end_ts IS NULL
+		 RETURNING id`,
+		id, end,
+	).Scan(&id); err != nil {
+		return nil, translateError(err)
+	}
+
+	row := r.db.QueryRow(ctx, `SELECT `+overspeedColumns+overspeedFrom+` WHERE o.id = $1`, id)
+	return scanOverspeed(row)
+}
+
+func (r *overspeedRepository) List(ctx context.Context, start, end int64, filter models.OverspeedFilter) ([]models.Overspeed, error) {
+	query := `SELECT ` + overspeedColumns + overspeedFrom + `
+		 WHERE o.start_ts <= $2 AND COALESCE(o.end_ts, o.last_ts) >= $1`
+	args := []any{start, end}
+	if filter.TenantID != "" {
+		args = append(args, filter.TenantID)
+		query += fmt.Sprintf(` AND o.tenant_id = $%d`, len(args))
+	}
+	if filter.VehicleID != "" {
+		args = append(args, filter.VehicleID)
+		query += fmt.Sprintf(` AND o.vehicle_id = $%d`, len(args))
+	}
+	if filter.MinDuration > 0 {
+		args = append(args, filter.MinDuration)
+		query += fmt.Sprintf(` AND COALESCE(o.end_ts, o.last_ts) - o.start_ts >= $%d`, len(args))
+	}
+	query += ` ORDER BY o.start_ts ASC, o.id ASC`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Overspeed
+	for rows.Next() {
+		o, err := scanOverspeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *o)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// rollupLockBase + resolution dipakai sebagai key advisory lock per resolusi,
+// supaya beberapa replika tidak me-rollup rentang yang sama bersamaan.
+const rollupLockBase int64 = 7261530100
+
+// rollupInsert dan rollupGroup mengapit sumber titik (FROM vehicle_locations l ...) pada query
+// rollup; $1 adalah resolusi.
+const (
+	rollupInsert = `INSERT INTO vehicle_location_rollups AS r (
+			tenant_id, vehicle_id, resolution, bucket_start, point_count, first_timestamp, last_timestamp,
+			avg_latitude, avg_longitude, min_latitude, max_latitude, min_longitude, max_longitude,
+			last_location_id, last_latitude, last_longitude
+		)
+		SELECT l.tenant_id, l.vehicle_id, $1::INTEGER, l.timestamp - l.timestamp % $1, COUNT(*), MIN(l.timestamp), MAX(l.timestamp),
+			AVG(l.latitude), AVG(l.longitude), MIN(l.latitude), MAX(l.latitude), MIN(l.longitude), MAX(l.longitude),
+			(ARRAY_AGG(l.id ORDER BY l.timestamp DESC, l.id DESC))[1],
+			(ARRAY_AGG(l.latitude ORDER BY l.timestamp DESC, l.id DESC))[1],
+			(ARRAY_AGG(l.longitude ORDER BY l.timestamp DESC, l.id DESC))[1]`
+	rollupGroup = `GROUP BY l.tenant_id, l.vehicle_id, l.timestamp - l.timestamp % $1
+		ON CONFLICT (tenant_id, vehicle_id, resolution, bucket_start) DO UPDATE SET`
+)
+
+type RollupRepository interface {
+	// Refresh menggabungkan titik terlambat (rollup_late_locations) ke bucket yang sudah selesai, lalu
+	// me-rollup bucket dari watermark terakhir sampai maksimal `maxSpan` detik, tidak melewati `upto`.
+	// Mengembalikan true bila masih ada rentang tersisa.
+	Refresh(ctx context.Context, resolution, upto, maxSpan int64) (bool, error)
+	Watermark(ctx context.Context, resolution int64) (int64, error)
+	// GetHistory membaca rollup kendaraan milik tenantID; kosong = semua tenant (hanya untuk proses
+	// internal).
+	GetHistory(ctx context.Context, tenantID, vehicleID string, resolution, start, end int64) ([]models.VehicleLocation, error)
+}
+
+type rollupRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRollupRepository(db *pgxpool.Pool) RollupRepository {
+	return &rollupRepository{db: db}
+}
+
+func (r *rollupRepository) Refresh(ctx context.Context, resolution, upto, maxSpan int64) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, rollupLockBase+resolution).Scan(&locked); err != nil {
+		return false, err
+	}
+	if !locked {
+		// replika lain sedang mengerjakan resolusi ini
+		return false, nil
+	}
+
+	// FOR UPDATE menahan Insert yang sedang menandai titik terlambat sampai transaksi ini selesai:
+	// titik yang di-commit sebelumnya terlihat oleh query di bawah, titik sesudahnya membaca
+	// watermark baru dan ditandai terlambat.
+	var from, done int64
+	err = tx.QueryRow(ctx,
+		`SELECT completed_until FROM rollup_watermarks WHERE resolution = $1 FOR UPDATE`,
+		resolution,
+	).Scan(&done)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		var first *int64
+		if err := tx.QueryRow(ctx, `SELECT MIN(timestamp) FROM vehicle_locations`).Scan(&first); err != nil {
+			return false, err
+		}
+		if first == nil {
+			return false, nil
+		}
+		// Watermark pertama di-commit tanpa rollup supaya putaran berikutnya bisa menguncinya.
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO rollup_watermarks (resolution, completed_until) VALUES ($1, $2)
+			 ON CONFLICT (resolution) DO NOTHING`,
+			resolution, *first-*first%resolution,
+		); err != nil {
+			return false, err
+		}
+		return true, tx.Commit(ctx)
+	case err != nil:
+		return false, err
+	default:
+		// hitung ulang satu bucket terakhir untuk menampung data yang datang terlambat
+		from = done - resolution
+	}
+
+	// Titik terlambat di bucket sebelum from digabung ke rollup yang ada, bukan dihitung ulang dari
+	// vehicle_locations: titik lain di bucket itu mungkin sudah diarsipkan dan dihapus.
+	if _, err := tx.Exec(ctx,
+		`WITH late AS (
+			DELETE FROM rollup_late_locations WHERE resolution = $1 AND bucket_start < $2
+			RETURNING location_id
+		)
+		`+rollupInsert+`
+		FROM vehicle_locations l JOIN late ON late.location_id = l.id
+		`+rollupGroup+`
+			point_count = r.point_count + EXCLUDED.point_count,
+			first_timestamp = LEAST(r.first_timestamp, EXCLUDED.first_timestamp),
+			last_timestamp = GREATEST(r.last_timestamp, EXCLUDED.last_timestamp),
+			avg_latitude = (r.avg_latitude * r.point_count + EXCLUDED.avg_latitude * EXCLUDED.point_count)
+				/ (r.point_count + EXCLUDED.point_count),
+			avg_longitude = (r.avg_longitude * r.point_count + EXCLUDED.avg_longitude * EXCLUDED.point_count)
+				/ (r.point_count + EXCLUDED.point_count),
+			min_latitude = LEAST(r.min_latitude, EXCLUDED.min_latitude),
+			max_latitude = GREATEST(r.max_latitude, EXCLUDED.max_latitude),
+			min_longitude = LEAST(r.min_longitude, EXCLUDED.min_longitude),
+			max_longitude = GREATEST(r.max_longitude, EXCLUDED.max_longitude),
+			last_location_id = CASE WHEN (EXCLUDED.last_timestamp, EXCLUDED.last_location_id) > (r.last_timestamp, r.last_location_id)
+				THEN EXCLUDED.last_location_id ELSE r.last_location_id END,
+			last_latitude = CASE WHEN (EXCLUDED.last_timestamp, EXCLUDED.last_location_id) > (r.last_timestamp, r.last_location_id)
+				THEN EXCLUDED.last_latitude ELSE r.last_latitude END,
+			last_longitude = CASE WHEN (EXCLUDED.last_timestamp, EXCLUDED.last_location_id) > (r.last_timestamp, r.last_location_id)
+				THEN EXCLUDED.last_longitude ELSE r.last_longitude END`,
+		resolution, from,
+	); err != nil {
+		return false, err
+	}
+
+	to := min(from+maxSpan, upto)
+	if to <= done {
+		return false, tx.Commit(ctx)
+	}
+
+	// Bucket [from, to) dihitung ulang penuh, termasuk titik terlambatnya; penanda titik itu dihapus
+	// dalam statement (dan snapshot) yang sama supaya tidak ikut digabung lagi nanti.
+	if _, err := tx.Exec(ctx,
+		`WITH settled AS (
+			DELETE FROM rollup_late_locations WHERE resolution = $1 AND bucket_start >= $2 AND bucket_start < $3
+		)
+		`+rollupInsert+`
+		FROM vehicle_locations l
+		WHERE l.timestamp >= $2 AND l.timestamp < $3
+		`+rollupGroup+`
+			point_count = EXCLUDED.point_count,
+			first_timestamp = EXCLUDED.first_timestamp,
+			last_timestamp = EXCLUDED.last_timestamp,
+			avg_latitude = EXCLUDED.avg_latitude,
+			avg_longitude = EXCLUDED.avg_longitude,
+			min_latitude = EXCLUDED.min_latitude,
+			max_latitude = EXCLUDED.max_latitude,
+			min_longitude = EXCLUDED.min_longitude,
+			max_longitude = EXCLUDED.max_longitude,
+			last_location_id = EXCLUDED.last_location_id,
+			last_latitude = EXCLUDED.last_latitude,
+			last_longitude = EXCLUDED.last_longitude`,
+		resolution, from, to,
+	); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO rollup_watermarks (resolution, completed_until) VALUES ($1, $2)
+		 ON CONFLICT (resolution) DO UPDATE SET completed_until = EXCLUDED.completed_until`,
+		resolution, to,
+	); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return to < upto, nil
+}
+
+func (r *rollupRepository) Watermark(ctx context.Context, resolution int64) (int64, error) {
+	var until int64
+	err := r.db.QueryRow(ctx,
+		`SELECT completed_until FROM rollup_watermarks WHERE resolution = $1`,
+		resolution,
+	).Scan(&until)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return until, err
+}
+
+// GetHistory mengembalikan titik terakhir setiap bucket dalam rentang [start, end).
+func (r *rollupRepository) GetHistory(ctx context.Context, tenantID, vehicleID string, resolution, start, end int64) ([]models.VehicleLocation, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT last_location_id, tenant_id, vehicle_id, last_latitude, last_longitude, last_timestamp
+		 FROM vehicle_location_rollups
+		 WHERE vehicle_id = $1 AND resolution = $2 AND bucket_start >= $3 AND bucket_start < $4
+			AND ($5 = '' OR tenant_id = $5)
+		 ORDER BY bucket_start ASC, tenant_id ASC`,
+		vehicleID, resolution, start, end, tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.VehicleLocation
+	for rows.Next() {
+		var loc models.VehicleLocation
+		if err := rows.Scan(&loc.ID, &loc.TenantID, &loc.VehicleID, &loc.Latitude, &loc.Longitude, &loc.Timestamp); err != nil {
+			return nil, err
+		}
+		result = append(result, loc)
+	}
+	return result, rows.Err()
+}
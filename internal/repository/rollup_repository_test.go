@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"sistem-manajemen-armada/internal/migrate"
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testPool membuka PostgreSQL dari TEST_POSTGRES_URL dengan schema sementara yang sudah dimigrasi.
+// Test dilewati bila env itu kosong.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL not set")
+	}
+	ctx := context.Background()
+
+	admin, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(admin.Close)
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`) })
+
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema + ",public"
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if err := migrate.Run(ctx, pool); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return pool
+}
+
+// rollupCounts mengembalikan point_count per bucket untuk kendaraan V1.
+func rollupCounts(t *testing.T, pool *pgxpool.Pool, resolution int64) map[int64]int64 {
+	t.Helper()
+	rows, err := pool.Query(context.Background(),
+		`SELECT bucket_start, point_count FROM vehicle_location_rollups
+		 WHERE vehicle_id = 'V1' AND resolution = $1`,
+		resolution,
+	)
+	if err != nil {
+		t.Fatalf("query rollups: %v", err)
+	}
+	defer rows.Close()
+	counts := map[int64]int64{}
+	for rows.Next() {
+		var bucket, n int64
+		if err := rows.Scan(&bucket, &n); err != nil {
+			t.Fatalf("scan rollup: %v", err)
+		}
+		counts[bucket] = n
+	}
+	return counts
+}
+
+// refreshUntil menjalankan Refresh sampai tidak ada rentang tersisa, seperti RollupService.
+func refreshUntil(rollups RollupRepository, resolution, upto int64) error {
+	for {
+		more, err := rollups.Refresh(context.Background(), resolution, upto, 24*60*60)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+func TestRollupRefreshMergesLatePoints(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	locations := NewLocationRepository(pool)
+	rollups := NewRollupRepository(pool)
+	insert := func(ts int64) {
+		t.Helper()
+		loc := models.VehicleLocation{TenantID: "default", VehicleID: "V1", Latitude: -6.2, Longitude: 106.8, Timestamp: ts}
+		if err := locations.Insert(ctx, loc); err != nil {
+			t.Fatalf("Insert(%d): %v", ts, err)
+		}
+	}
+
+	const res = 60
+	insert(600)
+	insert(610)
+	insert(670)
+	if err := refreshUntil(rollups, res, 900); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got := rollupCounts(t, pool, res); got[600] != 2 || got[660] != 1 {
+		t.Fatalf("rollups after first refresh = %v, want 600:2 660:1", got)
+	}
+
+	// titik untuk bucket yang sudah selesai ditandai saat insert, lalu digabung putaran berikutnya
+	insert(620)
+	if err := refreshUntil(rollups, res, 900); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got := rollupCounts(t, pool, res); got[600] != 3 || got[660] != 1 {
+		t.Fatalf("rollups after late point = %v, want 600:3 660:1", got)
+	}
+	var pending int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM rollup_late_locations`).Scan(&pending); err != nil {
+		t.Fatalf("count late locations: %v", err)
+	}
+	if pending != 0 {
+		t.Errorf("late locations left after refresh = %d, want 0", pending)
+	}
+
+	// insert yang berjalan bersamaan dengan Refresh tidak boleh hilang: setiap titik harus masuk
+	// rollup, baik lewat hitung ulang maupun lewat penanda terlambat
+	insert(900)
+	var (
+		wg         sync.WaitGroup
+		refreshErr error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for upto := int64(960); upto <= 3000 && refreshErr == nil; upto += res {
+			refreshErr = refreshUntil(rollups, res, upto)
+		}
+	}()
+	for ts := int64(900); ts < 3000; ts += 7 {
+		insert(ts)
+	}
+	wg.Wait()
+	if refreshErr != nil {
+		t.Fatalf("concurrent Refresh: %v", refreshErr)
+	}
+	if err := refreshUntil(rollups, res, 3000); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	var want []struct{ bucket, n int64 }
+	rows, err := pool.Query(ctx,
+		`SELECT timestamp - timestamp % $1, COUNT(*) FROM vehicle_locations
+		 WHERE vehicle_id = 'V1' AND timestamp < 3000 GROUP BY 1`,
+		res,
+	)
+	if err != nil {
+		t.Fatalf("count locations: %v", err)
+	}
+	for rows.Next() {
+		var w struct{ bucket, n int64 }
+		if err := rows.Scan(&w.bucket, &w.n); err != nil {
+			t.Fatalf("scan count: %v", err)
+		}
+		want = append(want, w)
+	}
+	rows.Close()
+	got := rollupCounts(t, pool, res)
+	for _, w := range want {
+		if got[w.bucket] != w.n {
+			t.Errorf("bucket %d: rollup count = %d, want %d", w.bucket, got[w.bucket], w.n)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SpeedLimitRepository interface {
+	// Get mengembalikan batas kecepatan jenis kendaraan milik tenant, atau ErrNotFound.
+	Get(ctx context.Context, tenantID, vehicleType string) (*models.SpeedLimit, error)
+	// List mengembalikan batas kecepatan milik tenant; tenantID kosong = semua tenant.
+	List(ctx context.Context, tenantID string) ([]models.SpeedLimit, error)
+	// Upsert membuat atau mengganti batas kecepatan jenis kendaraan milik l.TenantID.
+	Upsert(ctx context.Context, l models.SpeedLimit) (*models.SpeedLimit, error)
+	Delete(ctx context.Context, tenantID, vehicleType string) error
+}
+
+type speedLimitRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSpeedLimitRepository(db *pgxpool.Pool) SpeedLimitRepository {
+	return &speedLimitRepository{db: db}
+}
+
+const speedLimitColumns = `tenant_id, vehicle_type, limit_kmh, updated_at`
+
+func scanSpeedLimit(row pgx.Row) (*models.SpeedLimit, error) {
+	var l models.SpeedLimit
+	if err := row.Scan(&l.TenantID, &l.VehicleType, &l.LimitKmh, &l.UpdatedAt); err != nil {
+		return nil, translateError(err)
+	}
+	return &l, nil
+}
+
+func (r *speedLimitRepository) Get(ctx context.Context, tenantID, vehicleType string) (*models.SpeedLimit, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+speedLimitColumns+` FROM vehicle_type_speed_limits WHERE tenant_id = $1 AND vehicle_type = $2`,
+		tenantID, vehicleType,
+	)
+	return scanSpeedLimit(row)
+}
+
+func (r *speedLimitRepository) List(ctx context.Context, tenantID string) ([]models.SpeedLimit, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+speedLimitColumns+` FROM vehicle_type_speed_limits
+		 WHERE ($1 = '' OR tenant_id = $1)
+		 ORDER BY tenant_id ASC, vehicle_type ASC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.SpeedLimit
+	for rows.Next() {
+		l, err := scanSpeedLimit(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *l)
+	}
+	return result, rows.Err()
+}
+
+func (r *speedLimitRepository) Upsert(ctx context.Context, l models.SpeedLimit) (*models.SpeedLimit, error) {
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO vehicle_type_speed_limits (tenant_id, vehicle_type, limit_kmh)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (tenant_id, vehicle_type) DO UPDATE SET limit_kmh = EXCLUDED.limit_kmh, updated_at = now()
+		 RETURNING `+speedLimitColumns,
+		l.TenantID, l.VehicleType, l.LimitKmh,
+	)
+	return scanSpeedLimit(row)
+}
+
+func (r *speedLimitRepository) Delete(ctx context.Context, tenantID, vehicleType string) error {
+	tag, err := r.db.Exec(ctx,
+		`DELETE FROM vehicle_type_speed_limits WHERE tenant_id = $1 AND vehicle_type = $2`,
+		tenantID, vehicleType,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
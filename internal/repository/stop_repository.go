@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StopRepository interface {
+	// GetOpen mengembalikan periode diam kendaraan yang masih berjalan, atau ErrNotFound.
+	GetOpen(ctx context.Context, tenantID, vehicleID string) (*models.Stop, error)
+	Create(ctx context.Context, s models.Stop) (*models.Stop, error)
+	// Touch memajukan titik diam terakhir periode yang masih berjalan.
+	Touch(ctx context.Context, id int64, last int64) error
+	// Close menutup periode diam pada waktu end.
+	Close(ctx context.Context, id int64, end int64) error
+	// Discard menghapus periode diam yang terlalu singkat untuk dicatat.
+	Discard(ctx context.Context, id int64) error
+	// List mengembalikan periode diam yang beririsan dengan [start, end], urut waktu mulai.
+	// tenantID kosong = semua tenant (hanya untuk proses internal).
+	List(ctx context.Context, tenantID, vehicleID string, start, end int64, filter models.StopFilter) ([]models.Stop, error)
+}
+
+type stopRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewStopRepository(db *pgxpool.Pool) StopRepository {
+	return &stopRepository{db: db}
+}
+
+const stopColumns = `s.id, s.tenant_id, s.vehicle_id, COALESCE(s.driver_id, ''), s.type, s.start_ts, s.end_ts, s.last_ts,
+	s.latitude, s.longitude, s.geofence_id, COALESCE(g.name, '')`
+
+const stopFrom = ` FROM vehicle_stops s LEFT JOIN geofences g ON g.id = s.geofence_id`
+
+func scanStop(row pgx.Row) (*models.Stop, error) {
+	var s models.Stop
+	if err := row.Scan(&s.ID, &s.TenantID, &s.VehicleID, &s.DriverID, &s.Type, &s.Start, &s.End, &s.Last,
+		&s.Location.Latitude, &s.Location.Longitude, &s.GeofenceID, &s.GeofenceName); err != nil {
+		return nil, translateError(err)
+	}
+
+	until := s.Last
+	if s.End != nil {
+		until = *s.End
+	}
+	s.DurationS = until - s.Start
+	return &s, nil
+}
+
+func (r *stopRepository) GetOpen(ctx context.Context, tenantID, vehicleID string) (*models.Stop, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+stopColumns+stopFrom+` WHERE s.tenant_id = $1 AND s.vehicle_id = $2 AND s.end_ts IS NULL`,
+		tenantID, vehicleID,
+	)
+	return scanStop(row)
+}
+
+func (r *stopRepository) Create(ctx context.Context, s models.Stop) (*models.Stop, error) {
+	var id int64
+	if err := r.db.QueryRow(ctx,
+		`INSERT INTO vehicle_stops (vehicle_id, driver_id, type, start_ts, last_ts, latitude, longitude, geofence_id)
+		 VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8)
+		 RETURNING id`,
+		s.VehicleID, s.DriverID, s.Type, s.Start, s.Last, s.Location.Latitude, s.Location.Longitude, s.GeofenceID,
+	).Scan(&id); err != nil {
+		return nil, translateError(err)
+	}
+
+	row := r.db.QueryRow(ctx, `SELECT `+stopColumns+stopFrom+` WHERE s.id = $1`, id)
+	return scanStop(row)
+}
+
+func (r *stopRepository) Touch(ctx context.Context, id int64, last int64) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE vehicle_stops SET last_ts = GREATEST(last_ts, $2) WHERE id = $1 AND end_ts IS NULL`,
+		id, last,
+	)
+	return err
+}
+
+func (r *stopRepository) Close(ctx context.Context, id int64, end int64) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE vehicle_stops SET end_ts = $2, last_ts = $2 WHERE id = $1 AND end_ts IS NULL`,
+		id, end,
+	)
+	return err
+}
+
+func (r *stopRepository) Discard(ctx context.Context, id int64) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM vehicle_stops WHERE id = $1`, id)
+	return err
+}
+
+func (r *stopRepository) List(ctx context.Context, tenantID, vehicleID string, start, end int64, filter models.StopFilter) ([]models.Stop, error) {
+	query := `SELECT ` + stopColumns + stopFrom + `
+		 WHERE ($4 = '' OR s.tenant_id = $4) AND s.vehicle_id = $1
+			AND s.start_ts <= $3 AND COALESCE(s.end_ts, s.last_ts) >= $2`
+	args := []any{vehicleID, start, end, tenantID}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		query += fmt.Sprintf(` AND s.type = $%d`, len(args))
+	}
+	if filter.MinDuration > 0 {
+		args = append(args, filter.MinDuration)
+		query += fmt.Sprintf(` AND COALESCE(s.end_ts, s.last_ts) - s.start_ts >= $%d`, len(args))
+	}
+	query += ` ORDER BY s.start_ts ASC, s.id ASC`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Stop
+	for rows.Next() {
+		s, err := scanStop(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *s)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TripRepository interface {
+	// GetOpen mengembalikan perjalanan kendaraan yang masih berjalan, atau ErrNotFound.
+	GetOpen(ctx context.Context, tenantID, vehicleID string) (*models.Trip, error)
+	Create(ctx context.Context, t models.Trip) (*models.Trip, error)
+	// Update menyimpan progres perjalanan yang masih berjalan (titik terakhir, jarak, state berhenti).
+	Update(ctx context.Context, t models.Trip) error
+	// Close menutup perjalanan pada t.End / t.EndLocation.
+	Close(ctx context.Context, t models.Trip) (*models.Trip, error)
+	// List mengembalikan perjalanan yang beririsan dengan [start, end], urut waktu mulai.
+	// vehicleID kosong = seluruh armada; tenantID kosong = semua tenant (hanya untuk proses internal).
+	List(ctx context.Context, tenantID, vehicleID string, start, end int64) ([]models.Trip, error)
+}
+
+type tripRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTripRepository(db *pgxpool.Pool) TripRepository {
+	return &tripRepository{db: db}
+}
+
+const tripColumns = `id, tenant_id, vehicle_id, COALESCE(driver_id, ''), start_ts, start_latitude, start_longitude,
+	end_ts, end_latitude, end_longitude, last_ts, last_latitude, last_longitude,
+	stopped_ts, stopped_latitude, stopped_longitude, distance_m, max_speed_kmh, point_count`
+
+func scanTrip(row pgx.Row) (*models.Trip, error) {
+	var (
+		t                      models.Trip
+		endLat, endLon         *float64
+		stoppedTs              *int64
+		stoppedLat, stoppedLon *float64
+	)
+	if err := row.Scan(&t.ID, &t.TenantID, &t.VehicleID, &t.DriverID, &t.Start, &t.StartLocation.Latitude, &t.StartLocation.Longitude,
+		&t.End, &endLat, &endLon, &t.Last.Timestamp, &t.Last.Latitude, &t.Last.Longitude,
+		&stoppedTs, &stoppedLat, &stoppedLon, &t.DistanceM, &t.MaxSpeedKmh, &t.PointCount); err != nil {
+		return nil, translateError(err)
+	}
+
+	t.Last.VehicleID = t.VehicleID
+	if t.End != nil && endLat != nil && endLon != nil {
+		t.EndLocation = &models.Coordinate{Latitude: *endLat, Longitude: *endLon}
+	}
+	if stoppedTs != nil && stoppedLat != nil && stoppedLon != nil {
+		t.Stopped = &models.VehicleLocation{
+			VehicleID: t.VehicleID,
+			Timestamp: *stoppedTs,
+			Latitude:  *stoppedLat,
+			Longitude: *stoppedLon,
+		}
+	}
+
+	until := t.Last.Timestamp
+	if t.End != nil {
+		until = *t.End
+	}
+	t.DurationS = until - t.Start
+	if t.DurationS > 0 {
+		t.AvgSpeedKmh = t.DistanceM / float64(t.DurationS) * 3.6
+	}
+	return &t, nil
+}
+
+// stoppedArgs memecah state berhenti menjadi kolom nullable.
+func stoppedArgs(t models.Trip) (ts *int64, lat, lon *float64) {
+	if t.Stopped == nil {
+		return nil, nil, nil
+	}
+	return &t.Stopped.Timestamp, &t.Stopped.Latitude, &t.Stopped.Longitude
+}
+
+func (r *tripRepository) GetOpen(ctx context.Context, tenantID, vehicleID string) (*models.Trip, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+tripColumns+` FROM trips WHERE tenant_id = $1 AND vehicle_id = $2 AND end_ts IS NULL`,
+		tenantID, vehicleID,
+	)
+	return scanTrip(row)
+}
+
+func (r *tripRepository) Create(ctx context.Context, t models.Trip) (*models.Trip, error) {
+	stoppedTs, stoppedLat, stoppedLon := stoppedArgs(t)
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO trips (vehicle_id, driver_id, start_ts, start_latitude, start_longitude,
+			last_ts, last_latitude, last_longitude, stopped_ts, stopped_latitude, stopped_longitude,
+			distance_m, max_speed_kmh, point_count, tenant_id)
+		 VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		 RETURNING `+tripColumns,
+		t.VehicleID, t.DriverID, t.Start, t.StartLocation.Latitude, t.StartLocation.Longitude,
+		t.Last.Timestamp, t.Last.Latitude, t.Last.Longitude, stoppedTs, stoppedLat, stoppedLon,
+		t.DistanceM, t.MaxSpeedKmh, t.PointCount, t.TenantID,
+	)
+	return scanTrip(row)
+}
+
+func (r *tripRepository) Update(ctx context.Context, t models.Trip) error {
+	stoppedTs, stoppedLat, stoppedLon := stoppedArgs(t)
+	tag, err := r.db.Exec(ctx,
+		`UPDATE trips SET
+			last_ts = $2, last_latitude = $3, last_longitude = $4,
+			stopped_ts = $5, stopped_latitude = $6, stopped_longitude = $7,
+			distance_m = $8, max_speed_kmh = $9, point_count = $10,
+			updated_at = now()
+		 WHERE id = $1 AND end_ts IS NULL`,
+		t.ID, t.Last.Timestamp, t.Last.Latitude, t.Last.Longitude, stoppedTs, stoppedLat, stoppedLon,
+		t.DistanceM, t.MaxSpeedKmh, t.PointCount,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *tripRepository) Close(ctx context.Context, t models.Trip) (*models.Trip, error) {
+	row := r.db.QueryRow(ctx,
+		`UPDATE trips SET
+			end_ts = $2, end_latitude = $3, end_longitude = $4,
+			last_ts = $5, last_latitude = $6, last_longitude = $7,
+			stopped_ts = NULL, stopped_latitude = NULL, stopped_longitude = NULL,
+			distance_m = $8, max_speed_kmh = $9, point_count = $10,
+			updated_at = now()
+		 WHERE id = $1 AND end_ts IS NULL
+		 RETURNING `+tripColumns,
+		t.ID, t.End, t.EndLocation.Latitude, t.EndLocation.Longitude,
+		t.Last.Timestamp, t.Last.Latitude, t.Last.Longitude,
+		t.DistanceM, t.MaxSpeedKmh, t.PointCount,
+	)
+	return scanTrip(row)
+}
+
+func (r *tripRepository) List(ctx context.Context, tenantID, vehicleID string, start, end int64) ([]models.Trip, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+tripColumns+`
+		 FROM trips
+		 WHERE ($4 = '' OR tenant_id = $4) AND ($1 = '' OR vehicle_id = $1)
+			AND start_ts <= $3 AND COALESCE(end_ts, last_ts) >= $2
+		 ORDER BY start_ts ASC, id ASC`,
+		vehicleID, start, end, tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Trip
+	for rows.Next() {
+		t, err := scanTrip(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *t)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// VehicleRepository menyimpan registry kendaraan. ID kendaraan unik per tenant; parameter tenantID
+// kosong = semua tenant (hanya untuk proses internal).
+type VehicleRepository interface {
+	Create(ctx context.Context, v models.Vehicle) (*models.Vehicle, error)
+	Get(ctx context.Context, tenantID, id string) (*models.Vehicle, error)
+	List(ctx context.Context, filter models.VehicleFilter) ([]models.Vehicle, error)
+	// Update mengubah kendaraan v.ID milik v.TenantID.
+	Update(ctx context.Context, v models.Vehicle) (*models.Vehicle, error)
+	Delete(ctx context.Context, tenantID, id string) error
+	// HasPosition melaporkan apakah kendaraan sudah mengirim posisi di tenant, terdaftar atau tidak.
+	HasPosition(ctx context.Context, tenantID, id string) (bool, error)
+	// VisibleIDs mengembalikan ID kendaraan milik tenant (kosong = semua tenant) di salah satu grup.
+	// groups nil berarti semua grup, termasuk kendaraan belum terdaftar yang sudah mengirim posisi.
+	VisibleIDs(ctx context.Context, tenantID string, groups []string) ([]string, error)
+	// Quarantine menyimpan lokasi yang ditolak registry ke quarantined_locations.
+	Quarantine(ctx context.Context, loc models.VehicleLocation, reason string) error
+}
+
+type vehicleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewVehicleRepository(db *pgxpool.Pool) VehicleRepository {
+	return &vehicleRepository{db: db}
+}
+
+const vehicleColumns = `id, tenant_id, plate_number, COALESCE(vin, ''), type, capacity_kg, COALESCE(device_imei, ''),
+	group_name, active, created_at, updated_at`
+
+func scanVehicle(row pgx.Row) (*models.Vehicle, error) {
+	var v models.Vehicle
+	if err := row.Scan(&v.ID, &v.TenantID, &v.PlateNumber, &v.VIN, &v.Type, &v.CapacityKg, &v.DeviceIMEI,
+		&v.Group, &v.Active, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		return nil, translateError(err)
+	}
+	return &v, nil
+}
+
+func (r *vehicleRepository) Create(ctx context.Context, v models.Vehicle) (*models.Vehicle, error) {
+	// VIN / IMEI kosong disimpan sebagai NULL supaya tidak bentrok dengan constraint UNIQUE
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO vehicles (id, plate_number, vin, type, capacity_kg, device_imei, group_name, active, tenant_id)
+		 VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''), $7, $8, $9)
+		 RETURNING `+vehicleColumns,
+		v.ID, v.PlateNumber, v.VIN, v.Type, v.CapacityKg, v.DeviceIMEI, v.Group, v.Active, v.TenantID,
+	)
+	return scanVehicle(row)
+}
+
+func (r *vehicleRepository) Get(ctx context.Context, tenantID, id string) (*models.Vehicle, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+vehicleColumns+` FROM vehicles WHERE id = $1 AND ($2 = '' OR tenant_id = $2)
+		 ORDER BY tenant_id LIMIT 1`,
+		id, tenantID,
+	)
+	return scanVehicle(row)
+}
+
+func (r *vehicleRepository) List(ctx context.Context, filter models.VehicleFilter) ([]models.Vehicle, error) {
+	query := `SELECT ` + vehicleColumns + ` FROM vehicles WHERE TRUE`
+	var args []any
+	if filter.TenantID != "" {
+		args = append(args, filter.TenantID)
+		query += fmt.Sprintf(` AND tenant_id = $%d`, len(args))
+	}
+	if filter.Group != "" {
+		args = append(args, filter.Group)
+		query += fmt.Sprintf(` AND group_name = $%d`, len(args))
+	}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		query += fmt.Sprintf(` AND active = $%d`, len(args))
+	}
+	if filter.Groups != nil {
+		args = append(args, filter.Groups)
+		query += fmt.Sprintf(` AND group_name = ANY($%d)`, len(args))
+	}
+	query += ` ORDER BY id ASC`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Vehicle
+	for rows.Next() {
+		v, err := scanVehicle(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *v)
+	}
+	return result, rows.Err()
+}
+
+func (r *vehicleRepository) Update(ctx context.Context, v models.Vehicle) (*models.Vehicle, error) {
+	row := r.db.QueryRow(ctx,
+		`UPDATE vehicles SET
+			plate_number = $2,
+			vin = NULLIF($3, ''),
+			type = $4,
+			capacity_kg = $5,
+			device_imei = NULLIF($6, ''),
+			group_name = $7,
+			active = $8,
+			updated_at = now()
+		 WHERE id = $1 AND tenant_id = $9
+		 RETURNING `+vehicleColumns,
+		v.ID, v.PlateNumber, v.VIN, v.Type, v.CapacityKg, v.DeviceIMEI, v.Group, v.Active, v.TenantID,
+	)
+	return scanVehicle(row)
+}
+
+func (r *vehicleRepository) Delete(ctx context.Context, tenantID, id string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM vehicles WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *vehicleRepository) HasPosition(ctx context.Context, tenantID, id string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM vehicle_latest WHERE tenant_id = $1 AND vehicle_id = $2)`,
+		tenantID, id,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (r *vehicleRepository) VisibleIDs(ctx context.Context, tenantID string, groups []string) ([]string, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT id FROM vehicles
+		 WHERE ($1 = '' OR tenant_id = $1) AND ($2::TEXT[] IS NULL OR group_name = ANY($2))
+		 UNION
+		 SELECT vehicle_id FROM vehicle_latest
+		 WHERE $2::TEXT[] IS NULL AND ($1 = '' OR tenant_id = $1)`,
+		tenantID, groups,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *vehicleRepository) Quarantine(ctx context.Context, loc models.VehicleLocation, reason string) error {
+	_, err := r.db.Exec(ctx,
+		`INSERT INTO quarantined_locations (vehicle_id, latitude, longitude, timestamp, reason, tenant_id, device_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))`,
+		loc.VehicleID, loc.Latitude, loc.Longitude, loc.Timestamp, reason, loc.TenantID, loc.DeviceID,
+	)
+	return err
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookDeliveryRepository menyimpan log pengiriman webhook, sekaligus antrean pengiriman ulang.
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, d models.WebhookDelivery) (*models.WebhookDelivery, error)
+	// Enqueue membuat satu pengiriman pending untuk setiap webhook aktif milik tenant yang
+	// berlangganan eventType, dalam satu statement, dan mengembalikan jumlahnya. Webhook yang sudah
+	// punya pengiriman untuk eventKey dilewati, sehingga event yang sama aman diantrekan ulang.
+	Enqueue(ctx context.Context, tenantID, eventType, eventKey string, payload []byte) (int64, error)
+	// Get mengembalikan pengiriman milik webhook dan tenant (kosong = tenant mana pun), atau ErrNotFound.
+	Get(ctx context.Context, tenantID string, webhookID, id int64) (*models.WebhookDelivery, error)
+	// List mengembalikan pengiriman terbaru sebuah webhook, paling baru dulu.
+	List(ctx context.Context, tenantID string, webhookID int64, limit int) ([]models.WebhookDelivery, error)
+	// ClaimDue mengambil maksimal limit pengiriman pending yang sudah jatuh tempo dan menunda
+	// next_attempt_at-nya sebesar lease, supaya worker lain tidak mengirim pengiriman yang sama.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	// RecordAttempt menyimpan hasil percobaan: status, attempts, next_attempt_at, kode status dan error.
+	RecordAttempt(ctx context.Context, d models.WebhookDelivery) error
+}
+
+type webhookDeliveryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookDeliveryRepository(db *pgxpool.Pool) WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: db}
+}
+
+const webhookDeliveryColumns = `id, webhook_id, tenant_id, event_type, payload, status, attempts, next_attempt_at,
+	last_status_code, last_error, redelivery_of, created_at, delivered_at`
+
+func scanWebhookDelivery(row pgx.Row) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.TenantID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &d.RedeliveryOf, &d.CreatedAt, &d.DeliveredAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &d, nil
+}
+
+func (r *webhookDeliveryRepository) Create(ctx context.Context, d models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, tenant_id, event_type, payload, redelivery_of)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING `+webhookDeliveryColumns,
+		d.WebhookID, d.TenantID, d.EventType, d.Payload, d.RedeliveryOf,
+	)
+	return scanWebhookDelivery(row)
+}
+
+func (r *webhookDeliveryRepository) Enqueue(ctx context.Context, tenantID, eventType, eventKey string, payload []byte) (int64, error) {
+	tag, err := r.db.Exec(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, tenant_id, event_type, payload, event_key)
+		 SELECT id, tenant_id, $2, $3, $4 FROM webhooks
+		 WHERE tenant_id = $1 AND active AND (cardinality(event_types) = 0 OR $2 = ANY(event_types))
+		 ON CONFLICT (webhook_id, event_key) WHERE event_key IS NOT NULL DO NOTHING`,
+		tenantID, eventType, string(payload), eventKey,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *webhookDeliveryRepository) Get(ctx context.Context, tenantID string, webhookID, id int64) (*models.WebhookDelivery, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		 WHERE id = $1 AND webhook_id = $2 AND ($3 = '' OR tenant_id = $3)`,
+		id, webhookID, tenantID,
+	)
+	return scanWebhookDelivery(row)
+}
+
+func (r *webhookDeliveryRepository) List(ctx context.Context, tenantID string, webhookID int64, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		 WHERE webhook_id = $1 AND ($2 = '' OR tenant_id = $2)
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $3`,
+		webhookID, tenantID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *d)
+	}
+	return result, rows.Err()
+}
+
+func (r *webhookDeliveryRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx,
+		`UPDATE webhook_deliveries SET next_attempt_at = now() + $2::INTERVAL
+		 WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING `+webhookDeliveryColumns,
+		limit, lease,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *d)
+	}
+	return result, rows.Err()
+}
+
+func (r *webhookDeliveryRepository) RecordAttempt(ctx context.Context, d models.WebhookDelivery) error {
+	_, err := r.db.Exec(ctx,
+		`UPDATE webhook_deliveries
+		 SET status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5, last_error = $6, delivered_at = $7
+		 WHERE id = $1`,
+		d.ID, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError, d.DeliveredAt,
+	)
+	return err
+}
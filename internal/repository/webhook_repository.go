@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+
+	"sistem-manajemen-armada/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookRepository menyimpan konfigurasi webhook. Parameter tenantID membatasi ke webhook milik
+// tenant tersebut; kosong = semua tenant (hanya untuk proses internal).
+type WebhookRepository interface {
+	Create(ctx context.Context, w models.Webhook) (*models.Webhook, error)
+	Get(ctx context.Context, tenantID string, id int64) (*models.Webhook, error)
+	List(ctx context.Context, tenantID string) ([]models.Webhook, error)
+	// Update mengubah URL, jenis event dan status aktif webhook milik w.TenantID.
+	Update(ctx context.Context, w models.Webhook) (*models.Webhook, error)
+	Delete(ctx context.Context, tenantID string, id int64) error
+}
+
+type webhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+const webhookColumns = `id, tenant_id, url, event_types, active, secret, created_at, updated_at`
+
+func scanWebhook(row pgx.Row) (*models.Webhook, error) {
+	var w models.Webhook
+	if err := row.Scan(&w.ID, &w.TenantID, &w.URL, &w.EventTypes, &w.Active, &w.Secret, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, translateError(err)
+	}
+	return &w, nil
+}
+
+func (r *webhookRepository) Create(ctx context.Context, w models.Webhook) (*models.Webhook, error) {
+	row := r.db.QueryRow(ctx,
+		`INSERT INTO webhooks (tenant_id, url, event_types, active, secret)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING `+webhookColumns,
+		w.TenantID, w.URL, w.EventTypes, w.Active, w.Secret,
+	)
+	return scanWebhook(row)
+}
+
+func (r *webhookRepository) Get(ctx context.Context, tenantID string, id int64) (*models.Webhook, error) {
+	row := r.db.QueryRow(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND ($2 = '' OR tenant_id = $2)`,
+		id, tenantID,
+	)
+	return scanWebhook(row)
+}
+
+func (r *webhookRepository) List(ctx context.Context, tenantID string) ([]models.Webhook, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE ($1 = '' OR tenant_id = $1) ORDER BY id ASC`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *w)
+	}
+	return result, rows.Err()
+}
+
+func (r *webhookRepository) Update(ctx context.Context, w models.Webhook) (*models.Webhook, error) {
+	row := r.db.QueryRow(ctx,
+		`UPDATE webhooks SET url = $3, event_types = $4, active = $5, updated_at = now()
+		 WHERE id = $1 AND ($2 = '' OR tenant_id = $2)
+		 RETURNING `+webhookColumns,
+		w.ID, w.TenantID, w.URL, w.EventTypes, w.Active,
+	)
+	return scanWebhook(row)
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, tenantID string, id int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND ($2 = '' OR tenant_id = $2)`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"sistem-manajemen-armada/internal/auth"
+)
+
+// Akses ke data kendaraan dibatasi per tenant lalu per grup kendaraan sesuai principal di context
+// (lihat auth.Principal). Kendaraan yang tidak boleh dilihat diperlakukan seolah tidak ada
+// (ErrNotFound), supaya keberadaannya tidak bocor. Context tanpa principal (proses internal,
+// autentikasi dimatikan) tidak dibatasi.
+
+// tenantOf mengembalikan tenant pemanggil untuk membatasi query; kosong = semua tenant.
+func tenantOf(ctx context.Context) string {
+	return auth.FromContext(ctx).TenantID()
+}
+
+// ownerTenant mengembalikan tenant pemilik data yang dibuat pemanggil.
+func ownerTenant(ctx context.Context) string {
+	if tenant := tenantOf(ctx); tenant != "" {
+		return tenant
+	}
+	return auth.DefaultTenant
+}
+
+// allowedGroups mengembalikan grup yang boleh dilihat pemanggil; nil = semua grup.
+func allowedGroups(ctx context.Context) []string {
+	p := auth.FromContext(ctx)
+	if p.AllGroups() {
+		return nil
+	}
+	if p.Groups == nil {
+		return []string{}
+	}
+	return p.Groups
+}
+
+// CheckAccess mengembalikan ErrNotFound bila kendaraan tidak dikenal, milik tenant lain, atau di luar
+// grup pemanggil. Kendaraan yang belum terdaftar hanya terlihat oleh pemanggil tanpa batasan grup di
+// tenant yang sama. Aman dipanggil pada service nil (tanpa registry), yang berarti tanpa batasan.
+func (s *VehicleService) CheckAccess(ctx context.Context, vehicleID string) error {
+	p := auth.FromContext(ctx)
+	if s == nil || p == nil {
+		return nil
+	}
+	v, err := s.repo.Get(ctx, p.Tenant, vehicleID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		if !p.AllGroups() {
+			return ErrNotFound
+		}
+		known, err := s.repo.HasPosition(ctx, p.Tenant, vehicleID)
+		if err != nil {
+			return err
+		}
+		if !known {
+			return ErrNotFound
+		}
+		return nil
+	case err != nil:
+		return err
+	}
+	if !p.CanSeeGroup(v.Group) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Visible mengembalikan predikat kendaraan yang boleh dilihat pemanggil, untuk menyaring hasil yang
+// mencakup banyak kendaraan.
+func (s *VehicleService) Visible(ctx context.Context) (func(vehicleID string) bool, error) {
+	p := auth.FromContext(ctx)
+	if s == nil || p == nil {
+		return func(string) bool { return true }, nil
+	}
+	visible, err := s.repo.VisibleIDs(ctx, p.Tenant, allowedGroups(ctx))
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(visible))
+	for _, id := range visible {
+		ids[id] = true
+	}
+	return func(vehicleID string) bool { return ids[vehicleID] }, nil
+}
+
+// checkGroupAllowed menolak pembuatan / pemindahan kendaraan ke grup di luar akses pemanggil.
+func checkGroupAllowed(ctx context.Context, group string) error {
+	if !auth.FromContext(ctx).CanSeeGroup(group) {
+		return invalidf("group %q is not allowed", group)
+	}
+	return nil
+}
+
+// checkAllGroups menolak pemanggil yang dibatasi ke sebagian grup kendaraan, untuk fitur yang
+// mencakup seluruh armada tenant (mis. webhook event).
+func checkAllGroups(ctx context.Context) error {
+	if !auth.FromContext(ctx).AllGroups() {
+		return invalidf("only callers with access to all vehicle groups can manage this resource")
+	}
+	return nil
+}
+
+// filterVisible menyisakan item yang key-nya (ID kendaraan atau grup) lolos predikat visible.
+func filterVisible[T any](items []T, visible func(key string) bool, key func(T) string) []T {
+	result := items[:0]
+	for _, item := range items {
+		if visible(key(item)) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
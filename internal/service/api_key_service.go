@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"sistem-manajemen-armada/internal/auth"
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+const (
+	// apiKeyPrefix menandai key milik aplikasi ini, memudahkan secret scanner mengenali key yang bocor.
+	apiKeyPrefix = "fk_"
+	// apiKeyTouchEvery membatasi update last_used_at supaya tidak ada write database di setiap request.
+	apiKeyTouchEvery = time.Minute
+)
+
+// apiKeyScopes adalah scope yang boleh diberikan ke API key.
+var apiKeyScopes = []string{auth.ScopeReadLocations, auth.ScopeWriteLocations, auth.ScopeAdminGeofences, auth.ScopeAdminWebhooks}
+
+// APIKeyService menerbitkan, mencabut dan memverifikasi API key, serta membatasi laju request per key.
+// Rate limit dihitung di memori per replika API.
+type APIKeyService struct {
+	repo             repository.APIKeyRepository
+	defaultRateLimit int
+
+	mu      sync.Mutex
+	buckets map[int64]*tokenBucket
+	touched map[int64]time.Time
+}
+
+// NewAPIKeyService membuat service API key. defaultRateLimit (request per menit) dipakai bila key
+// dibuat tanpa rate limit.
+func NewAPIKeyService(repo repository.APIKeyRepository, defaultRateLimit int) *APIKeyService {
+	return &APIKeyService{
+		repo:             repo,
+		defaultRateLimit: defaultRateLimit,
+		buckets:          map[int64]*tokenBucket{},
+		touched:          map[int64]time.Time{},
+	}
+}
+
+// Create menerbitkan key baru untuk tenant pemanggil. Key lengkap hanya dikembalikan di sini.
+func (s *APIKeyService) Create(ctx context.Context, k models.APIKey) (*models.CreatedAPIKey, error) {
+	k.Name = strings.TrimSpace(k.Name)
+	if k.Name == "" {
+		return nil, invalidf("name is required")
+	}
+	if len(k.Scopes) == 0 {
+		return nil, invalidf("scopes is required")
+	}
+	for _, scope := range k.Scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			return nil, invalidf("scope %q is not allowed, must be one of %s", scope, strings.Join(apiKeyScopes, ", "))
+		}
+	}
+	slices.Sort(k.Scopes)
+	k.Scopes = slices.Compact(k.Scopes)
+	if k.RateLimitPerMinute < 0 {
+		return nil, invalidf("rate_limit_per_minute must not be negative")
+	}
+	if k.RateLimitPerMinute == 0 {
+		k.RateLimitPerMinute = s.defaultRateLimit
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now()) {
+		return nil, invalidf("expires_at must be in the future")
+	}
+	if p := auth.FromContext(ctx); p != nil {
+		k.CreatedBy = p.Subject
+	}
+	k.TenantID = ownerTenant(ctx)
+
+	prefix := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	k.Prefix = hex.EncodeToString(prefix)
+	key := apiKeyPrefix + k.Prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	k.Hash = hashAPIKey(key)
+
+	created, err := s.repo.Create(ctx, k)
+	if err != nil {
+		if errors.Is(err, ErrConflict) {
+			// tabrakan prefix acak; sangat jarang, klien cukup mengulang
+			return nil, errors.New("api key prefix collision, retry")
+		}
+		return nil, err
+	}
+	return &models.CreatedAPIKey{APIKey: *created, Key: key}, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context) ([]models.APIKey, error) {
+	return s.repo.List(ctx, tenantOf(ctx))
+}
+
+// Revoke mencabut key milik tenant pemanggil. Key langsung ditolak di request berikutnya.
+func (s *APIKeyService) Revoke(ctx context.Context, id int64) (*models.APIKey, error) {
+	return s.repo.Revoke(ctx, tenantOf(ctx), id)
+}
+
+// Authenticate memverifikasi key dari header X-API-Key dan mencatat waktu pemakaian terakhir.
+// Semua kegagalan dikembalikan sebagai ErrInvalidAPIKey supaya klien tidak bisa membedakan penyebabnya.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	k, err := s.repo.GetByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if subtle.ConstantTimeCompare(k.Hash, hashAPIKey(key)) != 1 ||
+		k.RevokedAt != nil || (k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	s.touch(ctx, k.ID, now)
+	return k, nil
+}
+
+// Allow mengambil satu token dari bucket key. Bila habis, dikembalikan lama tunggu sampai token berikutnya.
+func (s *APIKeyService) Allow(k *models.APIKey, now time.Time) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[k.ID]
+	if !ok || b.limit != k.RateLimitPerMinute {
+		b = newTokenBucket(k.RateLimitPerMinute, now)
+		s.buckets[k.ID] = b
+	}
+	return b.take(now)
+}
+
+func (s *APIKeyService) touch(ctx context.Context, id int64, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.touched[id]) < apiKeyTouchEvery {
+		s.mu.Unlock()
+		return
+	}
+	s.touched[id] = now
+	s.mu.Unlock()
+
+	if err := s.repo.TouchLastUsed(ctx, id, now); err != nil {
+		log.Printf("failed to update api key %d last_used_at: %v", id, err)
+	}
+}
+
+func hashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// tokenBucket mengizinkan burst sampai limit request, diisi ulang merata limit token per menit.
+type tokenBucket struct {
+	limit  int
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit int, now time.Time) *tokenBucket {
+	return &tokenBucket{limit: limit, tokens: float64(limit), last: now}
+}
+
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	rate := float64(b.limit) / time.Minute.Seconds() // token per detik
+	b.tokens = min(float64(b.limit), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
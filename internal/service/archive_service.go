@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"sistem-manajemen-armada/internal/archive"
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// archiveSpan adalah ukuran satu file arsip (satu hari UTC).
+const archiveSpan = int64(24 * 60 * 60)
+
+// ArchiveService memindahkan vehicle_locations lama ke file gzip CSV dan membacanya kembali.
+type ArchiveService struct {
+	repo  repository.ArchiveRepository
+	store archive.Store
+	after time.Duration
+}
+
+func NewArchiveService(repo repository.ArchiveRepository, store archive.Store, after time.Duration) *ArchiveService {
+	return &ArchiveService{repo: repo, store: store, after: after}
+}
+
+// Run mengarsipkan secara periodik sampai ctx dibatalkan.
+func (s *ArchiveService) Run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.ArchiveOnce(ctx, time.Now()); err != nil {
+			log.Printf("archive failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ArchiveOnce mengarsipkan semua hari penuh yang lebih tua dari `after`. Mengembalikan jumlah file.
+func (s *ArchiveService) ArchiveOnce(ctx context.Context, now time.Time) (int, error) {
+	cutoff := now.Add(-s.after).Unix()
+	cutoff -= cutoff % archiveSpan
+
+	files := 0
+	for ctx.Err() == nil {
+		oldest, ok, err := s.repo.OldestBefore(ctx, cutoff)
+		if err != nil {
+			return files, err
+		}
+		if !ok {
+			return files, nil
+		}
+
+		start := oldest - oldest%archiveSpan
+		end := min(start+archiveSpan, cutoff)
+
+		entry, err := s.archiveRange(ctx, start, end, now)
+		if err != nil {
+			return files, fmt.Errorf("archive %d-%d: %w", start, end, err)
+		}
+		if entry == nil {
+			continue
+		}
+
+		log.Printf("Archived %d locations [%d, %d) to %s", entry.RowCount, start, end, entry.ObjectKey)
+		files++
+	}
+	return files, ctx.Err()
+}
+
+func (s *ArchiveService) archiveRange(ctx context.Context, start, end int64, now time.Time) (*models.LocationArchive, error) {
+	day := time.Unix(start, 0).UTC()
+	// suffix waktu pembuatan: data terlambat untuk hari yang sama menghasilkan file kedua
+	key := fmt.Sprintf("vehicle_locations/%s/%d-%d-%d.csv.gz", day.Format("2006/01/02"), start, end, now.UnixNano())
+
+	w, err := archive.NewWriter(s.store, key)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	return s.repo.ArchiveRange(ctx, start, end, w)
+}
+
+// ForEach membaca titik arsip milik vehicleID di tenantID (kosong = semua tenant) dalam rentang
+// [start, end] setelah `after` (opsional), urut (timestamp, id). Arsip dibaca per rentang hari, jadi
+// memori yang dipakai maksimal satu hari data.
+func (s *ArchiveService) ForEach(ctx context.Context, tenantID, vehicleID string, start, end int64, after *models.HistoryCursor, fn func(loc models.VehicleLocation) error) error {
+	archives, err := s.repo.ListOverlapping(ctx, tenantID, vehicleID, start, end)
+	if err != nil {
+		return err
+	}
+
+	keep := func(loc models.VehicleLocation) bool {
+		if loc.VehicleID != vehicleID || loc.Timestamp < start || loc.Timestamp > end {
+			return false
+		}
+		// baris arsip lama tanpa tenant_id sudah dipilih lewat manifest per tenant
+		if tenantID != "" && loc.TenantID != "" && loc.TenantID != tenantID {
+			return false
+		}
+		return after == nil || afterCursor(loc, *after)
+	}
+
+	for i := 0; i < len(archives); {
+		// arsip dengan rentang sama (data terlambat) digabung lalu diurutkan ulang
+		j := i + 1
+		for j < len(archives) && archives[j].RangeStart == archives[i].RangeStart && archives[j].RangeEnd == archives[i].RangeEnd {
+			j++
+		}
+		group := archives[i:j]
+		i = j
+
+		if after != nil && group[0].RangeEnd <= after.Timestamp {
+			continue
+		}
+
+		if len(group) == 1 {
+			if err := s.readArchive(ctx, group[0], func(loc models.VehicleLocation) error {
+				if !keep(loc) {
+					return nil
+				}
+				return fn(loc)
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var merged []models.VehicleLocation
+		for _, a := range group {
+			if err := s.readArchive(ctx, a, func(loc models.VehicleLocation) error {
+				if keep(loc) {
+					merged = append(merged, loc)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		sortLocations(merged)
+		for _, loc := range merged {
+			if err := fn(loc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *ArchiveService) readArchive(ctx context.Context, a models.LocationArchive, fn func(loc models.VehicleLocation) error) error {
+	r, err := s.store.Get(ctx, a.ObjectKey)
+	if err != nil {
+		return fmt.Errorf("read archive %s: %w", a.ObjectKey, err)
+	}
+	defer r.Close()
+
+	if err := archive.ReadCSV(r, fn); err != nil {
+		return fmt.Errorf("read archive %s: %w", a.ObjectKey, err)
+	}
+	return nil
+}
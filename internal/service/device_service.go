@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// Algoritma tanda tangan payload device.
+const (
+	SigningHMACSHA256 = "hmac-sha256"
+	SigningEd25519    = "ed25519"
+)
+
+type DeviceService struct {
+	repo     repository.DeviceRepository
+	vehicles *VehicleService
+}
+
+func NewDeviceService(repo repository.DeviceRepository, vehicles *VehicleService) *DeviceService {
+	return &DeviceService{repo: repo, vehicles: vehicles}
+}
+
+func (s *DeviceService) Create(ctx context.Context, d models.Device) (*models.Device, error) {
+	if err := validateDevice(&d); err != nil {
+		return nil, err
+	}
+	d.TenantID = ownerTenant(ctx)
+	return s.repo.Create(ctx, d)
+}
+
+// Get mengembalikan device, atau ErrNotFound bila tidak ada atau milik tenant lain.
+func (s *DeviceService) Get(ctx context.Context, id string) (*models.Device, error) {
+	return s.repo.Get(ctx, tenantOf(ctx), id)
+}
+
+func (s *DeviceService) List(ctx context.Context) ([]models.Device, error) {
+	return s.repo.List(ctx, tenantOf(ctx))
+}
+
+func (s *DeviceService) Update(ctx context.Context, d models.Device) (*models.Device, error) {
+	if err := validateDevice(&d); err != nil {
+		return nil, err
+	}
+	existing, err := s.Get(ctx, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	d.TenantID = existing.TenantID
+	return s.repo.Update(ctx, d)
+}
+
+func (s *DeviceService) Delete(ctx context.Context, id string) error {
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, existing.TenantID, id)
+}
+
+func (s *DeviceService) ListAssignments(ctx context.Context, deviceID string) ([]models.DeviceAssignment, error) {
+	d, err := s.Get(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListAssignments(ctx, d.TenantID, deviceID)
+}
+
+// SetSigningKey mewajibkan payload device ditandatangani. Untuk hmac-sha256 secret dibuat server
+// dan hanya dikembalikan di sini; untuk ed25519 publicKey (base64, 32 byte) berasal dari device.
+// Kunci lama diganti. ErrNotFound bila device milik tenant lain.
+func (s *DeviceService) SetSigningKey(ctx context.Context, deviceID, algorithm, publicKey string) (*models.DeviceSigningKey, error) {
+	d, err := s.Get(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.DeviceSigningKey{DeviceID: deviceID, Algorithm: algorithm}
+
+	var key []byte
+	switch algorithm {
+	case SigningHMACSHA256:
+		if publicKey != "" {
+			return nil, invalidf("public_key is only used with %s", SigningEd25519)
+		}
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		result.Secret = base64.StdEncoding.EncodeToString(key)
+	case SigningEd25519:
+		decoded, err := base64.StdEncoding.DecodeString(publicKey)
+		if err != nil || len(decoded) != ed25519.PublicKeySize {
+			return nil, invalidf("public_key must be a base64 encoded %d-byte ed25519 public key", ed25519.PublicKeySize)
+		}
+		key = decoded
+	default:
+		return nil, invalidf("algorithm must be %s or %s", SigningHMACSHA256, SigningEd25519)
+	}
+
+	if _, err := s.repo.SetSigningKey(ctx, d.TenantID, deviceID, algorithm, key); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteSigningKey melepas kunci tanda tangan; payload device tidak lagi wajib ditandatangani.
+// ErrNotFound bila device milik tenant lain.
+func (s *DeviceService) DeleteSigningKey(ctx context.Context, deviceID string) error {
+	d, err := s.Get(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	_, err = s.repo.SetSigningKey(ctx, d.TenantID, deviceID, "", nil)
+	return err
+}
+
+// VerifySignature memeriksa tanda tangan payload dari tracker trackerID di tenant tenantID.
+// signature adalah base64 dari HMAC-SHA256 atau tanda tangan Ed25519 atas byte payload apa adanya.
+// Tracker yang bukan device terdaftar, atau device tanpa kunci, tidak wajib menandatangani.
+// Mengembalikan ErrTamperSuspected bila tanda tangan wajib tetapi tidak ada atau tidak valid.
+func (s *DeviceService) VerifySignature(ctx context.Context, tenantID, trackerID string, payload []byte, signature string) error {
+	d, err := s.repo.Get(ctx, tenantID, trackerID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return nil
+	case err != nil:
+		return err
+	case d.SigningAlgorithm == "":
+		return nil
+	}
+
+	if payload == nil || signature == "" {
+		return fmt.Errorf("%w: unsigned payload", ErrTamperSuspected)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrTamperSuspected)
+	}
+
+	var ok bool
+	switch d.SigningAlgorithm {
+	case SigningHMACSHA256:
+		mac := hmac.New(sha256.New, d.SigningKey)
+		mac.Write(payload)
+		ok = hmac.Equal(sig, mac.Sum(nil))
+	case SigningEd25519:
+		ok = len(d.SigningKey) == ed25519.PublicKeySize && ed25519.Verify(d.SigningKey, payload, sig)
+	default:
+		return fmt.Errorf("device %s has unknown signing algorithm %q", d.ID, d.SigningAlgorithm)
+	}
+	if !ok {
+		return fmt.Errorf("%w: invalid signature", ErrTamperSuspected)
+	}
+	return nil
+}
+
+// CurrentVehicle mengembalikan kendaraan yang saat ini dipasangi device. ErrNotFound bila device
+// tidak terdaftar di tenant pemanggil atau tidak sedang terpasang.
+func (s *DeviceService) CurrentVehicle(ctx context.Context, deviceID string) (string, error) {
+	return s.vehicleAt(ctx, tenantOf(ctx), deviceID, time.Now().Unix())
+}
+
+// vehicleAt mengembalikan kendaraan yang dipasangi device milik tenantID pada timestamp ts, atau
+// ErrNotFound.
+func (s *DeviceService) vehicleAt(ctx context.Context, tenantID, deviceID string, ts int64) (string, error) {
+	res, err := s.repo.Resolve(ctx, tenantID, deviceID, ts)
+	if err != nil {
+		return "", err
+	}
+	if res.VehicleID == "" {
+		return "", ErrNotFound
+	}
+	return res.VehicleID, nil
+}
+
+// Assign memasang device ke kendaraan mulai a.Start. Pemasangan terbuka sebelumnya ditutup
+// otomatis; titik yang sudah tercatat dalam rentang baru dipindah ke kendaraan ini. Device dan
+// kendaraan harus milik tenant yang sama.
+func (s *DeviceService) Assign(ctx context.Context, a models.DeviceAssignment) (*models.DeviceAssignment, error) {
+	if a.VehicleID == "" {
+		return nil, invalidf("vehicle_id is required")
+	}
+	if a.Start <= 0 {
+		return nil, invalidf("start is required")
+	}
+	if a.End != nil && *a.End <= a.Start {
+		return nil, invalidf("end must be after start")
+	}
+	d, err := s.Get(ctx, a.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	// device hanya dipasang di kendaraan tenant yang sama
+	if _, err := s.vehicles.getInTenant(ctx, d.TenantID, a.VehicleID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, invalidf("vehicle %q is not registered", a.VehicleID)
+		}
+		return nil, err
+	}
+	a.TenantID = d.TenantID
+	return s.repo.Assign(ctx, a)
+}
+
+// Unassign melepas device dari kendaraan pada waktu at (0 = sekarang).
+func (s *DeviceService) Unassign(ctx context.Context, deviceID string, at int64) (*models.DeviceAssignment, error) {
+	d, err := s.Get(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if at == 0 {
+		at = time.Now().Unix()
+	}
+	return s.repo.Unassign(ctx, d.TenantID, deviceID, at)
+}
+
+// Resolve mengisi loc.VehicleID dari kendaraan yang dipasangi device pada timestamp titik.
+// ID device diambil dari loc.DeviceID, atau loc.VehicleID untuk tracker lama yang mengirim
+// ID-nya sebagai vehicle_id. ID yang bukan device terdaftar di loc.TenantID diperlakukan sebagai
+// ID kendaraan.
+//
+// Kendaraan tidak pernah dipilih oleh vehicle_id di payload bila device_id diisi: kendaraan diambil
+// dari pemasangan device, atau ID device itu sendiri bila device tidak terdaftar / tidak terpasang.
+// vehicle_id yang diisi dan berbeda dari hasil tersebut ditolak (ErrRejected).
+func (s *DeviceService) Resolve(ctx context.Context, loc *models.VehicleLocation) error {
+	explicit := loc.DeviceID != ""
+	var claimed string
+	if explicit {
+		claimed = loc.VehicleID
+	} else {
+		loc.DeviceID = loc.VehicleID
+	}
+	loc.VehicleID = loc.DeviceID
+
+	res, err := s.repo.Resolve(ctx, loc.TenantID, loc.DeviceID, loc.Timestamp)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		if !explicit {
+			// bukan device: titik dicatat untuk kendaraan dengan ID tersebut, tanpa device
+			loc.DeviceID = ""
+			return nil
+		}
+		err = s.vehicles.Refuse(ctx, *loc, "unregistered device")
+	case err != nil:
+		return err
+	case !res.Active:
+		err = s.vehicles.Refuse(ctx, *loc, "inactive device")
+	case res.VehicleID == "":
+		err = s.vehicles.Refuse(ctx, *loc, "device not assigned to a vehicle")
+	default:
+		loc.VehicleID = res.VehicleID
+	}
+	if err != nil {
+		return err
+	}
+
+	if claimed != "" && claimed != loc.VehicleID {
+		return fmt.Errorf("%w: vehicle_id %q does not match vehicle %q of device %s", ErrRejected, claimed, loc.VehicleID, loc.DeviceID)
+	}
+	return nil
+}
+
+func validateDevice(d *models.Device) error {
+	d.ID = strings.TrimSpace(d.ID)
+	d.IMEI = strings.TrimSpace(d.IMEI)
+	d.Model = strings.TrimSpace(d.Model)
+
+	if !vehicleIDPattern.MatchString(d.ID) {
+		return invalidf("id must be 1-50 characters of letters, digits, '-' or '_'")
+	}
+	if d.IMEI != "" && (len(d.IMEI) < 14 || len(d.IMEI) > 16 || strings.Trim(d.IMEI, "0123456789") != "") {
+		return invalidf("imei must be 14-16 digits")
+	}
+	if len(d.Model) > 50 {
+		return invalidf("model must be at most 50 characters")
+	}
+	return nil
+}
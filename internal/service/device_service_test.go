@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// fakeDeviceRepository hanya mendukung Resolve: device -> kendaraan yang dipasangi.
+type fakeDeviceRepository struct {
+	repository.DeviceRepository
+	assigned map[string]string // tenant/device -> kendaraan; kosong = tidak terpasang
+}
+
+func (r *fakeDeviceRepository) Resolve(_ context.Context, tenantID, id string, _ int64) (*repository.DeviceResolution, error) {
+	vehicleID, ok := r.assigned[groupKey(tenantID, id)]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &repository.DeviceResolution{Active: true, VehicleID: vehicleID}, nil
+}
+
+func TestDeviceServiceResolveIgnoresPayloadVehicle(t *testing.T) {
+	devices := NewDeviceService(&fakeDeviceRepository{assigned: map[string]string{
+		"acme/TRK-1": "V5",
+		"acme/TRK-2": "",
+	}}, NewVehicleService(&fakeVehicleRepository{}, PolicyAccept))
+
+	tests := []struct {
+		name        string
+		loc         models.VehicleLocation
+		wantVehicle string
+		wantDevice  string
+		wantErr     error
+	}{
+		{"device maps to its vehicle", models.VehicleLocation{DeviceID: "TRK-1"}, "V5", "TRK-1", nil},
+		{"matching vehicle_id is accepted", models.VehicleLocation{DeviceID: "TRK-1", VehicleID: "V5"}, "V5", "TRK-1", nil},
+		{"mismatched vehicle_id is rejected", models.VehicleLocation{DeviceID: "TRK-1", VehicleID: "V2"}, "", "", ErrRejected},
+		{"legacy tracker sends device ID as vehicle_id", models.VehicleLocation{VehicleID: "TRK-1"}, "V5", "TRK-1", nil},
+		{"non-device vehicle_id stays a vehicle", models.VehicleLocation{VehicleID: "V7"}, "V7", "", nil},
+		{"unregistered device does not pick payload vehicle", models.VehicleLocation{DeviceID: "V1", VehicleID: "V2"}, "", "", ErrRejected},
+		{"unregistered device falls back to its own ID", models.VehicleLocation{DeviceID: "V1"}, "V1", "V1", nil},
+		{"unassigned device does not pick payload vehicle", models.VehicleLocation{DeviceID: "TRK-2", VehicleID: "V2"}, "", "", ErrRejected},
+		{"device of another tenant is unknown", models.VehicleLocation{TenantID: "beta", DeviceID: "TRK-1", VehicleID: "V5"}, "", "", ErrRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := tt.loc
+			if loc.TenantID == "" {
+				loc.TenantID = "acme"
+			}
+			err := devices.Resolve(context.Background(), &loc)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Resolve error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if loc.VehicleID != tt.wantVehicle || loc.DeviceID != tt.wantDevice {
+				t.Fatalf("Resolve = vehicle %q device %q, want vehicle %q device %q", loc.VehicleID, loc.DeviceID, tt.wantVehicle, tt.wantDevice)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// maxReportDays membatasi rentang laporan jarak.
+const maxReportDays = 366
+
+// Sumber jarak harian pada laporan.
+const (
+	DistanceSourceGPS      = "gps"
+	DistanceSourceOdometer = "odometer"
+)
+
+// DistanceService mengakumulasi jarak tempuh harian saat ingest dan menyusun laporan jarak.
+type DistanceService struct {
+	repo     repository.DistanceRepository
+	vehicles *VehicleService
+	tz       *time.Location
+}
+
+func NewDistanceService(repo repository.DistanceRepository, vehicles *VehicleService, tz *time.Location) *DistanceService {
+	return &DistanceService{repo: repo, vehicles: vehicles, tz: tz}
+}
+
+// ProcessLocation memenuhi LocationProcessor. Jarak segmen dari titik sebelumnya dihitung dengan
+// Haversine dan dicatat di hari titik baru; segmen yang menyiratkan lompatan GPS dibuang.
+func (s *DistanceService) ProcessLocation(ctx context.Context, prev *models.VehicleLocation, loc models.VehicleLocation) error {
+	inc := repository.DistanceIncrement{
+		TenantID:      loc.TenantID,
+		VehicleID:     loc.VehicleID,
+		Date:          time.Unix(loc.Timestamp, 0).In(s.tz).Format(time.DateOnly),
+		Timestamp:     loc.Timestamp,
+		OdometerStart: loc.Odometer,
+		Odometer:      loc.Odometer,
+	}
+
+	if prev != nil {
+		// bacaan sebelumnya (bisa dari hari kemarin) menjadi awal hari supaya jarak lewat tengah malam ikut terhitung
+		if prev.Odometer != nil {
+			inc.OdometerStart = prev.Odometer
+		}
+
+		d, ok := segmentMeters(*prev, loc)
+		switch {
+		case !ok:
+			inc.Rejected = true
+		case loc.Speed != nil && *loc.Speed == 0:
+			// tracker melaporkan diam: pergeseran posisi hanya noise GPS
+		default:
+			inc.DistanceM = d
+		}
+	}
+
+	return s.repo.Add(ctx, inc)
+}
+
+// Report menyusun laporan jarak per kendaraan per hari untuk tanggal [from, to] (YYYY-MM-DD).
+// Hanya kendaraan tenant dan grup yang boleh dilihat pemanggil yang disertakan.
+func (s *DistanceService) Report(ctx context.Context, from, to, group string) (*models.DistanceReport, error) {
+	fromDate, err1 := time.Parse(time.DateOnly, from)
+	toDate, err2 := time.Parse(time.DateOnly, to)
+	if err1 != nil || err2 != nil {
+		return nil, invalidf("from/to must be dates in YYYY-MM-DD format")
+	}
+	if toDate.Before(fromDate) {
+		return nil, invalidf("to must not be before from")
+	}
+	if toDate.Sub(fromDate) >= maxReportDays*24*time.Hour {
+		return nil, invalidf("date range must not exceed %d days", maxReportDays)
+	}
+
+	visible, err := s.vehicles.Visible(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.List(ctx, tenantOf(ctx), from, to, group)
+	if err != nil {
+		return nil, err
+	}
+	rows = filterVisible(rows, visible, func(d models.DailyDistance) string { return d.VehicleID })
+
+	report := &models.DistanceReport{
+		From:     from,
+		To:       to,
+		Timezone: s.tz.String(),
+		Group:    group,
+		Days:     []models.DistanceTotal{},
+		Vehicles: []models.VehicleDistanceReport{},
+	}
+	totals := map[string]float64{}
+	for _, row := range rows {
+		day := reconcileDay(row)
+
+		// baris sudah urut per kendaraan
+		n := len(report.Vehicles)
+		if n == 0 || report.Vehicles[n-1].VehicleID != row.VehicleID {
+			report.Vehicles = append(report.Vehicles, models.VehicleDistanceReport{
+				VehicleID: row.VehicleID,
+				Group:     row.Group,
+			})
+			n++
+		}
+		v := &report.Vehicles[n-1]
+		v.Days = append(v.Days, day)
+		v.DistanceM += day.DistanceM
+
+		totals[day.Date] += day.DistanceM
+		report.TotalDistanceM += day.DistanceM
+	}
+
+	for date, distance := range totals {
+		report.Days = append(report.Days, models.DistanceTotal{Date: date, DistanceM: distance})
+	}
+	sort.Slice(report.Days, func(i, j int) bool { return report.Days[i].Date < report.Days[j].Date })
+	return report, nil
+}
+
+// reconcileDay memilih jarak odometer bila bacaan awal/akhir tersedia dan masuk akal
+// (tidak mundur karena reset, tidak melebihi kecepatan maksimum sepanjang hari).
+func reconcileDay(row models.DailyDistance) models.DistanceDay {
+	day := models.DistanceDay{
+		Date:             row.Date,
+		DistanceM:        row.GPSDistanceM,
+		Source:           DistanceSourceGPS,
+		GPSDistanceM:     row.GPSDistanceM,
+		PointCount:       row.PointCount,
+		RejectedSegments: row.RejectedSegments,
+	}
+	if row.OdometerStart == nil || row.OdometerEnd == nil {
+		return day
+	}
+
+	odometerM := (*row.OdometerEnd - *row.OdometerStart) * 1000
+	if odometerM < 0 || odometerM > maxPlausibleSpeedKmh*24*1000 {
+		return day
+	}
+	day.OdometerDistanceM = &odometerM
+	day.DistanceM = odometerM
+	day.Source = DistanceSourceOdometer
+	return day
+}
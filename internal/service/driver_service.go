@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+type DriverService struct {
+	repo     repository.DriverRepository
+	vehicles *VehicleService
+}
+
+func NewDriverService(repo repository.DriverRepository, vehicles *VehicleService) *DriverService {
+	return &DriverService{repo: repo, vehicles: vehicles}
+}
+
+func (s *DriverService) Create(ctx context.Context, d models.Driver) (*models.Driver, error) {
+	if err := validateDriver(&d); err != nil {
+		return nil, err
+	}
+	d.TenantID = ownerTenant(ctx)
+	return s.repo.Create(ctx, d)
+}
+
+// Get mengembalikan pengemudi, atau ErrNotFound bila tidak ada atau milik tenant lain.
+func (s *DriverService) Get(ctx context.Context, id string) (*models.Driver, error) {
+	return s.repo.Get(ctx, tenantOf(ctx), id)
+}
+
+func (s *DriverService) List(ctx context.Context) ([]models.Driver, error) {
+	return s.repo.List(ctx, tenantOf(ctx))
+}
+
+func (s *DriverService) Update(ctx context.Context, d models.Driver) (*models.Driver, error) {
+	if err := validateDriver(&d); err != nil {
+		return nil, err
+	}
+	existing, err := s.Get(ctx, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	d.TenantID = existing.TenantID
+	return s.repo.Update(ctx, d)
+}
+
+func (s *DriverService) Delete(ctx context.Context, id string) error {
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, existing.TenantID, id)
+}
+
+// StartShift menugaskan pengemudi ke kendaraan. Shift tidak boleh beririsan dengan shift lain
+// untuk kendaraan atau pengemudi yang sama (ErrConflict).
+func (s *DriverService) StartShift(ctx context.Context, sh models.DriverShift) (*models.DriverShift, error) {
+	if sh.DriverID == "" || sh.VehicleID == "" {
+		return nil, invalidf("driver_id and vehicle_id are required")
+	}
+	if sh.Start == 0 {
+		sh.Start = time.Now().Unix()
+	}
+	if sh.Start < 0 {
+		return nil, invalidf("start must be a positive timestamp")
+	}
+	if sh.End != nil && *sh.End <= sh.Start {
+		return nil, invalidf("end must be after start")
+	}
+
+	d, err := s.Get(ctx, sh.DriverID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, invalidf("driver %q is not registered", sh.DriverID)
+		}
+		return nil, err
+	}
+	if !d.Active {
+		return nil, invalidf("driver %q is inactive", sh.DriverID)
+	}
+	// pengemudi hanya bertugas di kendaraan tenant yang sama
+	if _, err := s.vehicles.getInTenant(ctx, d.TenantID, sh.VehicleID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, invalidf("vehicle %q is not registered", sh.VehicleID)
+		}
+		return nil, err
+	}
+	sh.TenantID = d.TenantID
+
+	created, err := s.repo.CreateShift(ctx, sh)
+	if errors.Is(err, ErrConflict) {
+		return nil, fmt.Errorf("%w: shift overlaps another shift of this driver or vehicle", ErrConflict)
+	}
+	return created, err
+}
+
+func (s *DriverService) GetShift(ctx context.Context, id int64) (*models.DriverShift, error) {
+	return s.repo.GetShift(ctx, tenantOf(ctx), id)
+}
+
+func (s *DriverService) ListShifts(ctx context.Context, filter models.ShiftFilter) ([]models.DriverShift, error) {
+	filter.TenantID = tenantOf(ctx)
+	return s.repo.ListShifts(ctx, filter)
+}
+
+// EndShift menutup shift yang masih berjalan pada waktu at (0 = sekarang).
+func (s *DriverService) EndShift(ctx context.Context, id int64, at int64) (*models.DriverShift, error) {
+	sh, err := s.GetShift(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sh.End != nil {
+		return nil, invalidf("shift already ended")
+	}
+	if at == 0 {
+		at = time.Now().Unix()
+	}
+	if at <= sh.Start {
+		return nil, invalidf("end must be after start")
+	}
+	return s.repo.EndShift(ctx, sh.TenantID, id, at)
+}
+
+func (s *DriverService) DeleteShift(ctx context.Context, id int64) error {
+	return s.repo.DeleteShift(ctx, tenantOf(ctx), id)
+}
+
+// Timeline memuat shift kendaraan milik tenantID yang beririsan dengan [start, end] untuk dicocokkan
+// ke titik riwayat.
+func (s *DriverService) Timeline(ctx context.Context, tenantID, vehicleID string, start, end int64) (DriverTimeline, error) {
+	shifts, err := s.repo.ListShifts(ctx, models.ShiftFilter{TenantID: tenantID, VehicleID: vehicleID, Start: start, End: end})
+	if err != nil {
+		return nil, err
+	}
+	return DriverTimeline(shifts), nil
+}
+
+// DriverAt mengembalikan ID pengemudi kendaraan milik tenantID pada timestamp ts, atau "" bila tidak
+// ada shift.
+func (s *DriverService) DriverAt(ctx context.Context, tenantID, vehicleID string, ts int64) (string, error) {
+	timeline, err := s.Timeline(ctx, tenantID, vehicleID, ts, ts)
+	if err != nil {
+		return "", err
+	}
+	return timeline.At(ts), nil
+}
+
+// DriverTimeline adalah shift satu kendaraan, urut waktu mulai dan tidak saling beririsan.
+type DriverTimeline []models.DriverShift
+
+// At mengembalikan ID pengemudi pada timestamp ts, atau "" bila tidak ada shift.
+func (t DriverTimeline) At(ts int64) string {
+	// shift terakhir yang dimulai <= ts
+	i := sort.Search(len(t), func(i int) bool { return t[i].Start > ts }) - 1
+	if i < 0 {
+		return ""
+	}
+	if sh := t[i]; sh.End == nil || ts < *sh.End {
+		return sh.DriverID
+	}
+	return ""
+}
+
+// Annotate mengisi DriverID setiap titik.
+func (t DriverTimeline) Annotate(locations []models.VehicleLocation) {
+	if len(t) == 0 {
+		return
+	}
+	for i := range locations {
+		locations[i].DriverID = t.At(locations[i].Timestamp)
+	}
+}
+
+func validateDriver(d *models.Driver) error {
+	d.ID = strings.TrimSpace(d.ID)
+	d.Name = strings.TrimSpace(d.Name)
+	d.LicenseNumber = strings.ToUpper(strings.TrimSpace(d.LicenseNumber))
+	d.LicenseExpiry = strings.TrimSpace(d.LicenseExpiry)
+	d.Phone = strings.TrimSpace(d.Phone)
+	d.Email = strings.TrimSpace(d.Email)
+
+	if !vehicleIDPattern.MatchString(d.ID) {
+		return invalidf("id must be 1-50 characters of letters, digits, '-' or '_'")
+	}
+	if d.Name == "" || len(d.Name) > 100 {
+		return invalidf("name is required (max 100 characters)")
+	}
+	if d.LicenseNumber == "" || len(d.LicenseNumber) > 30 {
+		return invalidf("license_number is required (max 30 characters)")
+	}
+	if _, err := time.Parse(time.DateOnly, d.LicenseExpiry); err != nil {
+		return invalidf("license_expiry must be a date in YYYY-MM-DD format")
+	}
+	if len(d.Phone) > 20 || strings.Trim(d.Phone, "+0123456789 -") != "" {
+		return invalidf("phone must be at most 20 characters of digits, '+', '-' or spaces")
+	}
+	if d.Email != "" {
+		if _, err := mail.ParseAddress(d.Email); err != nil || len(d.Email) > 100 {
+			return invalidf("email is invalid")
+		}
+	}
+	return nil
+}
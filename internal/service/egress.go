@@ -0,0 +1,70 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// blockedNetworks adalah rentang non-publik selain yang sudah dikenali method netip.Addr
+// (loopback, privat, link-local, multicast, unspecified).
+var blockedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "jaringan ini"
+	netip.MustParsePrefix("100.64.0.0/10"), // shared address space (CGNAT)
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, termasuk broadcast
+}
+
+// egressAllowed melaporkan apakah koneksi keluar ke addr diizinkan: alamat publik selalu boleh,
+// alamat internal hanya bila termasuk salah satu rentang allow.
+func egressAllowed(addr netip.Addr, allow []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, p := range allow {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	for _, p := range blockedNetworks {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// guardedDialer membuat net.Dialer yang menolak koneksi ke alamat internal (lihat egressAllowed).
+// Pemeriksaan dilakukan di Control, yaitu setelah resolusi DNS dan tepat sebelum connect, sehingga
+// hostname yang me-resolve ke alamat internal atau berganti alamat (DNS rebinding) ikut tertolak.
+func guardedDialer(timeout time.Duration, allow []netip.Prefix) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("egress to %s refused: %w", address, err)
+			}
+			if !egressAllowed(ap.Addr(), allow) {
+				return fmt.Errorf("egress to %s refused: address is not public", ap.Addr())
+			}
+			return nil
+		},
+	}
+}
+
+// guardedClient membuat http.Client dengan guardedDialer. Proxy dari environment tidak dipakai,
+// supaya yang diperiksa adalah alamat tujuan sebenarnya, bukan alamat proxy. Redirect ikut
+// melewati pemeriksaan karena setiap koneksi baru dibuka lewat dialer yang sama.
+func guardedClient(timeout time.Duration, allow []netip.Prefix) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = guardedDialer(timeout, allow).DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"sistem-manajemen-armada/internal/repository"
+)
+
+var (
+	ErrNotFound = repository.ErrNotFound
+	ErrConflict = repository.ErrConflict
+	// ErrReferenceNotFound: data yang dirujuk (mis. kendaraan) tidak ada.
+	ErrReferenceNotFound = repository.ErrReferenceNotFound
+
+	// ErrRejected / ErrQuarantined dikembalikan SaveLocation bila lokasi tidak disimpan ke riwayat.
+	ErrRejected    = errors.New("location rejected")
+	ErrQuarantined = errors.New("location quarantined")
+
+	// ErrTamperSuspected: tanda tangan payload device tidak ada atau tidak valid.
+	ErrTamperSuspected = errors.New("tamper suspected")
+
+	// ErrInvalidAPIKey: API key tidak dikenal, salah, dicabut, atau kedaluwarsa.
+	ErrInvalidAPIKey = errors.New("invalid api key")
+)
+
+// ValidationError menandakan input dari client tidak valid.
+type ValidationError struct {
+	Msg string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Msg
+}
+
+func invalidf(format string, args ...any) error {
+	return &ValidationError{Msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"sistem-manajemen-armada/internal/geofence"
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// maxGeofenceRadiusM membatasi radius geofence supaya pra-filter bounding box tetap efektif.
+const maxGeofenceRadiusM = 100000
+
+type GeofenceService struct {
+	repo repository.GeofenceRepository
+}
+
+func NewGeofenceService(repo repository.GeofenceRepository) *GeofenceService {
+	return &GeofenceService{repo: repo}
+}
+
+func (s *GeofenceService) Create(ctx context.Context, g models.Geofence) (*models.Geofence, error) {
+	if err := validateGeofence(&g); err != nil {
+		return nil, err
+	}
+	g.TenantID = ownerTenant(ctx)
+	return s.repo.Create(ctx, g)
+}
+
+// Get mengembalikan geofence, atau ErrNotFound bila tidak ada atau milik tenant lain.
+func (s *GeofenceService) Get(ctx context.Context, id int64) (*models.Geofence, error) {
+	g, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tenant := tenantOf(ctx); tenant != "" && g.TenantID != tenant {
+		return nil, ErrNotFound
+	}
+	return g, nil
+}
+
+func (s *GeofenceService) List(ctx context.Context) ([]models.Geofence, error) {
+	return s.repo.List(ctx, tenantOf(ctx))
+}
+
+func (s *GeofenceService) Update(ctx context.Context, g models.Geofence) (*models.Geofence, error) {
+	if err := validateGeofence(&g); err != nil {
+		return nil, err
+	}
+	if _, err := s.Get(ctx, g.ID); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, g)
+}
+
+func (s *GeofenceService) Delete(ctx context.Context, id int64) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// Containing mengembalikan geofence milik tenant yang memuat titik; bila lebih dari satu, yang
+// pusatnya paling dekat. nil bila titik tidak berada di geofence mana pun.
+func (s *GeofenceService) Containing(ctx context.Context, tenantID string, lat, lon float64) (*models.Geofence, error) {
+	candidates, err := s.repo.ListInBox(ctx, tenantID, models.BoundingBox{MinLat: lat, MaxLat: lat, MinLon: lon, MaxLon: lon})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		best     *models.Geofence
+		bestDist float64
+	)
+	for i, g := range candidates {
+		d := geofence.DistanceMeters(g.Latitude, g.Longitude, lat, lon)
+		if d > g.RadiusM {
+			continue
+		}
+		if best == nil || d < bestDist {
+			best, bestDist = &candidates[i], d
+		}
+	}
+	return best, nil
+}
+
+// ListContaining mengembalikan semua geofence milik tenant yang memuat titik.
+func (s *GeofenceService) ListContaining(ctx context.Context, tenantID string, lat, lon float64) ([]models.Geofence, error) {
+	candidates, err := s.repo.ListInBox(ctx, tenantID, models.BoundingBox{MinLat: lat, MaxLat: lat, MinLon: lon, MaxLon: lon})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []models.Geofence
+	for _, g := range candidates {
+		if geofence.DistanceMeters(g.Latitude, g.Longitude, lat, lon) <= g.RadiusM {
+			result = append(result, g)
+		}
+	}
+	return result, nil
+}
+
+// SpeedZone mengembalikan geofence berbatas kecepatan milik tenant yang memuat titik; bila lebih
+// dari satu, yang batasnya paling rendah. nil bila titik tidak berada di zona kecepatan mana pun.
+func (s *GeofenceService) SpeedZone(ctx context.Context, tenantID string, lat, lon float64) (*models.Geofence, error) {
+	candidates, err := s.repo.ListInBox(ctx, tenantID, models.BoundingBox{MinLat: lat, MaxLat: lat, MinLon: lon, MaxLon: lon})
+	if err != nil {
+		return nil, err
+	}
+
+	var zone *models.Geofence
+	for i, g := range candidates {
+		if g.SpeedLimitKmh == nil || geofence.DistanceMeters(g.Latitude, g.Longitude, lat, lon) > g.RadiusM {
+			continue
+		}
+		if zone == nil || *g.SpeedLimitKmh < *zone.SpeedLimitKmh {
+			zone = &candidates[i]
+		}
+	}
+	return zone, nil
+}
+
+func validateGeofence(g *models.Geofence) error {
+	g.Name = strings.TrimSpace(g.Name)
+
+	if g.Name == "" || len(g.Name) > 100 {
+		return invalidf("name is required (max 100 characters)")
+	}
+	if g.Latitude < -90 || g.Latitude > 90 || g.Longitude < -180 || g.Longitude > 180 {
+		return invalidf("latitude/longitude out of range")
+	}
+	if g.RadiusM <= 0 || g.RadiusM > maxGeofenceRadiusM {
+		return invalidf("radius_m must be between 0 and %d", maxGeofenceRadiusM)
+	}
+	if g.SpeedLimitKmh != nil && (*g.SpeedLimitKmh <= 0 || *g.SpeedLimitKmh > maxPlausibleSpeedKmh) {
+		return invalidf("speed_limit_kmh must be between 0 and %d", maxPlausibleSpeedKmh)
+	}
+	return nil
+}
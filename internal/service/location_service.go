@@ -2,51 +2,168 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"sistem-manajemen-armada/internal/auth"
 	"sistem-manajemen-armada/internal/geofence"
 	"sistem-manajemen-armada/internal/models"
 	"sistem-manajemen-armada/internal/rabbitmq"
 	"sistem-manajemen-armada/internal/repository"
 )
 
+// rollupResolutions adalah interval downsampling yang bisa dilayani dari tabel rollup.
+var rollupResolutions = map[int64]bool{60: true, 3600: true}
+
+// errStopStream dipakai untuk menghentikan iterasi lebih awal saat limit halaman tercapai.
+var errStopStream = errors.New("stop stream")
+
+// PageOptions mengatur pagination riwayat berbasis cursor.
+type PageOptions struct {
+	Limit int // <= 0 berarti tanpa batas
+	After *models.HistoryCursor
+}
+
+// LocationProcessor memproses titik yang baru disimpan, mis. segmentasi perjalanan.
+// prev adalah posisi terakhir kendaraan sebelum titik ini (nil bila belum ada).
+type LocationProcessor interface {
+	ProcessLocation(ctx context.Context, prev *models.VehicleLocation, loc models.VehicleLocation) error
+}
+
+// HistoryOptions mengatur downsampling riwayat lokasi.
+type HistoryOptions struct {
+	// Interval (detik): ambil satu titik (yang terakhir) per bucket. 0 = semua titik.
+	Interval int64
+	// Tolerance (meter): sederhanakan jalur dengan Douglas–Peucker. 0 = nonaktif.
+	Tolerance float64
+}
+
 type LocationService struct {
 	repo      repository.LocationRepository
+	rollups   repository.RollupRepository
+	archives  *ArchiveService
+	vehicles  *VehicleService
+	devices   *DeviceService
+	drivers   *DriverService
+	events    repository.GeofenceEventRepository
 	geofence  *geofence.Geofence
 	rabbitCli *rabbitmq.Client
+
+	processors []LocationProcessor
 }
 
-func NewLocationService(repo repository.LocationRepository, g *geofence.Geofence, r *rabbitmq.Client) *LocationService {
+func NewLocationService(repo repository.LocationRepository, rollups repository.RollupRepository, archives *ArchiveService, vehicles *VehicleService, devices *DeviceService, drivers *DriverService, events repository.GeofenceEventRepository, g *geofence.Geofence, r *rabbitmq.Client) *LocationService {
 	return &LocationService{
 		repo:      repo,
+		rollups:   rollups,
+		archives:  archives,
+		vehicles:  vehicles,
+		devices:   devices,
+		drivers:   drivers,
+		events:    events,
 		geofence:  g,
 		rabbitCli: r,
 	}
 }
 
+// AddProcessor mendaftarkan processor yang dijalankan setelah setiap titik baru disimpan.
+func (s *LocationService) AddProcessor(p LocationProcessor) {
+	s.processors = append(s.processors, p)
+}
+
 func (s *LocationService) SaveLocation(ctx context.Context, loc models.VehicleLocation) error {
-	if loc.VehicleID == "" {
-		return errors.New("vehicle_id is required")
+	if loc.VehicleID == "" && loc.DeviceID == "" {
+		return errors.New("vehicle_id or device_id is required")
 	}
 	if loc.Timestamp == 0 {
 		return errors.New("timestamp is required")
 	}
+	if loc.TenantID == "" {
+		loc.TenantID = auth.DefaultTenant
+	}
+	if !tenantIDPattern.MatchString(loc.TenantID) {
+		return errors.New("invalid tenant_id")
+	}
+
+	// ID dari tracker dipetakan ke kendaraan yang dipasangi pada saat titik direkam
+	if s.devices != nil {
+		if err := s.devices.Resolve(ctx, &loc); err != nil {
+			return err
+		}
+	}
+	if loc.VehicleID == "" {
+		return errors.New("vehicle_id is required")
+	}
+
+	// Lokasi dari kendaraan yang tidak terdaftar ditolak / dikarantina sesuai kebijakan
+	if s.vehicles != nil {
+		if err := s.vehicles.CheckIngest(ctx, loc); err != nil {
+			return err
+		}
+	}
+
+	// posisi sebelumnya dibaca sebelum insert menimpa vehicle_latest
+	var prev *models.VehicleLocation
+	if len(s.processors) > 0 || s.rabbitCli != nil {
+		latest, err := s.repo.GetLatest(ctx, loc.TenantID, loc.VehicleID)
+		switch {
+		case errors.Is(err, ErrNotFound):
+		case err != nil:
+			return err
+		default:
+			prev = latest
+		}
+	}
 
 	if err := s.repo.Insert(ctx, loc); err != nil {
 		return err
 	}
 
+	// titik yang datang terlambat (lebih lama dari posisi terakhir) tidak diproses ulang
+	// dan tidak di-stream supaya posisi live tidak mundur
+	if prev == nil || loc.Timestamp > prev.Timestamp {
+		for _, p := range s.processors {
+			if err := p.ProcessLocation(ctx, prev, loc); err != nil {
+				log.Printf("failed to process location for %s: %v", loc.VehicleID, err)
+			}
+		}
+		if s.rabbitCli != nil {
+			if err := s.rabbitCli.PublishLocation(ctx, loc); err != nil {
+				log.Printf("failed to publish location for %s: %v", loc.VehicleID, err)
+			}
+		}
+	}
+
 	// Cek geofence
 	if s.geofence != nil && s.geofence.IsInside(loc.Latitude, loc.Longitude) {
 		event := models.GeofenceEvent{
+			TenantID:  loc.TenantID,
 			VehicleID: loc.VehicleID,
 			Event:     "geofence_entry",
 			Timestamp: loc.Timestamp,
 		}
+		if s.drivers != nil {
+			driverID, err := s.drivers.DriverAt(ctx, loc.TenantID, loc.VehicleID, loc.Timestamp)
+			if err != nil {
+				log.Printf("failed to look up driver for %s: %v", loc.VehicleID, err)
+			}
+			event.DriverID = driverID
+		}
 		event.Location.Latitude = loc.Latitude
 		event.Location.Longitude = loc.Longitude
 
+		if s.events != nil {
+			if err := s.events.Insert(ctx, event); err != nil {
+				log.Printf("failed to record geofence event for %s: %v", loc.VehicleID, err)
+			}
+		}
 		if err := s.rabbitCli.PublishGeofenceEvent(ctx, event); err != nil {
 			log.Printf("failed to publish geofence event: %v", err)
 		} else {
@@ -57,10 +174,385 @@ func (s *LocationService) SaveLocation(ctx context.Context, loc models.VehicleLo
 	return nil
 }
 
+// SaveSignedLocation memverifikasi tanda tangan payload tracker (lihat DeviceService.VerifySignature)
+// sebelum SaveLocation. payload adalah byte yang ditandatangani, nil bila pesan tidak bertanda
+// tangan. Payload dari device berkunci yang tidak bertanda tangan atau tanda tangannya tidak valid
+// selalu dikarantina, apa pun UNREGISTERED_VEHICLE_POLICY, dan event tamper_suspected dikirim.
+func (s *LocationService) SaveSignedLocation(ctx context.Context, loc models.VehicleLocation, payload []byte, signature string) error {
+	if s.devices != nil {
+		if loc.TenantID == "" {
+			loc.TenantID = auth.DefaultTenant
+		}
+		trackerID := loc.DeviceID
+		if trackerID == "" {
+			trackerID = loc.VehicleID
+		}
+		if err := s.devices.VerifySignature(ctx, loc.TenantID, trackerID, payload, signature); err != nil {
+			if !errors.Is(err, ErrTamperSuspected) {
+				return err
+			}
+			return s.tamperSuspected(ctx, trackerID, loc, err)
+		}
+	}
+	return s.SaveLocation(ctx, loc)
+}
+
+// tamperSuspected mengarantina titik dari device yang tanda tangannya gagal diverifikasi dan
+// mengirim event tamper_suspected.
+func (s *LocationService) tamperSuspected(ctx context.Context, trackerID string, loc models.VehicleLocation, cause error) error {
+	if loc.TenantID == "" {
+		loc.TenantID = auth.DefaultTenant
+	}
+	// titik dicatat untuk kendaraan yang dipasangi device, bukan vehicle_id dari payload
+	loc.DeviceID = trackerID
+	loc.VehicleID = trackerID
+	vehicleID, err := s.devices.vehicleAt(ctx, loc.TenantID, trackerID, loc.Timestamp)
+	switch {
+	case err == nil:
+		loc.VehicleID = vehicleID
+	case !errors.Is(err, ErrNotFound):
+		log.Printf("failed to resolve vehicle for device %s: %v", trackerID, err)
+	}
+
+	log.Printf("tamper suspected for device %s (vehicle %s): %v", trackerID, loc.VehicleID, cause)
+	if s.rabbitCli != nil {
+		event := models.TamperEvent{
+			TenantID:  loc.TenantID,
+			VehicleID: loc.VehicleID,
+			DeviceID:  trackerID,
+			Event:     "tamper_suspected",
+			Reason:    cause.Error(),
+			Location:  models.Coordinate{Latitude: loc.Latitude, Longitude: loc.Longitude},
+			Timestamp: loc.Timestamp,
+		}
+		if err := s.rabbitCli.Publish(ctx, loc.TenantID, "tamper.suspected", event); err != nil {
+			log.Printf("failed to publish tamper_suspected for %s: %v", trackerID, err)
+		}
+	}
+
+	if s.vehicles == nil {
+		return cause
+	}
+	return s.vehicles.Quarantine(ctx, loc, cause.Error())
+}
+
+// StreamGeofenceEvents memanggil fn untuk setiap event geofence dalam [start, end], urut waktu.
+// vehicleID kosong = seluruh armada.
+func (s *LocationService) StreamGeofenceEvents(ctx context.Context, vehicleID string, start, end int64, fn func(models.GeofenceEvent) error) error {
+	if end < start {
+		return invalidf("end must not be before start")
+	}
+	if vehicleID != "" {
+		if err := s.CheckAccess(ctx, vehicleID); err != nil {
+			return err
+		}
+		return s.events.Stream(ctx, tenantOf(ctx), vehicleID, start, end, fn)
+	}
+
+	visible, err := s.vehicles.Visible(ctx)
+	if err != nil {
+		return err
+	}
+	return s.events.Stream(ctx, tenantOf(ctx), "", start, end, func(e models.GeofenceEvent) error {
+		if !visible(e.VehicleID) {
+			return nil
+		}
+		return fn(e)
+	})
+}
+
+// CheckAccess mengembalikan ErrNotFound bila kendaraan milik tenant lain atau di luar grup pemanggil.
+// Handler yang men-stream respons memanggilnya sebelum status dikirim.
+func (s *LocationService) CheckAccess(ctx context.Context, vehicleID string) error {
+	return s.vehicles.CheckAccess(ctx, vehicleID)
+}
+
 func (s *LocationService) GetLatest(ctx context.Context, vehicleID string) (*models.VehicleLocation, error) {
-	return s.repo.GetLatest(ctx, vehicleID)
+	if err := s.CheckAccess(ctx, vehicleID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetLatest(ctx, tenantOf(ctx), vehicleID)
+}
+
+// ListLatest mengembalikan posisi terakhir seluruh armada. Posisi yang lebih tua dari
+// staleAfter ditandai stale.
+func (s *LocationService) ListLatest(ctx context.Context, filter models.LatestFilter, staleAfter time.Duration) ([]models.LatestLocation, error) {
+	filter.TenantID = tenantOf(ctx)
+	filter.Groups = allowedGroups(ctx)
+	locations, err := s.repo.ListLatest(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	staleBefore := time.Now().Add(-staleAfter).Unix()
+	result := make([]models.LatestLocation, 0, len(locations))
+	for _, loc := range locations {
+		result = append(result, models.LatestLocation{
+			VehicleLocation: loc,
+			Stale:           loc.Timestamp < staleBefore,
+		})
+	}
+	return result, nil
 }
 
-func (s *LocationService) GetHistory(ctx context.Context, vehicleID string, start, end int64) ([]models.VehicleLocation, error) {
-	return s.repo.GetHistory(ctx, vehicleID, start, end)
+// Nearby mengembalikan kendaraan dalam radius (meter) dari titik, urut dari yang terdekat.
+func (s *LocationService) Nearby(ctx context.Context, lat, lon, radiusM float64, limit int) ([]models.VehicleDistance, error) {
+	bbox := geofence.BoundingBoxAround(lat, lon, radiusM)
+	locations, err := s.repo.ListLatest(ctx, models.LatestFilter{BBox: &bbox, TenantID: tenantOf(ctx), Groups: allowedGroups(ctx)})
+	if err != nil {
+		return nil, err
+	}
+
+	result := byDistance(locations, lat, lon, radiusM)
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// Within mengembalikan kendaraan di dalam bounding box, urut dari yang terdekat ke titik tengahnya.
+func (s *LocationService) Within(ctx context.Context, bbox models.BoundingBox) ([]models.VehicleDistance, error) {
+	locations, err := s.repo.ListLatest(ctx, models.LatestFilter{BBox: &bbox, TenantID: tenantOf(ctx), Groups: allowedGroups(ctx)})
+	if err != nil {
+		return nil, err
+	}
+
+	centerLat := (bbox.MinLat + bbox.MaxLat) / 2
+	centerLon := (bbox.MinLon + bbox.MaxLon) / 2
+	return byDistance(locations, centerLat, centerLon, math.Inf(1)), nil
+}
+
+func (s *LocationService) GetHistory(ctx context.Context, vehicleID string, start, end int64, opts HistoryOptions) ([]models.VehicleLocation, error) {
+	if err := s.CheckAccess(ctx, vehicleID); err != nil {
+		return nil, err
+	}
+
+	var (
+		locations []models.VehicleLocation
+		err       error
+	)
+
+	if opts.Interval <= 0 {
+		// riwayat mentah: arsip + database, sudah urut
+		_, err = s.streamHistory(ctx, vehicleID, start, end, PageOptions{}, func(loc models.VehicleLocation) error {
+			locations = append(locations, loc)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if opts.Tolerance > 0 {
+			locations = geofence.Simplify(locations, opts.Tolerance)
+		}
+		return locations, nil
+	}
+
+	fromRollups := rollupResolutions[opts.Interval] && s.rollups != nil
+	switch {
+	case fromRollups:
+		// rollup tetap tersedia untuk rentang yang sudah diarsipkan
+		locations, err = s.historyFromRollups(ctx, vehicleID, start, end, opts.Interval)
+	default:
+		locations, err = s.repo.GetHistoryDownsampled(ctx, tenantOf(ctx), vehicleID, start, end, opts.Interval)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.archives != nil && !fromRollups {
+		var archived []models.VehicleLocation
+		if err := s.archives.ForEach(ctx, tenantOf(ctx), vehicleID, start, end, nil, func(loc models.VehicleLocation) error {
+			archived = append(archived, loc)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+
+		if len(archived) > 0 {
+			locations = append(downsample(archived, opts.Interval), locations...)
+			sortLocations(locations)
+			locations = downsample(locations, opts.Interval)
+		}
+	}
+
+	if opts.Tolerance > 0 {
+		locations = geofence.Simplify(locations, opts.Tolerance)
+	}
+	if s.drivers != nil {
+		timeline, err := s.drivers.Timeline(ctx, tenantOf(ctx), vehicleID, start, end)
+		if err != nil {
+			return nil, err
+		}
+		timeline.Annotate(locations)
+	}
+	return locations, nil
+}
+
+// StreamHistory memanggil fn untuk setiap titik riwayat (arsip lalu database) urut (timestamp, id)
+// tanpa menampung semuanya di memori. Bila page.Limit tercapai dan masih ada titik berikutnya,
+// cursor untuk halaman selanjutnya dikembalikan.
+func (s *LocationService) StreamHistory(ctx context.Context, vehicleID string, start, end int64, page PageOptions, fn func(loc models.VehicleLocation) error) (*models.HistoryCursor, error) {
+	if err := s.CheckAccess(ctx, vehicleID); err != nil {
+		return nil, err
+	}
+	return s.streamHistory(ctx, vehicleID, start, end, page, fn)
+}
+
+func (s *LocationService) streamHistory(ctx context.Context, vehicleID string, start, end int64, page PageOptions, fn func(loc models.VehicleLocation) error) (*models.HistoryCursor, error) {
+	var (
+		emitted  int
+		last     models.HistoryCursor
+		hasMore  bool
+		timeline DriverTimeline
+	)
+
+	if s.drivers != nil {
+		var err error
+		if timeline, err = s.drivers.Timeline(ctx, tenantOf(ctx), vehicleID, start, end); err != nil {
+			return nil, err
+		}
+	}
+
+	emit := func(loc models.VehicleLocation) error {
+		if page.Limit > 0 && emitted == page.Limit {
+			hasMore = true
+			return errStopStream
+		}
+		loc.DriverID = timeline.At(loc.Timestamp)
+		if err := fn(loc); err != nil {
+			return err
+		}
+		emitted++
+		last = models.HistoryCursor{Timestamp: loc.Timestamp, ID: loc.ID}
+		return nil
+	}
+
+	after := page.After
+	if s.archives != nil {
+		if err := s.archives.ForEach(ctx, tenantOf(ctx), vehicleID, start, end, after, emit); err != nil {
+			if errors.Is(err, errStopStream) {
+				return &last, nil
+			}
+			return nil, err
+		}
+		if emitted > 0 {
+			after = &last
+		}
+	}
+
+	// minta satu baris lebih untuk mengetahui apakah masih ada halaman berikutnya
+	limit := 0
+	if page.Limit > 0 {
+		limit = page.Limit - emitted + 1
+	}
+	if err := s.repo.StreamHistory(ctx, tenantOf(ctx), vehicleID, start, end, after, limit, emit); err != nil && !errors.Is(err, errStopStream) {
+		return nil, err
+	}
+
+	if hasMore {
+		return &last, nil
+	}
+	return nil, nil
+}
+
+// historyFromRollups membaca bucket yang sudah di-rollup, lalu melengkapi sisa rentang
+// yang belum di-rollup (biasanya beberapa menit terakhir) langsung dari vehicle_locations.
+func (s *LocationService) historyFromRollups(ctx context.Context, vehicleID string, start, end, resolution int64) ([]models.VehicleLocation, error) {
+	watermark, err := s.rollups.Watermark(ctx, resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketStart := start - start%resolution
+	if watermark <= bucketStart {
+		return s.repo.GetHistoryDownsampled(ctx, tenantOf(ctx), vehicleID, start, end, resolution)
+	}
+
+	rolledUntil := min(watermark, end+1)
+	rolled, err := s.rollups.GetHistory(ctx, tenantOf(ctx), vehicleID, resolution, bucketStart, rolledUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	// bucket di tepi rentang bisa berisi titik terakhir di luar [start, end]
+	locations := rolled[:0]
+	for _, loc := range rolled {
+		if loc.Timestamp >= start && loc.Timestamp <= end {
+			locations = append(locations, loc)
+		}
+	}
+
+	if rolledUntil <= end {
+		rest, err := s.repo.GetHistoryDownsampled(ctx, tenantOf(ctx), vehicleID, rolledUntil, end, resolution)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, rest...)
+	}
+	return locations, nil
+}
+
+// sortLocations mengurutkan titik berdasarkan (timestamp, id).
+func sortLocations(locations []models.VehicleLocation) {
+	sort.SliceStable(locations, func(i, j int) bool {
+		if locations[i].Timestamp != locations[j].Timestamp {
+			return locations[i].Timestamp < locations[j].Timestamp
+		}
+		return locations[i].ID < locations[j].ID
+	})
+}
+
+// downsample mengambil titik terakhir di setiap bucket `step` detik dari titik yang sudah terurut,
+// sama dengan GetHistoryDownsampled di repository.
+func downsample(locations []models.VehicleLocation, step int64) []models.VehicleLocation {
+	result := locations[:0]
+	for i, loc := range locations {
+		if i+1 < len(locations) && locations[i+1].Timestamp/step == loc.Timestamp/step {
+			continue
+		}
+		result = append(result, loc)
+	}
+	return result
+}
+
+func afterCursor(loc models.VehicleLocation, c models.HistoryCursor) bool {
+	return loc.Timestamp > c.Timestamp || (loc.Timestamp == c.Timestamp && loc.ID > c.ID)
+}
+
+// EncodeCursor mengubah cursor menjadi string opaque untuk client.
+func EncodeCursor(c models.HistoryCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", c.Timestamp, c.ID)))
+}
+
+// DecodeCursor membaca cursor yang dihasilkan EncodeCursor.
+func DecodeCursor(s string) (*models.HistoryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	tsStr, idStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errors.New("invalid cursor")
+	}
+	ts, err1 := strconv.ParseInt(tsStr, 10, 64)
+	id, err2 := strconv.ParseInt(idStr, 10, 64)
+	if err1 != nil || err2 != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &models.HistoryCursor{Timestamp: ts, ID: id}, nil
+}
+
+// byDistance menghitung jarak Haversine setiap lokasi ke titik acuan, membuang yang lebih jauh
+// dari maxM, lalu mengurutkan dari yang terdekat.
+func byDistance(locations []models.VehicleLocation, lat, lon, maxM float64) []models.VehicleDistance {
+	result := make([]models.VehicleDistance, 0, len(locations))
+	for _, loc := range locations {
+		d := geofence.DistanceMeters(lat, lon, loc.Latitude, loc.Longitude)
+		if d > maxM {
+			continue
+		}
+		result = append(result, models.VehicleDistance{VehicleLocation: loc, DistanceM: d})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].DistanceM < result[j].DistanceM })
+	return result
 }
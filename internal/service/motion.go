@@ -0,0 +1,33 @@
+package service
+
+import (
+	"sistem-manajemen-armada/internal/geofence"
+	"sistem-manajemen-armada/internal/models"
+)
+
+// maxPlausibleSpeedKmh: segmen yang menyiratkan kecepatan di atas ini dianggap lompatan GPS.
+const maxPlausibleSpeedKmh = 250
+
+// speedKmh mengembalikan kecepatan di titik loc: nilai dari tracker bila ada, atau dihitung
+// dari jarak ke titik sebelumnya. 0 bila tidak bisa dihitung.
+func speedKmh(prev *models.VehicleLocation, loc models.VehicleLocation) float64 {
+	if loc.Speed != nil {
+		return *loc.Speed
+	}
+	if prev == nil || loc.Timestamp <= prev.Timestamp {
+		return 0
+	}
+	d := geofence.DistanceMeters(prev.Latitude, prev.Longitude, loc.Latitude, loc.Longitude)
+	return d / float64(loc.Timestamp-prev.Timestamp) * 3.6
+}
+
+// segmentMeters mengembalikan jarak dua titik berurutan dan apakah segmen tersebut masuk akal
+// (bukan lompatan GPS).
+func segmentMeters(prev, loc models.VehicleLocation) (float64, bool) {
+	d := geofence.DistanceMeters(prev.Latitude, prev.Longitude, loc.Latitude, loc.Longitude)
+	dt := loc.Timestamp - prev.Timestamp
+	if dt <= 0 {
+		return d, d == 0
+	}
+	return d, d/float64(dt)*3.6 <= maxPlausibleSpeedKmh
+}
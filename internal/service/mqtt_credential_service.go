@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"sistem-manajemen-armada/internal/auth"
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// Jenis akses yang dikirim plugin auth broker pada pemeriksaan ACL.
+const (
+	MQTTAccessRead      = 1
+	MQTTAccessWrite     = 2
+	MQTTAccessReadWrite = 3
+	MQTTAccessSubscribe = 4
+)
+
+// MQTTCredentialService menerbitkan kredensial broker untuk tracker dan menjawab pemeriksaan
+// autentikasi / ACL dari plugin auth Mosquitto. Tracker login sebagai "{tenant}/{username}" dan
+// hanya boleh memakai topik di bawah /fleet/{tenant}/vehicle/{username}/. Layanan backend
+// (mqtt-listener dll.) memakai satu akun layanan yang diperlakukan sebagai superuser.
+type MQTTCredentialService struct {
+	repo     repository.MQTTCredentialRepository
+	vehicles *VehicleService
+	devices  *DeviceService
+
+	serviceUser     string
+	servicePassword string
+}
+
+// NewMQTTCredentialService membuat service kredensial MQTT. serviceUser / servicePassword adalah
+// akun layanan backend; serviceUser kosong = tidak ada akun layanan.
+func NewMQTTCredentialService(repo repository.MQTTCredentialRepository, vehicles *VehicleService, devices *DeviceService, serviceUser, servicePassword string) *MQTTCredentialService {
+	return &MQTTCredentialService{
+		repo:            repo,
+		vehicles:        vehicles,
+		devices:         devices,
+		serviceUser:     serviceUser,
+		servicePassword: servicePassword,
+	}
+}
+
+// Create menerbitkan kredensial untuk kendaraan, atau untuk device yang sedang terpasang di
+// kendaraan, milik pemanggil. Password hanya dikembalikan di sini.
+func (s *MQTTCredentialService) Create(ctx context.Context, username string) (*models.CreatedMQTTCredential, error) {
+	username = strings.TrimSpace(username)
+	if !vehicleIDPattern.MatchString(username) {
+		return nil, invalidf("username must be 1-50 characters of letters, digits, '-' or '_'")
+	}
+	if username == s.serviceUser {
+		return nil, invalidf("username is reserved")
+	}
+	if err := s.checkOwner(ctx, username); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, invalidf("username must be a registered vehicle or a device assigned to one")
+		}
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	password := base64.RawURLEncoding.EncodeToString(secret)
+
+	c := models.MQTTCredential{
+		TenantID: ownerTenant(ctx),
+		Username: username,
+		Hash:     hashMQTTPassword(password),
+	}
+	if p := auth.FromContext(ctx); p != nil {
+		c.CreatedBy = p.Subject
+	}
+
+	created, err := s.repo.Create(ctx, c)
+	if err != nil {
+		if errors.Is(err, ErrConflict) {
+			return nil, fmt.Errorf("%w: username already has an active credential, revoke it first", ErrConflict)
+		}
+		return nil, err
+	}
+	return &models.CreatedMQTTCredential{
+		MQTTCredential: *created,
+		Password:       password,
+		Login:          MQTTLogin(created.TenantID, created.Username),
+		Topic:          "/fleet/" + created.TenantID + "/vehicle/" + created.Username + "/location",
+	}, nil
+}
+
+func (s *MQTTCredentialService) List(ctx context.Context) ([]models.MQTTCredential, error) {
+	return s.repo.List(ctx, tenantOf(ctx))
+}
+
+// Revoke mencabut kredensial milik tenant pemanggil. Koneksi yang sudah terbuka ditolak broker pada
+// pemeriksaan ACL berikutnya setelah cache plugin auth kedaluwarsa.
+func (s *MQTTCredentialService) Revoke(ctx context.Context, id int64) (*models.MQTTCredential, error) {
+	return s.repo.Revoke(ctx, tenantOf(ctx), id)
+}
+
+// Authenticate memeriksa username / password yang dikirim klien saat CONNECT.
+func (s *MQTTCredentialService) Authenticate(ctx context.Context, login, password string) (bool, error) {
+	if s.IsSuperuser(login) {
+		return subtle.ConstantTimeCompare([]byte(password), []byte(s.servicePassword)) == 1, nil
+	}
+	tenantID, username := parseMQTTLogin(login)
+	c, err := s.repo.GetActive(ctx, tenantID, username)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(c.Hash, hashMQTTPassword(password)) == 1, nil
+}
+
+// IsSuperuser melaporkan apakah username adalah akun layanan backend.
+func (s *MQTTCredentialService) IsSuperuser(username string) bool {
+	return s.serviceUser != "" && username == s.serviceUser
+}
+
+// CheckACL memeriksa apakah login boleh publish / subscribe ke topik. Tracker hanya boleh
+// memakai topik di bawah /fleet/{tenant}/vehicle/{username}/, atau /fleet/vehicle/{username}/
+// untuk tenant default, dengan tenant dari login.
+func (s *MQTTCredentialService) CheckACL(ctx context.Context, login, topic string, acc int) (bool, error) {
+	if s.IsSuperuser(login) {
+		return true, nil
+	}
+	switch acc {
+	case MQTTAccessRead, MQTTAccessWrite, MQTTAccessReadWrite, MQTTAccessSubscribe:
+	default:
+		return false, nil
+	}
+
+	tenantID, username := parseMQTTLogin(login)
+	c, err := s.repo.GetActive(ctx, tenantID, username)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	prefixes := [][]string{{"", "fleet", tenantID, "vehicle", c.Username}}
+	if tenantID == auth.DefaultTenant {
+		prefixes = append(prefixes, []string{"", "fleet", "vehicle", c.Username})
+	}
+	parts := strings.Split(topic, "/")
+	for _, prefix := range prefixes {
+		if hasTopicPrefix(parts, prefix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MQTTLogin mengembalikan username CONNECT tracker untuk kredensial username milik tenantID.
+func MQTTLogin(tenantID, username string) string {
+	return tenantID + "/" + username
+}
+
+// parseMQTTLogin memecah username CONNECT "{tenant}/{username}". Username tanpa tenant milik
+// tenant default, sama seperti topik lama tanpa segmen tenant.
+func parseMQTTLogin(login string) (tenantID, username string) {
+	if i := strings.LastIndex(login, "/"); i >= 0 {
+		return login[:i], login[i+1:]
+	}
+	return auth.DefaultTenant, login
+}
+
+// checkOwner memastikan username adalah kendaraan, atau device yang terpasang di kendaraan, yang
+// boleh dilihat pemanggil.
+func (s *MQTTCredentialService) checkOwner(ctx context.Context, username string) error {
+	_, err := s.vehicles.Get(ctx, username)
+	if !errors.Is(err, ErrNotFound) || s.devices == nil {
+		return err
+	}
+	vehicleID, err := s.devices.CurrentVehicle(ctx, username)
+	if err != nil {
+		return err
+	}
+	_, err = s.vehicles.Get(ctx, vehicleID)
+	return err
+}
+
+// hasTopicPrefix melaporkan apakah topik berada di bawah prefix (minimal satu segmen setelahnya).
+// Segmen prefix dibandingkan apa adanya, sehingga wildcard '+' / '#' di posisi prefix ditolak.
+func hasTopicPrefix(parts, prefix []string) bool {
+	if len(parts) <= len(prefix) {
+		return false
+	}
+	for i, p := range prefix {
+		if parts[i] != p {
+			return false
+		}
+	}
+	return true
+}
+
+func hashMQTTPassword(password string) []byte {
+	sum := sha256.Sum256([]byte(password))
+	return sum[:]
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// fakeMQTTCredentialRepository hanya mendukung GetActive, dengan kunci tenant/username.
+type fakeMQTTCredentialRepository struct {
+	repository.MQTTCredentialRepository
+	active map[string]models.MQTTCredential
+}
+
+func (r *fakeMQTTCredentialRepository) GetActive(_ context.Context, tenantID, username string) (*models.MQTTCredential, error) {
+	c, ok := r.active[groupKey(tenantID, username)]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &c, nil
+}
+
+func TestMQTTCredentialServiceTenantLogin(t *testing.T) {
+	credential := func(tenant, password string) models.MQTTCredential {
+		return models.MQTTCredential{TenantID: tenant, Username: "B1", Hash: hashMQTTPassword(password)}
+	}
+	svc := NewMQTTCredentialService(&fakeMQTTCredentialRepository{active: map[string]models.MQTTCredential{
+		"acme/B1":    credential("acme", "acme-secret"),
+		"beta/B1":    credential("beta", "beta-secret"),
+		"default/B1": credential("default", "default-secret"),
+	}}, nil, nil, "backend", "backend-secret")
+	ctx := context.Background()
+
+	auth := []struct {
+		login, password string
+		want            bool
+	}{
+		{"acme/B1", "acme-secret", true},
+		{"beta/B1", "beta-secret", true},
+		{"acme/B1", "beta-secret", false},
+		{"B1", "default-secret", true},
+		{"B1", "acme-secret", false},
+		{"gamma/B1", "acme-secret", false},
+		{"backend", "backend-secret", true},
+	}
+	for _, tt := range auth {
+		ok, err := svc.Authenticate(ctx, tt.login, tt.password)
+		if err != nil {
+			t.Fatalf("Authenticate(%q): %v", tt.login, err)
+		}
+		if ok != tt.want {
+			t.Errorf("Authenticate(%q, %q) = %v, want %v", tt.login, tt.password, ok, tt.want)
+		}
+	}
+
+	acl := []struct {
+		login, topic string
+		want         bool
+	}{
+		{"acme/B1", "/fleet/acme/vehicle/B1/location", true},
+		{"acme/B1", "/fleet/beta/vehicle/B1/location", false},
+		{"acme/B1", "/fleet/vehicle/B1/location", false},
+		{"beta/B1", "/fleet/beta/vehicle/B1/location", true},
+		{"B1", "/fleet/default/vehicle/B1/location", true},
+		{"B1", "/fleet/vehicle/B1/location", true},
+		{"B1", "/fleet/acme/vehicle/B1/location", false},
+		{"gamma/B1", "/fleet/gamma/vehicle/B1/location", false},
+	}
+	for _, tt := range acl {
+		ok, err := svc.CheckACL(ctx, tt.login, tt.topic, MQTTAccessWrite)
+		if err != nil {
+			t.Fatalf("CheckACL(%q, %q): %v", tt.login, tt.topic, err)
+		}
+		if ok != tt.want {
+			t.Errorf("CheckACL(%q, %q) = %v, want %v", tt.login, tt.topic, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,577 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+const (
+	// maxRuleActions membatasi jumlah aksi dan penerima per aturan.
+	maxRuleActions    = 10
+	maxRuleRecipients = 20
+	// notificationListLimit membatasi jumlah log notifikasi yang ditampilkan.
+	notificationListLimit = 100
+)
+
+var phoneNumberPattern = regexp.MustCompile(`^\+?[0-9]{6,15}$`)
+
+// ruleEvent adalah field event armada yang dipakai untuk mencocokkan aturan. Trip / Overspeed hanya
+// ada di event perjalanan / overspeed.
+type ruleEvent struct {
+	TenantID  string            `json:"tenant_id"`
+	VehicleID string            `json:"vehicle_id"`
+	Event     string            `json:"event"`
+	Location  models.Coordinate `json:"location"`
+	Timestamp int64             `json:"timestamp"`
+	Trip      *models.Trip      `json:"trip"`
+	Overspeed *models.Overspeed `json:"overspeed"`
+}
+
+// speed mengembalikan kecepatan event untuk kriteria min_speed_kmh: kecepatan puncak overspeed atau
+// kecepatan maksimum perjalanan.
+func (e ruleEvent) speed() (float64, bool) {
+	switch {
+	case e.Overspeed != nil:
+		return e.Overspeed.PeakSpeedKmh, true
+	case e.Trip != nil:
+		return e.Trip.MaxSpeedKmh, true
+	}
+	return 0, false
+}
+
+// duration mengembalikan durasi overspeed atau perjalanan (detik) untuk kriteria min_duration_s.
+func (e ruleEvent) duration() (int64, bool) {
+	switch {
+	case e.Overspeed != nil:
+		return e.Overspeed.DurationS, true
+	case e.Trip != nil:
+		return e.Trip.DurationS, true
+	}
+	return 0, false
+}
+
+// NotificationRuleService mengelola aturan notifikasi per tenant dan mencocokkan event armada
+// dengan aturan tersebut. Notifikasi yang cocok diantrekan untuk NotificationService.
+type NotificationRuleService struct {
+	repo          repository.NotificationRuleRepository
+	notifications repository.NotificationRepository
+	vehicles      *VehicleService
+	geofences     *GeofenceService
+	tz            *time.Location // zona waktu aturan yang tidak mengisi timezone
+}
+
+func NewNotificationRuleService(repo repository.NotificationRuleRepository, notifications repository.NotificationRepository,
+	vehicles *VehicleService, geofences *GeofenceService, tz *time.Location) *NotificationRuleService {
+	return &NotificationRuleService{repo: repo, notifications: notifications, vehicles: vehicles, geofences: geofences, tz: tz}
+}
+
+// Create menyimpan aturan untuk tenant pemanggil.
+func (s *NotificationRuleService) Create(ctx context.Context, rule models.NotificationRule) (*models.NotificationRule, error) {
+	if err := checkAllGroups(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validate(ctx, &rule); err != nil {
+		return nil, err
+	}
+	rule.TenantID = ownerTenant(ctx)
+	return s.repo.Create(ctx, rule)
+}
+
+func (s *NotificationRuleService) Get(ctx context.Context, id int64) (*models.NotificationRule, error) {
+	if err := checkAllGroups(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(ctx, tenantOf(ctx), id)
+}
+
+func (s *NotificationRuleService) List(ctx context.Context) ([]models.NotificationRule, error) {
+	if err := checkAllGroups(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, tenantOf(ctx))
+}
+
+// Update mengganti seluruh isi aturan.
+func (s *NotificationRuleService) Update(ctx context.Context, rule models.NotificationRule) (*models.NotificationRule, error) {
+	if err := checkAllGroups(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validate(ctx, &rule); err != nil {
+		return nil, err
+	}
+	rule.TenantID = tenantOf(ctx)
+	return s.repo.Update(ctx, rule)
+}
+
+// Delete menghapus aturan beserta log notifikasinya.
+func (s *NotificationRuleService) Delete(ctx context.Context, id int64) error {
+	if err := checkAllGroups(ctx); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, tenantOf(ctx), id)
+}
+
+// ListNotifications mengembalikan notifikasi terbaru yang dipicu sebuah aturan.
+func (s *NotificationRuleService) ListNotifications(ctx context.Context, ruleID int64) ([]models.Notification, error) {
+	if _, err := s.Get(ctx, ruleID); err != nil {
+		return nil, err
+	}
+	return s.notifications.List(ctx, tenantOf(ctx), ruleID, notificationListLimit)
+}
+
+// Evaluate adalah dry-run: mencocokkan event (payload event armada seperti yang dipublish ke
+// RabbitMQ) dengan aturan tanpa mengirim atau mencatat notifikasi. rule != nil mengevaluasi aturan
+// yang belum disimpan, ruleID > 0 satu aturan tersimpan, selain itu semua aturan tenant.
+func (s *NotificationRuleService) Evaluate(ctx context.Context, payload json.RawMessage, ruleID int64, rule *models.NotificationRule) ([]models.RuleEvaluation, error) {
+	if err := checkAllGroups(ctx); err != nil {
+		return nil, err
+	}
+	e, err := parseRuleEvent(payload)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(WebhookEventTypes, e.Event) {
+		return nil, invalidf("event must be one of %s", strings.Join(WebhookEventTypes, ", "))
+	}
+	// event uji selalu milik tenant pemanggil
+	e.TenantID = ownerTenant(ctx)
+
+	var rules []models.NotificationRule
+	switch {
+	case rule != nil:
+		if err := s.validate(ctx, rule); err != nil {
+			return nil, err
+		}
+		rule.TenantID = e.TenantID
+		rules = []models.NotificationRule{*rule}
+	case ruleID > 0:
+		saved, err := s.repo.Get(ctx, tenantOf(ctx), ruleID)
+		if err != nil {
+			return nil, err
+		}
+		rules = []models.NotificationRule{*saved}
+	default:
+		if rules, err = s.repo.List(ctx, tenantOf(ctx)); err != nil {
+			return nil, err
+		}
+	}
+
+	m := &ruleMatcher{svc: s, event: e}
+	result := make([]models.RuleEvaluation, 0, len(rules))
+	for _, r := range rules {
+		eval := models.RuleEvaluation{RuleID: r.ID, RuleName: r.Name}
+		reason, err := m.match(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		if reason == "" && r.ID > 0 {
+			reason, err = s.coolingDown(ctx, r, e)
+			if err != nil {
+				return nil, err
+			}
+		}
+		eval.Matched = reason == ""
+		eval.Reason = reason
+		if eval.Matched {
+			eval.Notifications = s.build(r, e, payload)
+		}
+		result = append(result, eval)
+	}
+	return result, nil
+}
+
+// Process mencocokkan event armada milik tenantID dengan aturan aktif tenant tersebut dan
+// mengantrekan notifikasinya. Notifikasi yang sudah diantrekan untuk eventKey yang sama tidak
+// diantrekan lagi. Mengembalikan jumlah notifikasi yang cocok. Dipakai geofence worker.
+func (s *NotificationRuleService) Process(ctx context.Context, tenantID, eventKey string, payload []byte) (int, error) {
+	e, err := parseRuleEvent(payload)
+	if err != nil {
+		return 0, err
+	}
+	e.TenantID = tenantID
+
+	rules, err := s.repo.ListActive(ctx, tenantID, e.Event)
+	if err != nil || len(rules) == 0 {
+		return 0, err
+	}
+
+	m := &ruleMatcher{svc: s, event: e}
+	var pending []models.Notification
+	for _, r := range rules {
+		reason, err := m.match(ctx, r)
+		if err != nil {
+			return 0, err
+		}
+		if reason == "" {
+			reason, err = s.coolingDown(ctx, r, e)
+			if err != nil {
+				return 0, err
+			}
+		}
+		if reason != "" {
+			continue
+		}
+		pending = append(pending, s.build(r, e, payload)...)
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	if err := s.notifications.CreateAll(ctx, eventKey, pending); err != nil {
+		return 0, err
+	}
+	return len(pending), nil
+}
+
+// coolingDown mengembalikan alasan bila aturan sudah memicu notifikasi untuk kendaraan yang sama
+// dalam cooldown_s terakhir.
+func (s *NotificationRuleService) coolingDown(ctx context.Context, r models.NotificationRule, e ruleEvent) (string, error) {
+	if r.CooldownS <= 0 {
+		return "", nil
+	}
+	last, err := s.notifications.LastCreatedAt(ctx, r.ID, e.VehicleID)
+	if err != nil || last == nil {
+		return "", err
+	}
+	if time.Since(*last) < time.Duration(r.CooldownS)*time.Second {
+		return fmt.Sprintf("cooldown: last notification for vehicle %s at %s", e.VehicleID, last.Format(time.RFC3339)), nil
+	}
+	return "", nil
+}
+
+// build membuat satu notifikasi per penerima untuk setiap aksi aturan.
+func (s *NotificationRuleService) build(r models.NotificationRule, e ruleEvent, payload []byte) []models.Notification {
+	subject, message := s.render(r, e)
+
+	var result []models.Notification
+	add := func(a models.NotificationAction) {
+		result = append(result, models.Notification{
+			RuleID:    r.ID,
+			TenantID:  e.TenantID,
+			VehicleID: e.VehicleID,
+			EventType: e.Event,
+			Action:    a,
+			Subject:   subject,
+			Message:   message,
+			Payload:   payload,
+			Status:    models.DeliveryPending,
+		})
+	}
+	for _, a := range r.Actions {
+		if a.Type != models.ActionEmail && a.Type != models.ActionSMS {
+			add(a)
+			continue
+		}
+		for _, to := range a.To {
+			single := a
+			single.To = []string{to}
+			add(single)
+		}
+	}
+	return result
+}
+
+// render membuat subjek dan isi pesan teks notifikasi.
+func (s *NotificationRuleService) render(r models.NotificationRule, e ruleEvent) (subject, message string) {
+	title := strings.ReplaceAll(e.Event, "_", " ")
+	if title != "" {
+		title = strings.ToUpper(title[:1]) + title[1:]
+	}
+	subject = fmt.Sprintf("[%s] %s: %s", r.Name, title, e.VehicleID)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s\n", r.Name, title)
+	fmt.Fprintf(&b, "Vehicle: %s\n", e.VehicleID)
+	fmt.Fprintf(&b, "Time: %s\n", time.Unix(e.Timestamp, 0).In(s.location(r)).Format("2006-01-02 15:04:05 MST"))
+	fmt.Fprintf(&b, "Location: %.6f, %.6f\n", e.Location.Latitude, e.Location.Longitude)
+	switch {
+	case e.Overspeed != nil:
+		fmt.Fprintf(&b, "Speed: %.0f km/h (limit %.0f km/h)\n", e.Overspeed.PeakSpeedKmh, e.Overspeed.LimitKmh)
+		if e.Overspeed.GeofenceName != "" {
+			fmt.Fprintf(&b, "Zone: %s\n", e.Overspeed.GeofenceName)
+		}
+		if e.Overspeed.End != nil {
+			fmt.Fprintf(&b, "Duration: %s\n", time.Duration(e.Overspeed.DurationS)*time.Second)
+		}
+	case e.Trip != nil && e.Trip.End != nil:
+		fmt.Fprintf(&b, "Distance: %.1f km\n", e.Trip.DistanceM/1000)
+		fmt.Fprintf(&b, "Duration: %s\n", time.Duration(e.Trip.DurationS)*time.Second)
+		fmt.Fprintf(&b, "Max speed: %.0f km/h\n", e.Trip.MaxSpeedKmh)
+	}
+	return subject, strings.TrimSuffix(b.String(), "\n")
+}
+
+// location mengembalikan zona waktu aturan; timezone sudah divalidasi saat aturan disimpan.
+func (s *NotificationRuleService) location(r models.NotificationRule) *time.Location {
+	if r.Timezone != "" {
+		if loc, err := time.LoadLocation(r.Timezone); err == nil {
+			return loc
+		}
+	}
+	if s.tz != nil {
+		return s.tz
+	}
+	return time.UTC
+}
+
+// ruleMatcher mencocokkan satu event dengan beberapa aturan. Grup kendaraan dan geofence yang
+// memuat lokasi event hanya dibaca sekali, saat pertama kali dibutuhkan.
+type ruleMatcher struct {
+	svc   *NotificationRuleService
+	event ruleEvent
+
+	group  *string
+	inside map[int64]bool
+}
+
+// match mengembalikan alasan aturan tidak cocok dengan event, atau "" bila cocok.
+func (m *ruleMatcher) match(ctx context.Context, r models.NotificationRule) (string, error) {
+	e := m.event
+	if !r.Active {
+		return "rule is inactive", nil
+	}
+	if len(r.EventTypes) > 0 && !slices.Contains(r.EventTypes, e.Event) {
+		return fmt.Sprintf("event %s is not in event_types", e.Event), nil
+	}
+
+	if r.TimeStart != "" {
+		start, _ := parseClock(r.TimeStart)
+		end, _ := parseClock(r.TimeEnd)
+		local := time.Unix(e.Timestamp, 0).In(m.svc.location(r))
+		now := local.Hour()*60 + local.Minute()
+		inside := start <= now && now < end
+		if start > end {
+			// rentang melewati tengah malam, mis. 22:00-05:00
+			inside = now >= start || now < end
+		}
+		if !inside {
+			return fmt.Sprintf("event time %s is outside %s-%s", local.Format("15:04"), r.TimeStart, r.TimeEnd), nil
+		}
+	}
+
+	if r.MinSpeedKmh != nil {
+		speed, ok := e.speed()
+		if !ok {
+			return "event has no speed for min_speed_kmh", nil
+		}
+		if speed < *r.MinSpeedKmh {
+			return fmt.Sprintf("speed %.0f km/h is below min_speed_kmh %.0f", speed, *r.MinSpeedKmh), nil
+		}
+	}
+	if r.MinDurationS != nil {
+		duration, ok := e.duration()
+		if !ok {
+			return "event has no duration for min_duration_s", nil
+		}
+		if duration < *r.MinDurationS {
+			return fmt.Sprintf("duration %ds is below min_duration_s %d", duration, *r.MinDurationS), nil
+		}
+	}
+
+	if len(r.VehicleGroups) > 0 {
+		group, err := m.vehicleGroup(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !slices.Contains(r.VehicleGroups, group) {
+			return fmt.Sprintf("vehicle group %q is not in vehicle_groups", group), nil
+		}
+	}
+
+	if len(r.GeofenceIDs) > 0 {
+		inside, err := m.geofences(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !slices.ContainsFunc(r.GeofenceIDs, func(id int64) bool { return inside[id] }) {
+			return "event location is not inside any of geofence_ids", nil
+		}
+	}
+	return "", nil
+}
+
+// vehicleGroup mengembalikan grup kendaraan event; kosong bila kendaraan belum terdaftar di tenant
+// event.
+func (m *ruleMatcher) vehicleGroup(ctx context.Context) (string, error) {
+	if m.group == nil {
+		var group string
+		v, err := m.svc.vehicles.getInTenant(ctx, m.event.TenantID, m.event.VehicleID)
+		switch {
+		case errors.Is(err, ErrNotFound):
+		case err != nil:
+			return "", err
+		default:
+			group = v.Group
+		}
+		m.group = &group
+	}
+	return *m.group, nil
+}
+
+func (m *ruleMatcher) geofences(ctx context.Context) (map[int64]bool, error) {
+	if m.inside == nil {
+		zones, err := m.svc.geofences.ListContaining(ctx, m.event.TenantID, m.event.Location.Latitude, m.event.Location.Longitude)
+		if err != nil {
+			return nil, err
+		}
+		m.inside = make(map[int64]bool, len(zones))
+		for _, z := range zones {
+			m.inside[z.ID] = true
+		}
+	}
+	return m.inside, nil
+}
+
+func parseRuleEvent(payload []byte) (ruleEvent, error) {
+	var e ruleEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return e, invalidf("invalid event: %v", err)
+	}
+	if e.Event == "" {
+		return e, invalidf("event is required")
+	}
+	return e, nil
+}
+
+// parseClock mengubah "HH:MM" menjadi menit sejak tengah malam.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (s *NotificationRuleService) validate(ctx context.Context, r *models.NotificationRule) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 100 {
+		return invalidf("name is required (max 100 characters)")
+	}
+
+	for _, t := range r.EventTypes {
+		if !slices.Contains(WebhookEventTypes, t) {
+			return invalidf("event type %q is not supported, must be one of %s", t, strings.Join(WebhookEventTypes, ", "))
+		}
+	}
+	r.EventTypes = compactSorted(r.EventTypes)
+
+	for _, id := range r.GeofenceIDs {
+		if _, err := s.geofences.Get(ctx, id); errors.Is(err, ErrNotFound) {
+			return invalidf("geofence %d not found", id)
+		} else if err != nil {
+			return err
+		}
+	}
+	r.GeofenceIDs = compactSorted(r.GeofenceIDs)
+
+	for i, g := range r.VehicleGroups {
+		if r.VehicleGroups[i] = strings.TrimSpace(g); r.VehicleGroups[i] == "" {
+			return invalidf("vehicle_groups must not contain empty names")
+		}
+	}
+	r.VehicleGroups = compactSorted(r.VehicleGroups)
+
+	if (r.TimeStart == "") != (r.TimeEnd == "") {
+		return invalidf("time_start and time_end must be set together")
+	}
+	if r.TimeStart != "" {
+		start, err := parseClock(r.TimeStart)
+		if err != nil {
+			return invalidf("time_start must be HH:MM")
+		}
+		end, err := parseClock(r.TimeEnd)
+		if err != nil {
+			return invalidf("time_end must be HH:MM")
+		}
+		if start == end {
+			return invalidf("time_start and time_end must differ")
+		}
+	}
+	if r.Timezone != "" {
+		if _, err := time.LoadLocation(r.Timezone); err != nil {
+			return invalidf("unknown timezone %q", r.Timezone)
+		}
+	}
+
+	if r.MinSpeedKmh != nil && (*r.MinSpeedKmh <= 0 || *r.MinSpeedKmh > maxPlausibleSpeedKmh) {
+		return invalidf("min_speed_kmh must be between 0 and %d", maxPlausibleSpeedKmh)
+	}
+	if r.MinDurationS != nil && *r.MinDurationS < 0 {
+		return invalidf("min_duration_s must not be negative")
+	}
+	if r.CooldownS < 0 {
+		return invalidf("cooldown_s must not be negative")
+	}
+
+	if len(r.Actions) == 0 || len(r.Actions) > maxRuleActions {
+		return invalidf("actions must contain 1 to %d actions", maxRuleActions)
+	}
+	for i := range r.Actions {
+		if err := validateAction(&r.Actions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateAction(a *models.NotificationAction) error {
+	switch a.Type {
+	case models.ActionWebhook, models.ActionChat:
+		a.URL = strings.TrimSpace(a.URL)
+		u, err := url.Parse(a.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return invalidf("%s action url must be an absolute http or https URL", a.Type)
+		}
+		if a.Type == models.ActionChat && a.Secret != "" {
+			return invalidf("secret is only supported for webhook actions")
+		}
+		if a.Type == models.ActionWebhook && a.ChatID != "" {
+			return invalidf("chat_id is only supported for chat actions")
+		}
+		a.To = nil
+
+	case models.ActionEmail, models.ActionSMS:
+		if len(a.To) == 0 || len(a.To) > maxRuleRecipients {
+			return invalidf("%s action must have 1 to %d recipients in to", a.Type, maxRuleRecipients)
+		}
+		for i, to := range a.To {
+			to = strings.TrimSpace(to)
+			if a.Type == models.ActionEmail {
+				addr, err := mail.ParseAddress(to)
+				if err != nil {
+					return invalidf("invalid email address %q", to)
+				}
+				to = addr.Address
+			} else if !phoneNumberPattern.MatchString(to) {
+				return invalidf("invalid phone number %q", to)
+			}
+			a.To[i] = to
+		}
+		a.URL, a.Secret, a.ChatID = "", "", ""
+
+	default:
+		return invalidf("action type must be one of %s, %s, %s, %s",
+			models.ActionWebhook, models.ActionEmail, models.ActionSMS, models.ActionChat)
+	}
+	return nil
+}
+
+// compactSorted mengurutkan dan menghapus duplikat; nil menjadi slice kosong supaya tersimpan
+// sebagai array kosong.
+func compactSorted[T int64 | string](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	slices.Sort(items)
+	return slices.Compact(items)
+}
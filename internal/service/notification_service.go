@@ -0,0 +1,281 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// Header tambahan request aksi webhook aturan notifikasi.
+const (
+	NotificationHeaderID   = "X-Fleet-Notification"
+	NotificationHeaderRule = "X-Fleet-Rule"
+)
+
+// errNotConfigured menandai aksi yang tidak bisa dikirim karena konfigurasi server belum diisi;
+// notifikasi langsung ditandai failed tanpa diulang.
+var errNotConfigured = errors.New("not configured")
+
+// NotificationConfig mengatur pengiriman notifikasi aturan.
+type NotificationConfig struct {
+	Timeout     time.Duration // batas waktu satu pengiriman
+	MaxAttempts int           // setelah percobaan ini gagal, notifikasi ditandai failed
+	RetryBase   time.Duration // jeda sebelum percobaan kedua; berlipat dua setiap kali gagal
+	RetryMax    time.Duration // batas atas jeda antar percobaan
+
+	// SMTP untuk aksi email. SMTPAddr kosong = aksi email tidak aktif.
+	SMTPAddr     string // host:port
+	SMTPUsername string // kosong = tanpa AUTH
+	SMTPPassword string
+	SMTPFrom     string
+
+	// Gateway SMS: menerima POST JSON {"to": "...", "message": "..."}. Kosong = aksi sms tidak aktif.
+	SMSGatewayURL   string
+	SMSGatewayToken string // dikirim sebagai bearer token bila diisi
+
+	// AllowedNetworks adalah rentang alamat internal yang tetap boleh dituju, baik URL aksi webhook /
+	// chat maupun SMTP dan gateway SMS. Alamat internal lain ditolak saat koneksi dibuka.
+	AllowedNetworks []netip.Prefix
+}
+
+// NotificationService mengirim notifikasi yang diantrekan NotificationRuleService ke webhook,
+// email, gateway SMS, atau webhook chat. Notifikasi yang gagal diulang dengan backoff eksponensial.
+type NotificationService struct {
+	repo   repository.NotificationRepository
+	client *http.Client
+	cfg    NotificationConfig
+}
+
+// NewNotificationService membuat service pengirim notifikasi. client nil = http.Client dengan
+// cfg.Timeout yang menolak alamat internal di luar cfg.AllowedNetworks.
+func NewNotificationService(repo repository.NotificationRepository, client *http.Client, cfg NotificationConfig) *NotificationService {
+	if client == nil {
+		client = guardedClient(cfg.Timeout, cfg.AllowedNetworks)
+	}
+	return &NotificationService{repo: repo, client: client, cfg: cfg}
+}
+
+// Run mengirim notifikasi yang jatuh tempo secara periodik sampai ctx dibatalkan.
+func (s *NotificationService) Run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		// batch penuh berarti masih ada antrean; lanjutkan tanpa menunggu tick berikutnya
+		for {
+			n, err := s.DeliverDue(ctx)
+			if err != nil {
+				log.Printf("notification delivery failed: %v", err)
+			}
+			if err != nil || n < webhookBatch || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue mengirim satu batch notifikasi pending yang sudah jatuh tempo dan mengembalikan
+// jumlahnya.
+func (s *NotificationService) DeliverDue(ctx context.Context) (int, error) {
+	due, err := s.repo.ClaimDue(ctx, webhookBatch, s.cfg.Timeout+webhookLeaseMargin)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, n := range due {
+		s.attempt(ctx, &n, time.Now())
+		if err := s.repo.RecordAttempt(ctx, n); err != nil {
+			log.Printf("failed to record notification %d: %v", n.ID, err)
+		}
+	}
+	return len(due), nil
+}
+
+// attempt mengirim n sekali lalu memperbarui status, jumlah percobaan dan jadwal berikutnya.
+func (s *NotificationService) attempt(ctx context.Context, n *models.Notification, now time.Time) {
+	n.Attempts++
+	err := s.send(ctx, *n, now)
+
+	switch {
+	case err == nil:
+		n.Status = models.DeliverySucceeded
+		n.LastError = ""
+		n.DeliveredAt = &now
+		log.Printf("Sent %s notification for rule %d (notification %d)", n.Action.Type, n.RuleID, n.ID)
+	case errors.Is(err, errNotConfigured) || n.Attempts >= s.cfg.MaxAttempts:
+		n.Status = models.DeliveryFailed
+		n.LastError = err.Error()
+		log.Printf("notification %d for rule %d failed after %d attempts: %v", n.ID, n.RuleID, n.Attempts, err)
+	default:
+		n.Status = models.DeliveryPending
+		n.LastError = err.Error()
+		n.NextAttemptAt = now.Add(retryBackoff(s.cfg.RetryBase, s.cfg.RetryMax, n.Attempts))
+	}
+}
+
+func (s *NotificationService) send(ctx context.Context, n models.Notification, now time.Time) error {
+	a := n.Action
+	switch a.Type {
+	case models.ActionWebhook:
+		ts := now.Unix()
+		header := http.Header{}
+		header.Set(WebhookHeaderEvent, n.EventType)
+		header.Set(NotificationHeaderID, strconv.FormatInt(n.ID, 10))
+		header.Set(NotificationHeaderRule, strconv.FormatInt(n.RuleID, 10))
+		header.Set(WebhookHeaderTimestamp, strconv.FormatInt(ts, 10))
+		if a.Secret != "" {
+			header.Set(WebhookHeaderSignature, SignWebhook([]byte(a.Secret), ts, n.Payload))
+		}
+		return s.post(ctx, a.URL, n.Payload, header)
+
+	case models.ActionChat:
+		// Slack (incoming webhook) dan Bot API Telegram (sendMessage) sama-sama membaca field "text"
+		body := map[string]string{"text": n.Message}
+		if a.ChatID != "" {
+			body["chat_id"] = a.ChatID
+		}
+		return s.postJSON(ctx, a.URL, body, nil)
+
+	case models.ActionSMS:
+		if s.cfg.SMSGatewayURL == "" {
+			return fmt.Errorf("SMS gateway is %w", errNotConfigured)
+		}
+		header := http.Header{}
+		if s.cfg.SMSGatewayToken != "" {
+			header.Set("Authorization", "Bearer "+s.cfg.SMSGatewayToken)
+		}
+		return s.postJSON(ctx, s.cfg.SMSGatewayURL, map[string]string{"to": recipient(a), "message": n.Message}, header)
+
+	case models.ActionEmail:
+		if s.cfg.SMTPAddr == "" {
+			return fmt.Errorf("SMTP is %w", errNotConfigured)
+		}
+		return s.sendEmail(ctx, recipient(a), n.Subject, n.Message, now)
+	}
+	return fmt.Errorf("unknown action type %q", a.Type)
+}
+
+func (s *NotificationService) postJSON(ctx context.Context, url string, v any, header http.Header) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.post(ctx, url, body, header)
+}
+
+// post mengirim body JSON ke url. Respons 2xx dianggap berhasil.
+func (s *NotificationService) post(ctx context.Context, url string, body []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "fleet-notifications/1")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendEmail mengirim email teks lewat SMTP. STARTTLS dipakai bila server mendukungnya; AUTH hanya
+// dikirim lewat koneksi terenkripsi (aturan smtp.PlainAuth).
+func (s *NotificationService) sendEmail(ctx context.Context, to, subject, body string, now time.Time) error {
+	host, _, err := net.SplitHostPort(s.cfg.SMTPAddr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address: %w", err)
+	}
+
+	conn, err := guardedDialer(s.cfg.Timeout, s.cfg.AllowedNetworks).DialContext(ctx, "tcp", s.cfg.SMTPAddr)
+	if err != nil {
+		return err
+	}
+	if s.cfg.Timeout > 0 {
+		_ = conn.SetDeadline(now.Add(s.cfg.Timeout))
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+	if s.cfg.SMTPUsername != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.SMTPUsername, s.cfg.SMTPPassword, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.cfg.SMTPFrom); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.cfg.SMTPFrom)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	if _, err := io.WriteString(w, msg.String()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// recipient mengembalikan penerima aksi email / sms; notifikasi selalu berisi satu penerima.
+func recipient(a models.NotificationAction) string {
+	if len(a.To) == 0 {
+		return ""
+	}
+	return a.To[0]
+}
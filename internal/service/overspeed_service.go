@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/rabbitmq"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// OverspeedConfig mengatur deteksi kendaraan melebihi batas kecepatan.
+type OverspeedConfig struct {
+	// LimitKmh: batas kecepatan global, 0 = hanya batas per jenis kendaraan / zona.
+	LimitKmh float64
+	// MaxGap: jeda data lebih lama dari ini menutup periode overspeed di titik terakhir sebelum jeda.
+	MaxGap time.Duration
+}
+
+// OverspeedService mendeteksi periode overspeed secara inkremental dari stream lokasi. Batas yang
+// berlaku di suatu titik adalah yang terendah dari batas global, batas jenis kendaraan, dan batas
+// zona (geofence) yang memuat titik tersebut.
+type OverspeedService struct {
+	repo      repository.OverspeedRepository
+	limits    repository.SpeedLimitRepository
+	vehicles  *VehicleService
+	geofences *GeofenceService
+	drivers   *DriverService
+	rabbitCli *rabbitmq.Client
+	cfg       OverspeedConfig
+}
+
+func NewOverspeedService(repo repository.OverspeedRepository, limits repository.SpeedLimitRepository, vehicles *VehicleService,
+	geofences *GeofenceService, drivers *DriverService, r *rabbitmq.Client, cfg OverspeedConfig) *OverspeedService {
+	return &OverspeedService{
+		repo:      repo,
+		limits:    limits,
+		vehicles:  vehicles,
+		geofences: geofences,
+		drivers:   drivers,
+		rabbitCli: r,
+		cfg:       cfg,
+	}
+}
+
+// List mengembalikan periode overspeed yang beririsan dengan [start, end] untuk kendaraan yang boleh
+// dilihat pemanggil.
+func (s *OverspeedService) List(ctx context.Context, start, end int64, filter models.OverspeedFilter) ([]models.Overspeed, error) {
+	if end < start {
+		return nil, invalidf("end must not be before start")
+	}
+	filter.TenantID = tenantOf(ctx)
+	if filter.VehicleID != "" {
+		if err := s.vehicles.CheckAccess(ctx, filter.VehicleID); err != nil {
+			return nil, err
+		}
+		return s.repo.List(ctx, start, end, filter)
+	}
+
+	visible, err := s.vehicles.Visible(ctx)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repo.List(ctx, start, end, filter)
+	if err != nil {
+		return nil, err
+	}
+	return filterVisible(events, visible, func(o models.Overspeed) string { return o.VehicleID }), nil
+}
+
+func (s *OverspeedService) ListLimits(ctx context.Context) ([]models.SpeedLimit, error) {
+	return s.limits.List(ctx, tenantOf(ctx))
+}
+
+// SetLimit membuat atau mengganti batas kecepatan untuk satu jenis kendaraan di tenant pemanggil.
+func (s *OverspeedService) SetLimit(ctx context.Context, l models.SpeedLimit) (*models.SpeedLimit, error) {
+	l.VehicleType = strings.TrimSpace(l.VehicleType)
+	if l.VehicleType == "" || len(l.VehicleType) > 30 {
+		return nil, invalidf("vehicle_type is required (max 30 characters)")
+	}
+	if l.LimitKmh <= 0 || l.LimitKmh > maxPlausibleSpeedKmh {
+		return nil, invalidf("limit_kmh must be between 0 and %d", maxPlausibleSpeedKmh)
+	}
+	l.TenantID = ownerTenant(ctx)
+	return s.limits.Upsert(ctx, l)
+}
+
+func (s *OverspeedService) DeleteLimit(ctx context.Context, vehicleType string) error {
+	return s.limits.Delete(ctx, ownerTenant(ctx), vehicleType)
+}
+
+// ProcessLocation memenuhi LocationProcessor.
+func (s *OverspeedService) ProcessLocation(ctx context.Context, prev *models.VehicleLocation, loc models.VehicleLocation) error {
+	open, err := s.repo.GetOpen(ctx, loc.TenantID, loc.VehicleID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		open = nil
+	case err != nil:
+		return err
+	}
+
+	maxGap := int64(s.cfg.MaxGap / time.Second)
+	if open != nil {
+		if loc.Timestamp <= open.Last {
+			return nil
+		}
+		if loc.Timestamp-open.Last > maxGap {
+			// data terputus: periode berakhir di titik terakhir yang diketahui
+			at := open.PeakLocation
+			if prev != nil {
+				at = models.Coordinate{Latitude: prev.Latitude, Longitude: prev.Longitude}
+			}
+			if err := s.close(ctx, loc.TenantID, open, open.Last, at); err != nil {
+				return err
+			}
+			open = nil
+		}
+	}
+	if prev != nil && loc.Timestamp-prev.Timestamp > maxGap {
+		prev = nil
+	}
+
+	speed := speedKmh(prev, loc)
+	if speed > maxPlausibleSpeedKmh {
+		// lompatan GPS, bukan kecepatan sebenarnya
+		return nil
+	}
+	limit, zone, err := s.limitAt(ctx, loc)
+	if err != nil {
+		return err
+	}
+	over := limit > 0 && speed > limit
+
+	if open != nil {
+		if over && open.LimitKmh == limit && sameGeofence(open.GeofenceID, zone) {
+			open.Last = loc.Timestamp
+			if speed > open.PeakSpeedKmh {
+				open.PeakSpeedKmh = speed
+				open.PeakAt = loc.Timestamp
+				open.PeakLocation = models.Coordinate{Latitude: loc.Latitude, Longitude: loc.Longitude}
+			}
+			return s.repo.Update(ctx, *open)
+		}
+		// kecepatan kembali di bawah batas, atau batas berubah karena masuk / keluar zona
+		if err := s.close(ctx, loc.TenantID, open, loc.Timestamp, models.Coordinate{Latitude: loc.Latitude, Longitude: loc.Longitude}); err != nil {
+			return err
+		}
+	}
+
+	if !over {
+		return nil
+	}
+	return s.start(ctx, loc, speed, limit, zone)
+}
+
+// limitAt mengembalikan batas kecepatan yang berlaku untuk titik loc (0 = tidak ada batas) beserta
+// zona yang menentukannya, bila batas zona yang paling rendah.
+func (s *OverspeedService) limitAt(ctx context.Context, loc models.VehicleLocation) (float64, *models.Geofence, error) {
+	limit := s.cfg.LimitKmh
+
+	if s.vehicles != nil {
+		v, err := s.vehicles.getInTenant(ctx, loc.TenantID, loc.VehicleID)
+		switch {
+		case errors.Is(err, ErrNotFound):
+		case err != nil:
+			return 0, nil, err
+		case v.Type != "":
+			l, err := s.limits.Get(ctx, loc.TenantID, v.Type)
+			switch {
+			case errors.Is(err, ErrNotFound):
+			case err != nil:
+				return 0, nil, err
+			case limit == 0 || l.LimitKmh < limit:
+				limit = l.LimitKmh
+			}
+		}
+	}
+
+	if s.geofences != nil {
+		zone, err := s.geofences.SpeedZone(ctx, loc.TenantID, loc.Latitude, loc.Longitude)
+		if err != nil {
+			return 0, nil, err
+		}
+		if zone != nil && (limit == 0 || *zone.SpeedLimitKmh <= limit) {
+			return *zone.SpeedLimitKmh, zone, nil
+		}
+	}
+	return limit, nil, nil
+}
+
+func (s *OverspeedService) start(ctx context.Context, loc models.VehicleLocation, speed, limit float64, zone *models.Geofence) error {
+	at := models.Coordinate{Latitude: loc.Latitude, Longitude: loc.Longitude}
+	o := models.Overspeed{
+		TenantID:      loc.TenantID,
+		VehicleID:     loc.VehicleID,
+		Start:         loc.Timestamp,
+		Last:          loc.Timestamp,
+		LimitKmh:      limit,
+		PeakSpeedKmh:  speed,
+		PeakAt:        loc.Timestamp,
+		StartLocation: at,
+		PeakLocation:  at,
+	}
+	if zone != nil {
+		o.GeofenceID = &zone.ID
+	}
+	if s.drivers != nil {
+		driverID, err := s.drivers.DriverAt(ctx, loc.TenantID, loc.VehicleID, loc.Timestamp)
+		if err != nil {
+			log.Printf("failed to look up driver for %s: %v", loc.VehicleID, err)
+		}
+		o.DriverID = driverID
+	}
+
+	created, err := s.repo.Create(ctx, o)
+	if errors.Is(err, ErrConflict) {
+		// periode sudah dibuka oleh pemrosesan titik lain yang bersamaan
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.publish(ctx, loc.TenantID, "overspeed_started", "overspeed.started", *created, at, created.Start)
+	return nil
+}
+
+func (s *OverspeedService) close(ctx context.Context, tenantID string, o *models.Overspeed, end int64, at models.Coordinate) error {
+	closed, err := s.repo.Close(ctx, o.ID, end)
+	if errors.Is(err, ErrNotFound) {
+		// sudah ditutup oleh pemrosesan titik lain yang bersamaan
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.publish(ctx, tenantID, "overspeed_ended", "overspeed.ended", *closed, at, end)
+	return nil
+}
+
+func (s *OverspeedService) publish(ctx context.Context, tenantID, name, routingKey string, o models.Overspeed, at models.Coordinate, ts int64) {
+	if s.rabbitCli == nil {
+		return
+	}
+	event := models.OverspeedEvent{
+		TenantID:  tenantID,
+		VehicleID: o.VehicleID,
+		DriverID:  o.DriverID,
+		Event:     name,
+		Location:  at,
+		Timestamp: ts,
+		Overspeed: o,
+	}
+	if err := s.rabbitCli.Publish(ctx, event.TenantID, routingKey, event); err != nil {
+		log.Printf("failed to publish %s for %s: %v", name, o.VehicleID, err)
+		return
+	}
+	log.Printf("Published %s for %s (%.0f km/h, limit %.0f km/h)", name, o.VehicleID, o.PeakSpeedKmh, o.LimitKmh)
+}
+
+func sameGeofence(id *int64, zone *models.Geofence) bool {
+	if id == nil || zone == nil {
+		return id == nil && zone == nil
+	}
+	return *id == zone.ID
+}
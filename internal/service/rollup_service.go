@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"sistem-manajemen-armada/internal/repository"
+)
+
+const (
+	// rollupGrace memberi waktu untuk data yang datang terlambat sebelum bucket dianggap selesai.
+	rollupGrace = 2 * time.Minute
+	// rollupMaxSpan membatasi rentang yang diproses per transaksi (penting saat backfill awal).
+	rollupMaxSpan = int64(24 * 60 * 60)
+)
+
+// RollupService menjaga tabel ringkasan per menit / per jam tetap up to date.
+type RollupService struct {
+	repo repository.RollupRepository
+}
+
+func NewRollupService(repo repository.RollupRepository) *RollupService {
+	return &RollupService{repo: repo}
+}
+
+// Run menjalankan rollup secara periodik sampai ctx dibatalkan.
+func (s *RollupService) Run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		s.RefreshAll(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshAll me-rollup semua resolusi sampai bucket terakhir yang sudah selesai.
+func (s *RollupService) RefreshAll(ctx context.Context, now time.Time) {
+	for resolution := range rollupResolutions {
+		upto := now.Add(-rollupGrace).Unix()
+		upto -= upto % resolution
+
+		for {
+			more, err := s.repo.Refresh(ctx, resolution, upto, rollupMaxSpan)
+			if err != nil {
+				log.Printf("rollup %ds failed: %v", resolution, err)
+				break
+			}
+			if !more || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"sistem-manajemen-armada/internal/geofence"
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// Jenis periode diam.
+const (
+	StopTypeStop = "stop" // kunci kontak off / tidak dilaporkan
+	StopTypeIdle = "idle" // kunci kontak on, kendaraan tidak bergerak
+)
+
+// StopConfig mengatur ambang deteksi berhenti dan idle.
+type StopConfig struct {
+	// MaxSpeedKmh: kecepatan di bawah ini dianggap diam.
+	MaxSpeedKmh float64
+	// RadiusM: titik dalam radius ini dari lokasi berhenti masih dianggap di tempat yang sama.
+	RadiusM float64
+	// MinStop / MinIdle: periode yang lebih singkat tidak dicatat.
+	MinStop time.Duration
+	MinIdle time.Duration
+}
+
+// StopService mendeteksi periode diam secara inkremental dari stream lokasi dan mengaitkannya
+// dengan geofence tempat kendaraan berhenti.
+type StopService struct {
+	repo      repository.StopRepository
+	vehicles  *VehicleService
+	geofences *GeofenceService
+	drivers   *DriverService
+	cfg       StopConfig
+}
+
+func NewStopService(repo repository.StopRepository, vehicles *VehicleService, geofences *GeofenceService, drivers *DriverService, cfg StopConfig) *StopService {
+	return &StopService{repo: repo, vehicles: vehicles, geofences: geofences, drivers: drivers, cfg: cfg}
+}
+
+// List mengembalikan periode diam kendaraan yang beririsan dengan [start, end]. Periode yang
+// masih berjalan hanya disertakan bila sudah melewati ambang durasinya.
+func (s *StopService) List(ctx context.Context, vehicleID string, start, end int64, filter models.StopFilter) ([]models.Stop, error) {
+	if end < start {
+		return nil, invalidf("end must not be before start")
+	}
+	switch filter.Type {
+	case "", StopTypeStop, StopTypeIdle:
+	default:
+		return nil, invalidf("type must be %q or %q", StopTypeStop, StopTypeIdle)
+	}
+	if err := s.vehicles.CheckAccess(ctx, vehicleID); err != nil {
+		return nil, err
+	}
+
+	stops, err := s.repo.List(ctx, tenantOf(ctx), vehicleID, start, end, filter)
+	if err != nil {
+		return nil, err
+	}
+	result := stops[:0]
+	for _, st := range stops {
+		if st.End == nil && st.DurationS < s.minDuration(st.Type) {
+			continue
+		}
+		result = append(result, st)
+	}
+	return result, nil
+}
+
+// ProcessLocation memenuhi LocationProcessor.
+func (s *StopService) ProcessLocation(ctx context.Context, prev *models.VehicleLocation, loc models.VehicleLocation) error {
+	open, err := s.repo.GetOpen(ctx, loc.TenantID, loc.VehicleID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return err
+	}
+
+	kind := StopTypeStop
+	if loc.Ignition != nil && *loc.Ignition {
+		kind = StopTypeIdle
+	}
+
+	if open == nil {
+		if speedKmh(prev, loc) >= s.cfg.MaxSpeedKmh {
+			return nil
+		}
+		return s.open(ctx, kind, loc)
+	}
+
+	if loc.Timestamp <= open.Last {
+		return nil
+	}
+	still := geofence.DistanceMeters(open.Location.Latitude, open.Location.Longitude, loc.Latitude, loc.Longitude) <= s.cfg.RadiusM &&
+		(loc.Speed == nil || *loc.Speed < s.cfg.MaxSpeedKmh)
+	// status kunci kontak yang tidak dilaporkan tidak mengubah jenis periode
+	sameKind := loc.Ignition == nil || kind == open.Type
+
+	switch {
+	case still && sameKind:
+		return s.repo.Touch(ctx, open.ID, loc.Timestamp)
+	case still:
+		// mesin dinyalakan / dimatikan di tempat: periode lama selesai, periode baru dimulai
+		if err := s.finish(ctx, open, loc.Timestamp); err != nil {
+			return err
+		}
+		return s.open(ctx, kind, loc)
+	default:
+		// kendaraan bergerak lagi; periode diam berakhir di titik diam terakhir
+		return s.finish(ctx, open, open.Last)
+	}
+}
+
+func (s *StopService) open(ctx context.Context, kind string, loc models.VehicleLocation) error {
+	st := models.Stop{
+		TenantID:  loc.TenantID,
+		VehicleID: loc.VehicleID,
+		Type:      kind,
+		Start:     loc.Timestamp,
+		Last:      loc.Timestamp,
+		Location:  models.Coordinate{Latitude: loc.Latitude, Longitude: loc.Longitude},
+	}
+
+	if s.geofences != nil {
+		g, err := s.geofences.Containing(ctx, loc.TenantID, loc.Latitude, loc.Longitude)
+		if err != nil {
+			log.Printf("failed to look up geofence for stop of %s: %v", loc.VehicleID, err)
+		} else if g != nil {
+			st.GeofenceID = &g.ID
+		}
+	}
+	if s.drivers != nil {
+		driverID, err := s.drivers.DriverAt(ctx, loc.TenantID, loc.VehicleID, loc.Timestamp)
+		if err != nil {
+			log.Printf("failed to look up driver for %s: %v", loc.VehicleID, err)
+		}
+		st.DriverID = driverID
+	}
+
+	_, err := s.repo.Create(ctx, st)
+	if errors.Is(err, ErrConflict) {
+		// periode sudah dibuka oleh pemrosesan titik lain yang bersamaan
+		return nil
+	}
+	return err
+}
+
+// finish menutup periode diam, atau membuangnya bila lebih singkat dari ambang jenisnya.
+func (s *StopService) finish(ctx context.Context, st *models.Stop, end int64) error {
+	if end-st.Start < s.minDuration(st.Type) {
+		return s.repo.Discard(ctx, st.ID)
+	}
+	return s.repo.Close(ctx, st.ID, end)
+}
+
+func (s *StopService) minDuration(kind string) int64 {
+	if kind == StopTypeIdle {
+		return int64(s.cfg.MinIdle / time.Second)
+	}
+	return int64(s.cfg.MinStop / time.Second)
+}
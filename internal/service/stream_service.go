@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sistem-manajemen-armada/internal/auth"
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/rabbitmq"
+)
+
+const (
+	// streamBuffer adalah jumlah pesan yang boleh antre per koneksi; pesan untuk koneksi yang
+	// lebih lambat dari ini dibuang supaya tidak menahan koneksi lain.
+	streamBuffer = 256
+	// streamGroupRefresh adalah interval penyegaran peta kendaraan -> grup untuk filter grup.
+	streamGroupRefresh = time.Minute
+	// streamRetry adalah jeda sebelum berlangganan ulang ke RabbitMQ setelah koneksi terputus.
+	streamRetry = 5 * time.Second
+)
+
+// Jenis pesan stream.
+const (
+	StreamTypeLocation = "location"
+	StreamTypeEvent    = "event"
+)
+
+// StreamFilter memilih pesan untuk satu koneksi stream. Nilai kosong berarti tidak difilter.
+type StreamFilter struct {
+	VehicleIDs []string
+	Groups     []string
+	BBox       *models.BoundingBox
+	// Types: StreamTypeLocation dan/atau StreamTypeEvent. Kosong = keduanya.
+	Types []string
+}
+
+// StreamMessage adalah satu titik lokasi atau event armada yang dikirim ke klien stream.
+// Data berisi payload asli (VehicleLocation, GeofenceEvent, TripEvent, ...).
+type StreamMessage struct {
+	Type  string          `json:"type"`
+	Event string          `json:"event"` // "location" atau nama event, mis. "trip_started"
+	Data  json.RawMessage `json:"data"`
+}
+
+// StreamSubscription adalah satu koneksi stream yang terdaftar di StreamService.
+type StreamSubscription struct {
+	ch      chan StreamMessage
+	dropped atomic.Int64
+
+	vehicles map[string]bool
+	groups   map[string]bool
+	bbox     *models.BoundingBox
+	// tenant membatasi pesan ke tenant pemanggil; kosong = semua tenant.
+	tenant string
+	// allowed adalah grup yang boleh dilihat pemanggil; nil = semua grup.
+	allowed   map[string]bool
+	locations bool
+	events    bool
+}
+
+// Messages mengembalikan channel pesan; ditutup saat Unsubscribe.
+func (sub *StreamSubscription) Messages() <-chan StreamMessage {
+	return sub.ch
+}
+
+// Dropped mengembalikan jumlah pesan yang dibuang karena klien terlalu lambat.
+func (sub *StreamSubscription) Dropped() int64 {
+	return sub.dropped.Load()
+}
+
+// streamEnvelope adalah field yang dibutuhkan untuk mencocokkan filter. Lokasi membawa koordinat
+// di level atas, event membawanya di field location.
+type streamEnvelope struct {
+	TenantID  string             `json:"tenant_id"`
+	VehicleID string             `json:"vehicle_id"`
+	Event     string             `json:"event"`
+	Latitude  float64            `json:"latitude"`
+	Longitude float64            `json:"longitude"`
+	Location  *models.Coordinate `json:"location"`
+}
+
+// StreamService meneruskan titik lokasi dan event armada dari RabbitMQ ke koneksi SSE / WebSocket.
+// Setiap replika API berlangganan dengan queue sementara sendiri, jadi semua replika menerima
+// semua pesan.
+type StreamService struct {
+	rabbitCli *rabbitmq.Client
+	vehicles  *VehicleService
+
+	mu   sync.RWMutex
+	subs map[*StreamSubscription]struct{}
+
+	groupsMu sync.RWMutex
+	groups   map[string]string // groupKey(tenant_id, vehicle_id) -> group
+}
+
+func NewStreamService(r *rabbitmq.Client, vehicles *VehicleService) *StreamService {
+	return &StreamService{
+		rabbitCli: r,
+		vehicles:  vehicles,
+		subs:      map[*StreamSubscription]struct{}{},
+		groups:    map[string]string{},
+	}
+}
+
+// Subscribe mendaftarkan koneksi stream baru. Pesan dibatasi ke tenant dan kendaraan yang boleh
+// dilihat principal di ctx. Panggil Unsubscribe saat koneksi ditutup.
+func (s *StreamService) Subscribe(ctx context.Context, filter StreamFilter) (*StreamSubscription, error) {
+	sub := &StreamSubscription{
+		ch:        make(chan StreamMessage, streamBuffer),
+		vehicles:  stringSet(filter.VehicleIDs),
+		groups:    stringSet(filter.Groups),
+		bbox:      filter.BBox,
+		tenant:    tenantOf(ctx),
+		locations: len(filter.Types) == 0,
+		events:    len(filter.Types) == 0,
+	}
+	if groups := allowedGroups(ctx); groups != nil {
+		sub.allowed = make(map[string]bool, len(groups))
+		for _, g := range groups {
+			sub.allowed[g] = true
+		}
+	}
+	for _, t := range filter.Types {
+		switch t {
+		case StreamTypeLocation:
+			sub.locations = true
+		case StreamTypeEvent:
+			sub.events = true
+		default:
+			return nil, invalidf("types must be %q and/or %q", StreamTypeLocation, StreamTypeEvent)
+		}
+	}
+
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	return sub, nil
+}
+
+func (s *StreamService) Unsubscribe(sub *StreamSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[sub]; ok {
+		delete(s.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Run berlangganan ke RabbitMQ dan membagikan pesan ke koneksi stream sampai ctx dibatalkan.
+// Bila koneksi RabbitMQ terputus, langganan diulang setelah streamRetry.
+func (s *StreamService) Run(ctx context.Context) {
+	go s.refreshGroupsLoop(ctx)
+
+	for {
+		msgs, err := s.rabbitCli.Subscribe(ctx)
+		if err != nil {
+			log.Printf("stream subscribe failed: %v", err)
+		} else {
+			log.Println("Stream subscribed to location and event exchanges")
+			for msg := range msgs {
+				s.dispatch(msg.Body)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(streamRetry):
+		}
+	}
+}
+
+func (s *StreamService) dispatch(body []byte) {
+	var env streamEnvelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		log.Printf("invalid stream message: %v", err)
+		return
+	}
+
+	// pesan dari publisher lama tanpa tenant_id milik tenant default
+	if env.TenantID == "" {
+		env.TenantID = auth.DefaultTenant
+	}
+
+	msg := StreamMessage{Type: StreamTypeLocation, Event: StreamTypeLocation, Data: body}
+	lat, lon := env.Latitude, env.Longitude
+	if env.Event != "" {
+		msg.Type, msg.Event = StreamTypeEvent, env.Event
+		if env.Location != nil {
+			lat, lon = env.Location.Latitude, env.Location.Longitude
+		}
+	}
+
+	s.groupsMu.RLock()
+	group := s.groups[groupKey(env.TenantID, env.VehicleID)]
+	s.groupsMu.RUnlock()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for sub := range s.subs {
+		if !sub.matches(msg.Type, env.TenantID, env.VehicleID, group, lat, lon) {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+func (sub *StreamSubscription) matches(kind, tenantID, vehicleID, group string, lat, lon float64) bool {
+	if kind == StreamTypeLocation && !sub.locations || kind == StreamTypeEvent && !sub.events {
+		return false
+	}
+	if sub.tenant != "" && sub.tenant != tenantID {
+		return false
+	}
+	if len(sub.vehicles) > 0 && !sub.vehicles[vehicleID] {
+		return false
+	}
+	if len(sub.groups) > 0 && !sub.groups[group] {
+		return false
+	}
+	// kendaraan yang belum ada di peta grup (grup "") hanya terlihat tanpa batasan grup
+	if sub.allowed != nil && !sub.allowed[group] {
+		return false
+	}
+	if b := sub.bbox; b != nil && (lat < b.MinLat || lat > b.MaxLat || lon < b.MinLon || lon > b.MaxLon) {
+		return false
+	}
+	return true
+}
+
+// refreshGroupsLoop menjaga peta kendaraan -> grup untuk filter grup tanpa query per pesan.
+func (s *StreamService) refreshGroupsLoop(ctx context.Context) {
+	ticker := time.NewTicker(streamGroupRefresh)
+	defer ticker.Stop()
+
+	for {
+		vehicles, err := s.vehicles.List(ctx, models.VehicleFilter{})
+		if err != nil {
+			log.Printf("failed to refresh stream vehicle groups: %v", err)
+		} else {
+			groups := make(map[string]string, len(vehicles))
+			for _, v := range vehicles {
+				groups[groupKey(v.TenantID, v.ID)] = v.Group
+			}
+			s.groupsMu.Lock()
+			s.groups = groups
+			s.groupsMu.Unlock()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// groupKey adalah key peta grup. ID kendaraan hanya unik per tenant, dan '/' tidak pernah muncul di
+// ID tenant maupun kendaraan.
+func groupKey(tenantID, vehicleID string) string {
+	return tenantID + "/" + vehicleID
+}
+
+func stringSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
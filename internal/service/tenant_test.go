@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"sistem-manajemen-armada/internal/auth"
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// fakeVehicleRepository menyimpan registry di memori dengan aturan tenant yang sama dengan Postgres:
+// tenantID kosong = semua tenant.
+type fakeVehicleRepository struct {
+	repository.VehicleRepository
+	vehicles []models.Vehicle
+	latest   []models.VehicleLocation
+}
+
+func (r *fakeVehicleRepository) Get(_ context.Context, tenantID, id string) (*models.Vehicle, error) {
+	for _, v := range r.vehicles {
+		if v.ID == id && (tenantID == "" || v.TenantID == tenantID) {
+			return &v, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *fakeVehicleRepository) List(_ context.Context, filter models.VehicleFilter) ([]models.Vehicle, error) {
+	var result []models.Vehicle
+	for _, v := range r.vehicles {
+		if filter.TenantID != "" && v.TenantID != filter.TenantID {
+			continue
+		}
+		if filter.Groups != nil && !slices.Contains(filter.Groups, v.Group) {
+			continue
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+func (r *fakeVehicleRepository) HasPosition(_ context.Context, tenantID, id string) (bool, error) {
+	for _, loc := range r.latest {
+		if loc.TenantID == tenantID && loc.VehicleID == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeVehicleRepository) VisibleIDs(_ context.Context, tenantID string, groups []string) ([]string, error) {
+	var ids []string
+	for _, v := range r.vehicles {
+		if (tenantID == "" || v.TenantID == tenantID) && (groups == nil || slices.Contains(groups, v.Group)) {
+			ids = append(ids, v.ID)
+		}
+	}
+	if groups == nil {
+		for _, loc := range r.latest {
+			if tenantID == "" || loc.TenantID == tenantID {
+				ids = append(ids, loc.VehicleID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// fakeLocationRepository menyimpan posisi terakhir dan riwayat di memori.
+type fakeLocationRepository struct {
+	repository.LocationRepository
+	latest  []models.VehicleLocation
+	history []models.VehicleLocation
+	groups  map[string]string // tenant/vehicle -> grup, untuk filter grup ListLatest
+}
+
+func (r *fakeLocationRepository) GetLatest(_ context.Context, tenantID, vehicleID string) (*models.VehicleLocation, error) {
+	for _, loc := range r.latest {
+		if loc.VehicleID == vehicleID && (tenantID == "" || loc.TenantID == tenantID) {
+			return &loc, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *fakeLocationRepository) ListLatest(_ context.Context, filter models.LatestFilter) ([]models.VehicleLocation, error) {
+	var result []models.VehicleLocation
+	for _, loc := range r.latest {
+		if filter.TenantID != "" && loc.TenantID != filter.TenantID {
+			continue
+		}
+		if filter.Groups != nil && !slices.Contains(filter.Groups, r.groups[groupKey(loc.TenantID, loc.VehicleID)]) {
+			continue
+		}
+		result = append(result, loc)
+	}
+	return result, nil
+}
+
+func (r *fakeLocationRepository) match(tenantID, vehicleID string, start, end int64) []models.VehicleLocation {
+	var result []models.VehicleLocation
+	for _, loc := range r.history {
+		if loc.VehicleID == vehicleID && (tenantID == "" || loc.TenantID == tenantID) &&
+			loc.Timestamp >= start && loc.Timestamp <= end {
+			result = append(result, loc)
+		}
+	}
+	return result
+}
+
+func (r *fakeLocationRepository) StreamHistory(_ context.Context, tenantID, vehicleID string, start, end int64, after *models.HistoryCursor, limit int, fn func(loc models.VehicleLocation) error) error {
+	emitted := 0
+	for _, loc := range r.match(tenantID, vehicleID, start, end) {
+		if after != nil && !afterCursor(loc, *after) {
+			continue
+		}
+		if limit > 0 && emitted == limit {
+			return nil
+		}
+		if err := fn(loc); err != nil {
+			return err
+		}
+		emitted++
+	}
+	return nil
+}
+
+func (r *fakeLocationRepository) GetHistoryDownsampled(_ context.Context, tenantID, vehicleID string, start, end, step int64) ([]models.VehicleLocation, error) {
+	return downsample(r.match(tenantID, vehicleID, start, end), step), nil
+}
+
+// Dua tenant memakai ID kendaraan "V1" yang sama; acme juga punya V2 (grup lain) dan kendaraan U9
+// yang belum terdaftar tetapi sudah mengirim posisi.
+func newTenantFixture() (*VehicleService, *LocationService) {
+	vehicles := []models.Vehicle{
+		{ID: "V1", TenantID: "acme", PlateNumber: "B1", Group: "north", Active: true},
+		{ID: "V2", TenantID: "acme", PlateNumber: "B2", Group: "south", Active: true},
+		{ID: "V1", TenantID: "beta", PlateNumber: "D1", Group: "north", Active: true},
+	}
+	latest := []models.VehicleLocation{
+		{ID: 3, TenantID: "acme", VehicleID: "V1", Latitude: -6.1, Longitude: 106.8, Timestamp: 1200},
+		{ID: 4, TenantID: "acme", VehicleID: "V2", Latitude: -6.2, Longitude: 106.9, Timestamp: 1200},
+		{ID: 5, TenantID: "acme", VehicleID: "U9", Latitude: -6.3, Longitude: 107.0, Timestamp: 1200},
+		{ID: 7, TenantID: "beta", VehicleID: "V1", Latitude: -7.2, Longitude: 112.7, Timestamp: 1300},
+	}
+	history := []models.VehicleLocation{
+		{ID: 1, TenantID: "acme", VehicleID: "V1", Latitude: -6.0, Longitude: 106.7, Timestamp: 1000},
+		{ID: 2, TenantID: "beta", VehicleID: "V1", Latitude: -7.1, Longitude: 112.6, Timestamp: 1100},
+		{ID: 3, TenantID: "acme", VehicleID: "V1", Latitude: -6.1, Longitude: 106.8, Timestamp: 1200},
+		{ID: 4, TenantID: "acme", VehicleID: "V2", Latitude: -6.2, Longitude: 106.9, Timestamp: 1200},
+		{ID: 7, TenantID: "beta", VehicleID: "V1", Latitude: -7.2, Longitude: 112.7, Timestamp: 1300},
+	}
+	groups := map[string]string{}
+	for _, v := range vehicles {
+		groups[groupKey(v.TenantID, v.ID)] = v.Group
+	}
+
+	vehicleSvc := NewVehicleService(&fakeVehicleRepository{vehicles: vehicles, latest: latest}, PolicyQuarantine)
+	locationRepo := &fakeLocationRepository{latest: latest, history: history, groups: groups}
+	return vehicleSvc, NewLocationService(locationRepo, nil, nil, vehicleSvc, nil, nil, nil, nil, nil)
+}
+
+func tenantCtx(tenant, role string, groups ...string) context.Context {
+	p := &auth.Principal{Subject: tenant + "-" + role, Role: role, Tenant: tenant}
+	if len(groups) > 0 {
+		p.Groups = groups
+	}
+	return auth.WithPrincipal(context.Background(), p)
+}
+
+func vehicleIDs[T any](items []T, key func(T) string) []string {
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, key(item))
+	}
+	return ids
+}
+
+func TestVehicleServiceTenantIsolation(t *testing.T) {
+	vehicles, _ := newTenantFixture()
+	acme := tenantCtx("acme", auth.RoleAdmin)
+	beta := tenantCtx("beta", auth.RoleAdmin)
+
+	v, err := vehicles.Get(acme, "V1")
+	if err != nil || v.TenantID != "acme" || v.PlateNumber != "B1" {
+		t.Fatalf("Get(acme, V1) = %+v, %v; want acme vehicle B1", v, err)
+	}
+	v, err = vehicles.Get(beta, "V1")
+	if err != nil || v.TenantID != "beta" || v.PlateNumber != "D1" {
+		t.Fatalf("Get(beta, V1) = %+v, %v; want beta vehicle D1", v, err)
+	}
+	if _, err := vehicles.Get(beta, "V2"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(beta, V2) error = %v, want ErrNotFound", err)
+	}
+
+	list, err := vehicles.List(acme, models.VehicleFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range list {
+		if v.TenantID != "acme" {
+			t.Fatalf("List(acme) returned vehicle of tenant %q", v.TenantID)
+		}
+	}
+	if got := vehicleIDs(list, func(v models.Vehicle) string { return v.ID }); !slices.Equal(got, []string{"V1", "V2"}) {
+		t.Fatalf("List(acme) = %v, want [V1 V2]", got)
+	}
+	list, err = vehicles.List(beta, models.VehicleFilter{TenantID: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].TenantID != "beta" {
+		t.Fatalf("List(beta) with acme filter = %+v, want only the beta vehicle", list)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		vehicle string
+		wantErr error
+	}{
+		{"own registered vehicle", acme, "V2", nil},
+		{"same ID in own tenant", beta, "V1", nil},
+		{"other tenant's vehicle", beta, "V2", ErrNotFound},
+		{"own unregistered vehicle with position", acme, "U9", nil},
+		{"other tenant's unregistered vehicle", beta, "U9", ErrNotFound},
+		{"unknown vehicle", acme, "X0", ErrNotFound},
+		{"vehicle outside caller's groups", tenantCtx("acme", auth.RoleDispatcher, "north"), "V2", ErrNotFound},
+		{"unregistered vehicle for group-restricted caller", tenantCtx("acme", auth.RoleDispatcher, "north"), "U9", ErrNotFound},
+		{"internal caller without principal", context.Background(), "V2", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := vehicles.CheckAccess(tt.ctx, tt.vehicle); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CheckAccess(%s) error = %v, want %v", tt.vehicle, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLocationServiceTenantIsolation(t *testing.T) {
+	_, locations := newTenantFixture()
+	acme := tenantCtx("acme", auth.RoleAdmin)
+	beta := tenantCtx("beta", auth.RoleAdmin)
+
+	loc, err := locations.GetLatest(acme, "V1")
+	if err != nil || loc.TenantID != "acme" || loc.ID != 3 {
+		t.Fatalf("GetLatest(acme, V1) = %+v, %v; want acme location 3", loc, err)
+	}
+	loc, err = locations.GetLatest(beta, "V1")
+	if err != nil || loc.TenantID != "beta" || loc.ID != 7 {
+		t.Fatalf("GetLatest(beta, V1) = %+v, %v; want beta location 7", loc, err)
+	}
+	for _, id := range []string{"V2", "U9"} {
+		if _, err := locations.GetLatest(beta, id); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetLatest(beta, %s) error = %v, want ErrNotFound", id, err)
+		}
+	}
+
+	latest, err := locations.ListLatest(beta, models.LatestFilter{TenantID: "acme"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(latest) != 1 || latest[0].TenantID != "beta" || latest[0].ID != 7 {
+		t.Fatalf("ListLatest(beta) = %+v, want only beta location 7", latest)
+	}
+	latest, err = locations.ListLatest(tenantCtx("acme", auth.RoleViewer, "north"), models.LatestFilter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vehicleIDs(latest, func(l models.LatestLocation) string { return l.TenantID + "/" + l.VehicleID }); !slices.Equal(got, []string{"acme/V1"}) {
+		t.Fatalf("ListLatest(acme north viewer) = %v, want [acme/V1]", got)
+	}
+
+	for _, opts := range []HistoryOptions{{}, {Interval: 60}} {
+		history, err := locations.GetHistory(beta, "V1", 0, 2000, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := vehicleIDs(history, func(l models.VehicleLocation) string { return l.TenantID }); !slices.Equal(got, []string{"beta", "beta"}) {
+			t.Fatalf("GetHistory(beta, V1, interval %d) tenants = %v, want two beta points", opts.Interval, got)
+		}
+
+		history, err = locations.GetHistory(acme, "V1", 0, 2000, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := vehicleIDs(history, func(l models.VehicleLocation) string { return l.TenantID }); !slices.Equal(got, []string{"acme", "acme"}) {
+			t.Fatalf("GetHistory(acme, V1, interval %d) tenants = %v, want two acme points", opts.Interval, got)
+		}
+
+		if _, err := locations.GetHistory(beta, "V2", 0, 2000, opts); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetHistory(beta, V2, interval %d) error = %v, want ErrNotFound", opts.Interval, err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/rabbitmq"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// TripConfig mengatur heuristik segmentasi perjalanan.
+type TripConfig struct {
+	// MinSpeedKmh: kecepatan minimum yang dianggap bergerak.
+	MinSpeedKmh float64
+	// StopAfter: kendaraan yang berhenti selama ini dianggap selesai perjalanan.
+	StopAfter time.Duration
+	// MaxGap: jeda data lebih lama dari ini menutup perjalanan di titik terakhir sebelum jeda.
+	MaxGap time.Duration
+}
+
+// TripService memotong stream lokasi menjadi perjalanan secara inkremental. Perjalanan dimulai
+// saat kendaraan bergerak (dan kunci kontak tidak off), lalu selesai saat kunci kontak off,
+// kendaraan berhenti selama StopAfter, atau data terputus lebih dari MaxGap.
+type TripService struct {
+	repo      repository.TripRepository
+	vehicles  *VehicleService
+	drivers   *DriverService
+	rabbitCli *rabbitmq.Client
+	cfg       TripConfig
+}
+
+func NewTripService(repo repository.TripRepository, vehicles *VehicleService, drivers *DriverService, r *rabbitmq.Client, cfg TripConfig) *TripService {
+	return &TripService{repo: repo, vehicles: vehicles, drivers: drivers, rabbitCli: r, cfg: cfg}
+}
+
+// List mengembalikan perjalanan kendaraan yang beririsan dengan [start, end]. vehicleID kosong =
+// seluruh armada (yang boleh dilihat pemanggil).
+func (s *TripService) List(ctx context.Context, vehicleID string, start, end int64) ([]models.Trip, error) {
+	if end < start {
+		return nil, invalidf("end must not be before start")
+	}
+	if vehicleID != "" {
+		if err := s.vehicles.CheckAccess(ctx, vehicleID); err != nil {
+			return nil, err
+		}
+		return s.repo.List(ctx, tenantOf(ctx), vehicleID, start, end)
+	}
+
+	visible, err := s.vehicles.Visible(ctx)
+	if err != nil {
+		return nil, err
+	}
+	trips, err := s.repo.List(ctx, tenantOf(ctx), "", start, end)
+	if err != nil {
+		return nil, err
+	}
+	return filterVisible(trips, visible, func(t models.Trip) string { return t.VehicleID }), nil
+}
+
+// ProcessLocation memenuhi LocationProcessor.
+func (s *TripService) ProcessLocation(ctx context.Context, prev *models.VehicleLocation, loc models.VehicleLocation) error {
+	open, err := s.repo.GetOpen(ctx, loc.TenantID, loc.VehicleID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		open = nil
+	case err != nil:
+		return err
+	}
+
+	maxGap := int64(s.cfg.MaxGap / time.Second)
+	if open != nil {
+		if loc.Timestamp <= open.Last.Timestamp {
+			return nil
+		}
+		if loc.Timestamp-open.Last.Timestamp <= maxGap {
+			return s.advance(ctx, open, loc)
+		}
+		// data terputus: perjalanan berakhir di titik terakhir yang diketahui
+		if err := s.close(ctx, loc.TenantID, open, open.Last); err != nil {
+			return err
+		}
+		prev = nil
+	}
+
+	if prev != nil && loc.Timestamp-prev.Timestamp > maxGap {
+		prev = nil
+	}
+	return s.maybeStart(ctx, prev, loc)
+}
+
+func (s *TripService) maybeStart(ctx context.Context, prev *models.VehicleLocation, loc models.VehicleLocation) error {
+	if loc.Ignition != nil && !*loc.Ignition {
+		return nil
+	}
+	speed := speedKmh(prev, loc)
+	if speed < s.cfg.MinSpeedKmh {
+		return nil
+	}
+
+	t := models.Trip{
+		TenantID:      loc.TenantID,
+		VehicleID:     loc.VehicleID,
+		Start:         loc.Timestamp,
+		StartLocation: models.Coordinate{Latitude: loc.Latitude, Longitude: loc.Longitude},
+		Last:          loc,
+		PointCount:    1,
+	}
+	if speed <= maxPlausibleSpeedKmh {
+		t.MaxSpeedKmh = speed
+	}
+	// kendaraan berangkat dari titik sebelumnya
+	if prev != nil {
+		if d, ok := segmentMeters(*prev, loc); ok {
+			t.Start = prev.Timestamp
+			t.StartLocation = models.Coordinate{Latitude: prev.Latitude, Longitude: prev.Longitude}
+			t.DistanceM = d
+			t.PointCount = 2
+		}
+	}
+
+	if s.drivers != nil {
+		driverID, err := s.drivers.DriverAt(ctx, loc.TenantID, t.VehicleID, t.Start)
+		if err != nil {
+			log.Printf("failed to look up driver for %s: %v", t.VehicleID, err)
+		}
+		t.DriverID = driverID
+	}
+
+	created, err := s.repo.Create(ctx, t)
+	if errors.Is(err, ErrConflict) {
+		// perjalanan sudah dibuka oleh pemrosesan titik lain yang bersamaan
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.publish(ctx, loc.TenantID, "trip_started", "trip.started", *created, created.StartLocation, created.Start)
+	return nil
+}
+
+func (s *TripService) advance(ctx context.Context, t *models.Trip, loc models.VehicleLocation) error {
+	last := t.Last
+	speed := speedKmh(&last, loc)
+	if d, ok := segmentMeters(last, loc); ok {
+		t.DistanceM += d
+		if speed <= maxPlausibleSpeedKmh {
+			t.MaxSpeedKmh = max(t.MaxSpeedKmh, speed)
+		}
+	}
+	t.PointCount++
+	t.Last = loc
+
+	stopAfter := int64(s.cfg.StopAfter / time.Second)
+	switch {
+	case loc.Ignition != nil && !*loc.Ignition:
+		return s.close(ctx, loc.TenantID, t, loc)
+	case speed >= s.cfg.MinSpeedKmh:
+		t.Stopped = nil
+	case t.Stopped == nil:
+		stopped := loc
+		t.Stopped = &stopped
+	case loc.Timestamp-t.Stopped.Timestamp >= stopAfter:
+		// perjalanan berakhir saat kendaraan mulai berhenti
+		return s.close(ctx, loc.TenantID, t, *t.Stopped)
+	}
+	return s.repo.Update(ctx, *t)
+}
+
+func (s *TripService) close(ctx context.Context, tenantID string, t *models.Trip, at models.VehicleLocation) error {
+	end := at.Timestamp
+	t.End = &end
+	t.EndLocation = &models.Coordinate{Latitude: at.Latitude, Longitude: at.Longitude}
+
+	closed, err := s.repo.Close(ctx, *t)
+	if err != nil {
+		return err
+	}
+
+	s.publish(ctx, tenantID, "trip_ended", "trip.ended", *closed, *closed.EndLocation, *closed.End)
+	return nil
+}
+
+func (s *TripService) publish(ctx context.Context, tenantID, name, routingKey string, t models.Trip, at models.Coordinate, ts int64) {
+	if s.rabbitCli == nil {
+		return
+	}
+	event := models.TripEvent{
+		TenantID:  tenantID,
+		VehicleID: t.VehicleID,
+		DriverID:  t.DriverID,
+		Event:     name,
+		Location:  at,
+		Timestamp: ts,
+		Trip:      t,
+	}
+	if err := s.rabbitCli.Publish(ctx, event.TenantID, routingKey, event); err != nil {
+		log.Printf("failed to publish %s for %s: %v", name, t.VehicleID, err)
+		return
+	}
+	log.Printf("Published %s for %s (trip %d)", name, t.VehicleID, t.ID)
+}
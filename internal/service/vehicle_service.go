@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"sistem-manajemen-armada/internal/auth"
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// Kebijakan untuk lokasi dari kendaraan yang tidak terdaftar atau nonaktif.
+const (
+	PolicyAccept     = "accept"
+	PolicyReject     = "reject"
+	PolicyQuarantine = "quarantine"
+)
+
+// vehicleIDPattern dan tenantIDPattern harus aman dipakai sebagai segmen topik MQTT (tanpa '/', '+',
+// '#') dan routing key RabbitMQ (tanpa '.').
+var (
+	vehicleIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,50}$`)
+	tenantIDPattern  = vehicleIDPattern
+)
+
+type VehicleService struct {
+	repo   repository.VehicleRepository
+	policy string
+}
+
+func NewVehicleService(repo repository.VehicleRepository, unregisteredPolicy string) *VehicleService {
+	switch unregisteredPolicy {
+	case PolicyAccept, PolicyReject, PolicyQuarantine:
+	default:
+		log.Printf("WARN: unknown unregistered vehicle policy %q, using %q", unregisteredPolicy, PolicyQuarantine)
+		unregisteredPolicy = PolicyQuarantine
+	}
+	return &VehicleService{repo: repo, policy: unregisteredPolicy}
+}
+
+func (s *VehicleService) Create(ctx context.Context, v models.Vehicle) (*models.Vehicle, error) {
+	if err := validateVehicle(&v); err != nil {
+		return nil, err
+	}
+	if err := checkGroupAllowed(ctx, v.Group); err != nil {
+		return nil, err
+	}
+	v.TenantID = ownerTenant(ctx)
+	return s.repo.Create(ctx, v)
+}
+
+// Get mengembalikan kendaraan di tenant pemanggil, atau ErrNotFound bila tidak ada atau di luar grup
+// pemanggil.
+func (s *VehicleService) Get(ctx context.Context, id string) (*models.Vehicle, error) {
+	return s.getInTenant(ctx, tenantOf(ctx), id)
+}
+
+// getInTenant seperti Get, tetapi mencari kendaraan di tenant yang diberikan: tenant pemilik device /
+// pengemudi yang dipasang, atau tenant lokasi untuk proses internal.
+func (s *VehicleService) getInTenant(ctx context.Context, tenantID, id string) (*models.Vehicle, error) {
+	v, err := s.repo.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !auth.FromContext(ctx).CanSeeGroup(v.Group) {
+		return nil, ErrNotFound
+	}
+	return v, nil
+}
+
+func (s *VehicleService) List(ctx context.Context, filter models.VehicleFilter) ([]models.Vehicle, error) {
+	filter.TenantID = tenantOf(ctx)
+	if groups := allowedGroups(ctx); groups != nil {
+		filter.Groups = groups
+	}
+	return s.repo.List(ctx, filter)
+}
+
+func (s *VehicleService) Update(ctx context.Context, v models.Vehicle) (*models.Vehicle, error) {
+	if err := validateVehicle(&v); err != nil {
+		return nil, err
+	}
+	existing, err := s.Get(ctx, v.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGroupAllowed(ctx, v.Group); err != nil {
+		return nil, err
+	}
+	v.TenantID = existing.TenantID
+	return s.repo.Update(ctx, v)
+}
+
+func (s *VehicleService) Delete(ctx context.Context, id string) error {
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, existing.TenantID, id)
+}
+
+// CheckIngest menerapkan kebijakan registry untuk lokasi yang masuk. Mengembalikan nil bila
+// lokasi boleh disimpan, ErrQuarantined bila dipindah ke quarantined_locations, atau ErrRejected.
+// Kendaraan dicari di tenant lokasi; kendaraan tenant lain dengan ID yang sama tidak tersentuh.
+func (s *VehicleService) CheckIngest(ctx context.Context, loc models.VehicleLocation) error {
+	v, err := s.repo.Get(ctx, loc.TenantID, loc.VehicleID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return s.Refuse(ctx, loc, "unregistered vehicle")
+	case err != nil:
+		return err
+	case !v.Active:
+		return s.Refuse(ctx, loc, "inactive vehicle")
+	default:
+		return nil
+	}
+}
+
+// Refuse menerapkan kebijakan untuk lokasi yang tidak bisa dikaitkan ke kendaraan aktif.
+// Dengan kebijakan accept, lokasi tetap disimpan (nil).
+func (s *VehicleService) Refuse(ctx context.Context, loc models.VehicleLocation, reason string) error {
+	switch s.policy {
+	case PolicyAccept:
+		return nil
+	case PolicyReject:
+		return fmt.Errorf("%w: %s", ErrRejected, reason)
+	}
+
+	return s.Quarantine(ctx, loc, reason)
+}
+
+// Quarantine memindahkan lokasi ke quarantined_locations apa pun kebijakannya, lalu mengembalikan
+// ErrQuarantined.
+func (s *VehicleService) Quarantine(ctx context.Context, loc models.VehicleLocation, reason string) error {
+	if err := s.repo.Quarantine(ctx, loc, reason); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", ErrQuarantined, reason)
+}
+
+func validateVehicle(v *models.Vehicle) error {
+	v.ID = strings.TrimSpace(v.ID)
+	v.PlateNumber = strings.ToUpper(strings.TrimSpace(v.PlateNumber))
+	v.VIN = strings.ToUpper(strings.TrimSpace(v.VIN))
+	v.DeviceIMEI = strings.TrimSpace(v.DeviceIMEI)
+
+	if !vehicleIDPattern.MatchString(v.ID) {
+		return invalidf("id must be 1-50 characters of letters, digits, '-' or '_'")
+	}
+	if v.PlateNumber == "" || len(v.PlateNumber) > 20 {
+		return invalidf("plate_number is required (max 20 characters)")
+	}
+	if v.VIN != "" && len(v.VIN) != 17 {
+		return invalidf("vin must be 17 characters")
+	}
+	if v.DeviceIMEI != "" && (len(v.DeviceIMEI) < 14 || len(v.DeviceIMEI) > 16 || strings.Trim(v.DeviceIMEI, "0123456789") != "") {
+		return invalidf("device_imei must be 14-16 digits")
+	}
+	if v.CapacityKg < 0 {
+		return invalidf("capacity_kg must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+const (
+	// webhookBatch adalah jumlah pengiriman yang diambil sekali jalan.
+	webhookBatch = 50
+	// webhookLeaseMargin ditambahkan ke timeout request saat mengklaim pengiriman, supaya worker
+	// lain tidak mengirim ulang pengiriman yang masih berjalan.
+	webhookLeaseMargin = 30 * time.Second
+	// webhookDeliveryListLimit membatasi jumlah log pengiriman yang ditampilkan.
+	webhookDeliveryListLimit = 100
+	// webhookSecretPrefix menandai secret webhook, memudahkan secret scanner mengenalinya.
+	webhookSecretPrefix = "whsec_"
+)
+
+// Header request webhook.
+const (
+	WebhookHeaderEvent     = "X-Fleet-Event"
+	WebhookHeaderDelivery  = "X-Fleet-Delivery"
+	WebhookHeaderTimestamp = "X-Fleet-Timestamp"
+	WebhookHeaderSignature = "X-Fleet-Signature"
+)
+
+// WebhookEventTypes adalah jenis event yang bisa dilanggan webhook.
+var WebhookEventTypes = []string{
+	"geofence_entry",
+	"trip_started",
+	"trip_ended",
+	"overspeed_started",
+	"overspeed_ended",
+	"tamper_suspected",
+}
+
+// WebhookConfig mengatur pengiriman webhook.
+type WebhookConfig struct {
+	Timeout     time.Duration // batas waktu satu request
+	MaxAttempts int           // setelah percobaan ini gagal, pengiriman ditandai failed
+	RetryBase   time.Duration // jeda sebelum percobaan kedua; berlipat dua setiap kali gagal
+	RetryMax    time.Duration // batas atas jeda antar percobaan
+
+	// AllowedNetworks adalah rentang alamat internal (loopback, privat, link-local, ...) yang tetap
+	// boleh dituju webhook. Alamat internal lain ditolak saat koneksi dibuka.
+	AllowedNetworks []netip.Prefix
+}
+
+// WebhookService mengelola webhook per tenant dan mengirim event armada ke sana. Setiap event
+// dicatat dulu di webhook_deliveries lalu dikirim oleh Run, sehingga pengiriman yang gagal diulang
+// dengan backoff eksponensial dan tetap tercatat walau worker restart.
+type WebhookService struct {
+	repo       repository.WebhookRepository
+	deliveries repository.WebhookDeliveryRepository
+	client     *http.Client
+	cfg        WebhookConfig
+}
+
+// NewWebhookService membuat service webhook. client nil = http.Client dengan cfg.Timeout yang
+// menolak alamat internal di luar cfg.AllowedNetworks.
+func NewWebhookService(repo repository.WebhookRepository, deliveries repository.WebhookDeliveryRepository, client *http.Client, cfg WebhookConfig) *WebhookService {
+	if client == nil {
+		client = guardedClient(cfg.Timeout, cfg.AllowedNetworks)
+	}
+	return &WebhookService{repo: repo, deliveries: deliveries, client: client, cfg: cfg}
+}
+
+// Create mendaftarkan webhook untuk tenant pemanggil. Secret HMAC hanya dikembalikan di sini.
+func (s *WebhookService) Create(ctx context.Context, w models.Webhook) (*models.CreatedWebhook, error) {
+	if err := checkAllGroups(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateWebhook(&w); err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	w.Secret = []byte(secret)
+	w.TenantID = ownerTenant(ctx)
+
+	created, err := s.repo.Create(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+	return &models.CreatedWebhook{Webhook: *created, SigningSecret: secret}, nil
+}
+
+func (s *WebhookService) Get(ctx context.Context, id int64) (*models.Webhook, error) {
+	if err := checkAllGroups(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(ctx, tenantOf(ctx), id)
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]models.Webhook, error) {
+	if err := checkAllGroups(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, tenantOf(ctx))
+}
+
+// Update mengubah URL, jenis event dan status aktif webhook. Secret tidak berubah.
+func (s *WebhookService) Update(ctx context.Context, w models.Webhook) (*models.Webhook, error) {
+	if err := checkAllGroups(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateWebhook(&w); err != nil {
+		return nil, err
+	}
+	w.TenantID = tenantOf(ctx)
+	return s.repo.Update(ctx, w)
+}
+
+// Delete menghapus webhook beserta log pengirimannya.
+func (s *WebhookService) Delete(ctx context.Context, id int64) error {
+	if err := checkAllGroups(ctx); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, tenantOf(ctx), id)
+}
+
+// ListDeliveries mengembalikan log pengiriman terbaru sebuah webhook.
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID int64) ([]models.WebhookDelivery, error) {
+	if _, err := s.Get(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	return s.deliveries.List(ctx, tenantOf(ctx), webhookID, webhookDeliveryListLimit)
+}
+
+// Redeliver menjadwalkan pengiriman ulang payload sebuah pengiriman sebagai pengiriman baru.
+// Pengiriman lama tetap di log apa adanya.
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	if _, err := s.Get(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	d, err := s.deliveries.Get(ctx, tenantOf(ctx), webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	return s.deliveries.Create(ctx, models.WebhookDelivery{
+		WebhookID:    d.WebhookID,
+		TenantID:     d.TenantID,
+		EventType:    d.EventType,
+		Payload:      d.Payload,
+		RedeliveryOf: &d.ID,
+	})
+}
+
+// Enqueue mencatat event untuk setiap webhook aktif milik tenant yang berlangganan eventType.
+// Pengiriman sebenarnya dilakukan Run. Event dengan eventKey (lihat EventKey) yang sudah diantrekan
+// tidak dijadwalkan lagi. Mengembalikan jumlah pengiriman yang baru dijadwalkan.
+func (s *WebhookService) Enqueue(ctx context.Context, tenantID, eventType, eventKey string, payload []byte) (int64, error) {
+	return s.deliveries.Enqueue(ctx, tenantID, eventType, eventKey, payload)
+}
+
+// EventKey mengembalikan kunci idempoten event armada: hex SHA-256 payload-nya. Payload yang sama
+// (kendaraan, jenis event, timestamp, lokasi) dianggap event yang sama, mis. pesan RabbitMQ yang
+// dikirim ulang setelah Nack.
+func EventKey(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Run mengirim pengiriman yang jatuh tempo secara periodik sampai ctx dibatalkan.
+func (s *WebhookService) Run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		// batch penuh berarti masih ada antrean; lanjutkan tanpa menunggu tick berikutnya
+		for {
+			n, err := s.DeliverDue(ctx)
+			if err != nil {
+				log.Printf("webhook delivery failed: %v", err)
+			}
+			if err != nil || n < webhookBatch || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue mengirim satu batch pengiriman pending yang sudah jatuh tempo dan mengembalikan
+// jumlahnya.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	due, err := s.deliveries.ClaimDue(ctx, webhookBatch, s.cfg.Timeout+webhookLeaseMargin)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range due {
+		w, err := s.repo.Get(ctx, "", d.WebhookID)
+		if errors.Is(err, ErrNotFound) {
+			// webhook dihapus; log pengirimannya ikut terhapus
+			continue
+		}
+		if err != nil {
+			log.Printf("failed to load webhook %d: %v", d.WebhookID, err)
+			continue
+		}
+
+		s.attempt(ctx, w, &d, time.Now())
+		if err := s.deliveries.RecordAttempt(ctx, d); err != nil {
+			log.Printf("failed to record webhook delivery %d: %v", d.ID, err)
+		}
+	}
+	return len(due), nil
+}
+
+// attempt mengirim d ke w sekali lalu memperbarui status, jumlah percobaan dan jadwal berikutnya.
+func (s *WebhookService) attempt(ctx context.Context, w *models.Webhook, d *models.WebhookDelivery, now time.Time) {
+	if !w.Active {
+		d.Status = models.DeliveryFailed
+		d.LastError = "webhook is disabled"
+		return
+	}
+
+	d.Attempts++
+	code, err := s.send(ctx, w, *d, now)
+	d.LastStatusCode = nil
+	if code != 0 {
+		d.LastStatusCode = &code
+	}
+
+	switch {
+	case err == nil:
+		d.Status = models.DeliverySucceeded
+		d.LastError = ""
+		d.DeliveredAt = &now
+		log.Printf("Delivered %s to webhook %d (delivery %d)", d.EventType, w.ID, d.ID)
+	case d.Attempts >= s.cfg.MaxAttempts:
+		d.Status = models.DeliveryFailed
+		d.LastError = err.Error()
+		log.Printf("webhook %d delivery %d failed after %d attempts: %v", w.ID, d.ID, d.Attempts, err)
+	default:
+		d.Status = models.DeliveryPending
+		d.LastError = err.Error()
+		d.NextAttemptAt = now.Add(s.backoff(d.Attempts))
+	}
+}
+
+// send melakukan request POST dan mengembalikan kode status (0 bila tidak ada respons).
+// Respons 2xx dianggap berhasil.
+func (s *WebhookService) send(ctx context.Context, w *models.Webhook, d models.WebhookDelivery, now time.Time) (int, error) {
+	ts := now.Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "fleet-webhooks/1")
+	req.Header.Set(WebhookHeaderEvent, d.EventType)
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(WebhookHeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(WebhookHeaderSignature, SignWebhook(w.Secret, ts, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff mengembalikan jeda sebelum percobaan berikutnya setelah attempts kali gagal.
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	return retryBackoff(s.cfg.RetryBase, s.cfg.RetryMax, attempts)
+}
+
+// retryBackoff mengembalikan base yang berlipat dua untuk setiap kegagalan setelah yang pertama,
+// dibatasi maxDelay.
+func retryBackoff(base, maxDelay time.Duration, attempts int) time.Duration {
+	d := base
+	for i := 1; i < attempts && d < maxDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxDelay)
+}
+
+// SignWebhook mengembalikan nilai header X-Fleet-Signature: "sha256=" + hex HMAC-SHA256 dari
+// "{timestamp}.{body}" dengan secret webhook sebagai kunci. Penerima sebaiknya juga menolak
+// timestamp yang terlalu lama untuk mencegah replay.
+func SignWebhook(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func validateWebhook(w *models.Webhook) error {
+	w.URL = strings.TrimSpace(w.URL)
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return invalidf("url must be an absolute http or https URL")
+	}
+
+	for _, t := range w.EventTypes {
+		if !slices.Contains(WebhookEventTypes, t) {
+			return invalidf("event type %q is not supported, must be one of %s", t, strings.Join(WebhookEventTypes, ", "))
+		}
+	}
+	if w.EventTypes == nil {
+		w.EventTypes = []string{}
+	}
+	slices.Sort(w.EventTypes)
+	w.EventTypes = slices.Compact(w.EventTypes)
+	return nil
+}
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sistem-manajemen-armada/internal/auth"
+	"sistem-manajemen-armada/internal/models"
+	"sistem-manajemen-armada/internal/repository"
+)
+
+// fakeWebhookRepository menyimpan webhook di memori; tenantID kosong = semua tenant.
+type fakeWebhookRepository struct {
+	repository.WebhookRepository
+	webhooks []models.Webhook
+}
+
+func (r *fakeWebhookRepository) Get(_ context.Context, tenantID string, id int64) (*models.Webhook, error) {
+	for _, w := range r.webhooks {
+		if w.ID == id && (tenantID == "" || w.TenantID == tenantID) {
+			return &w, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// fakeWebhookDeliveryRepository menyimpan antrean pengiriman di memori dengan aturan klaim yang
+// sama dengan Postgres: hanya pengiriman pending yang sudah jatuh tempo yang diambil.
+type fakeWebhookDeliveryRepository struct {
+	repository.WebhookDeliveryRepository
+	deliveries []models.WebhookDelivery
+}
+
+func (r *fakeWebhookDeliveryRepository) Create(_ context.Context, d models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	d.ID = int64(len(r.deliveries) + 1)
+	d.Status = models.DeliveryPending
+	d.CreatedAt = time.Now()
+	d.NextAttemptAt = d.CreatedAt
+	r.deliveries = append(r.deliveries, d)
+	return &d, nil
+}
+
+func (r *fakeWebhookDeliveryRepository) Get(_ context.Context, tenantID string, webhookID, id int64) (*models.WebhookDelivery, error) {
+	for _, d := range r.deliveries {
+		if d.ID == id && d.WebhookID == webhookID && (tenantID == "" || d.TenantID == tenantID) {
+			return &d, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *fakeWebhookDeliveryRepository) ClaimDue(_ context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	now := time.Now()
+	var due []models.WebhookDelivery
+	for i := range r.deliveries {
+		d := &r.deliveries[i]
+		if d.Status != models.DeliveryPending || d.NextAttemptAt.After(now) || len(due) == limit {
+			continue
+		}
+		d.NextAttemptAt = now.Add(lease)
+		due = append(due, *d)
+	}
+	return due, nil
+}
+
+func (r *fakeWebhookDeliveryRepository) RecordAttempt(_ context.Context, d models.WebhookDelivery) error {
+	for i := range r.deliveries {
+		if r.deliveries[i].ID == d.ID {
+			r.deliveries[i] = d
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+// makeDue memajukan jadwal semua pengiriman pending ke sekarang, menggantikan menunggu backoff.
+func (r *fakeWebhookDeliveryRepository) makeDue() {
+	for i := range r.deliveries {
+		r.deliveries[i].NextAttemptAt = time.Now()
+	}
+}
+
+// webhookReceiver adalah penerima webhook yang mencatat setiap request dan menjawab dengan status.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	w.WriteHeader(rc.status)
+}
+
+func (rc *webhookReceiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func (rc *webhookReceiver) request(i int) (*http.Request, []byte) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.requests[i], rc.bodies[i]
+}
+
+// loopback mengizinkan penerima httptest yang listen di alamat loopback.
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
+// newWebhookFixture menyiapkan satu webhook aktif milik acme yang menunjuk ke penerima httptest,
+// dengan satu pengiriman pending.
+func newWebhookFixture(t *testing.T, status int, cfg WebhookConfig) (*WebhookService, *fakeWebhookDeliveryRepository, *webhookReceiver) {
+	t.Helper()
+	receiver := &webhookReceiver{status: status}
+	srv := httptest.NewServer(receiver)
+	t.Cleanup(srv.Close)
+
+	webhooks := &fakeWebhookRepository{webhooks: []models.Webhook{
+		{ID: 1, TenantID: "acme", URL: srv.URL + "/hook", Active: true, Secret: []byte("whsec_test")},
+	}}
+	deliveries := &fakeWebhookDeliveryRepository{}
+	_, _ = deliveries.Create(context.Background(), models.WebhookDelivery{
+		WebhookID: 1, TenantID: "acme", EventType: "trip_started", Payload: []byte(`{"vehicle_id":"V1"}`),
+	})
+	return NewWebhookService(webhooks, deliveries, nil, cfg), deliveries, receiver
+}
+
+func TestWebhookServiceDeliverDueSignsRequest(t *testing.T) {
+	svc, deliveries, receiver := newWebhookFixture(t, http.StatusNoContent, WebhookConfig{
+		Timeout: 5 * time.Second, MaxAttempts: 3, RetryBase: time.Minute, RetryMax: time.Hour, AllowedNetworks: loopback,
+	})
+
+	n, err := svc.DeliverDue(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("DeliverDue = %d, %v, want 1, nil", n, err)
+	}
+	if receiver.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", receiver.count())
+	}
+
+	req, body := receiver.request(0)
+	if string(body) != `{"vehicle_id":"V1"}` {
+		t.Errorf("body = %s", body)
+	}
+	if got := req.Header.Get(WebhookHeaderEvent); got != "trip_started" {
+		t.Errorf("%s = %q, want trip_started", WebhookHeaderEvent, got)
+	}
+	if got := req.Header.Get(WebhookHeaderDelivery); got != "1" {
+		t.Errorf("%s = %q, want 1", WebhookHeaderDelivery, got)
+	}
+	ts, err := strconv.ParseInt(req.Header.Get(WebhookHeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", WebhookHeaderTimestamp, err)
+	}
+	if got, want := req.Header.Get(WebhookHeaderSignature), SignWebhook([]byte("whsec_test"), ts, body); got != want {
+		t.Errorf("%s = %q, want %q", WebhookHeaderSignature, got, want)
+	}
+	if !strings.HasPrefix(req.Header.Get(WebhookHeaderSignature), "sha256=") {
+		t.Errorf("signature is missing the sha256= prefix")
+	}
+	if SignWebhook([]byte("other"), ts, body) == req.Header.Get(WebhookHeaderSignature) {
+		t.Errorf("signature does not depend on the secret")
+	}
+
+	d := deliveries.deliveries[0]
+	if d.Status != models.DeliverySucceeded || d.Attempts != 1 || d.DeliveredAt == nil {
+		t.Errorf("delivery = status %s, attempts %d, delivered %v, want succeeded after 1 attempt", d.Status, d.Attempts, d.DeliveredAt)
+	}
+	if d.LastStatusCode == nil || *d.LastStatusCode != http.StatusNoContent {
+		t.Errorf("last status code = %v, want %d", d.LastStatusCode, http.StatusNoContent)
+	}
+}
+
+func TestWebhookServiceRetriesUntilMaxAttempts(t *testing.T) {
+	cfg := WebhookConfig{
+		Timeout: 5 * time.Second, MaxAttempts: 4, RetryBase: time.Minute, RetryMax: 3 * time.Minute, AllowedNetworks: loopback,
+	}
+	svc, deliveries, receiver := newWebhookFixture(t, http.StatusInternalServerError, cfg)
+
+	// jeda setelah gagal ke-1, ke-2 dan ke-3: base, berlipat dua, lalu dibatasi RetryMax
+	for attempt, wantDelay := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute} {
+		before := time.Now()
+		if _, err := svc.DeliverDue(context.Background()); err != nil {
+			t.Fatalf("DeliverDue: %v", err)
+		}
+		after := time.Now()
+
+		d := deliveries.deliveries[0]
+		if d.Status != models.DeliveryPending || d.Attempts != attempt+1 {
+			t.Fatalf("after attempt %d: status %s, attempts %d, want pending", attempt+1, d.Status, d.Attempts)
+		}
+		if d.NextAttemptAt.Before(before.Add(wantDelay)) || d.NextAttemptAt.After(after.Add(wantDelay)) {
+			t.Fatalf("after attempt %d: next attempt in %v, want %v", attempt+1, d.NextAttemptAt.Sub(before), wantDelay)
+		}
+		if d.LastError != "unexpected status 500" {
+			t.Fatalf("after attempt %d: last error %q", attempt+1, d.LastError)
+		}
+
+		// belum jatuh tempo: tidak dikirim lagi
+		if n, _ := svc.DeliverDue(context.Background()); n != 0 {
+			t.Fatalf("after attempt %d: delivery claimed again before its backoff elapsed", attempt+1)
+		}
+		deliveries.makeDue()
+	}
+
+	if _, err := svc.DeliverDue(context.Background()); err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+	d := deliveries.deliveries[0]
+	if d.Status != models.DeliveryFailed || d.Attempts != cfg.MaxAttempts {
+		t.Fatalf("status %s after %d attempts, want failed after %d", d.Status, d.Attempts, cfg.MaxAttempts)
+	}
+	if d.LastStatusCode == nil || *d.LastStatusCode != http.StatusInternalServerError || d.DeliveredAt != nil {
+		t.Fatalf("last status code %v, delivered %v", d.LastStatusCode, d.DeliveredAt)
+	}
+
+	deliveries.makeDue()
+	if n, _ := svc.DeliverDue(context.Background()); n != 0 {
+		t.Fatalf("failed delivery was claimed again")
+	}
+	if receiver.count() != cfg.MaxAttempts {
+		t.Fatalf("receiver got %d requests, want %d", receiver.count(), cfg.MaxAttempts)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(30*time.Second, time.Hour, tt.attempts); got != tt.want {
+			t.Errorf("retryBackoff(30s, 1h, %d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookServiceRedeliver(t *testing.T) {
+	svc, deliveries, receiver := newWebhookFixture(t, http.StatusOK, WebhookConfig{
+		Timeout: 5 * time.Second, MaxAttempts: 1, RetryBase: time.Minute, RetryMax: time.Hour, AllowedNetworks: loopback,
+	})
+	deliveries.deliveries[0].Status = models.DeliveryFailed
+	deliveries.deliveries[0].Attempts = 1
+
+	if _, err := svc.Redeliver(tenantCtx("beta", auth.RoleAdmin), 1, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Redeliver from another tenant = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.Redeliver(tenantCtx("acme", auth.RoleAdmin), 1, 2); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Redeliver of unknown delivery = %v, want ErrNotFound", err)
+	}
+
+	created, err := svc.Redeliver(tenantCtx("acme", auth.RoleAdmin), 1, 1)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if created.ID == 1 || created.RedeliveryOf == nil || *created.RedeliveryOf != 1 {
+		t.Fatalf("redelivery = id %d, redelivery_of %v, want a new delivery of 1", created.ID, created.RedeliveryOf)
+	}
+	if created.Status != models.DeliveryPending || created.TenantID != "acme" || string(created.Payload) != `{"vehicle_id":"V1"}` {
+		t.Fatalf("redelivery = %+v, want the original payload pending", created)
+	}
+
+	if n, err := svc.DeliverDue(context.Background()); err != nil || n != 1 {
+		t.Fatalf("DeliverDue = %d, %v, want 1, nil", n, err)
+	}
+	req, _ := receiver.request(0)
+	if got := req.Header.Get(WebhookHeaderDelivery); got != strconv.FormatInt(created.ID, 10) {
+		t.Errorf("%s = %q, want the new delivery ID %d", WebhookHeaderDelivery, got, created.ID)
+	}
+	if d := deliveries.deliveries[0]; d.Status != models.DeliveryFailed || d.Attempts != 1 {
+		t.Errorf("original delivery changed: status %s, attempts %d", d.Status, d.Attempts)
+	}
+	if d := deliveries.deliveries[1]; d.Status != models.DeliverySucceeded {
+		t.Errorf("redelivery status %s, want succeeded", d.Status)
+	}
+}
+
+func TestWebhookServiceRefusesInternalAddress(t *testing.T) {
+	svc, deliveries, receiver := newWebhookFixture(t, http.StatusOK, WebhookConfig{
+		Timeout: 5 * time.Second, MaxAttempts: 3, RetryBase: time.Minute, RetryMax: time.Hour,
+	})
+
+	if _, err := svc.DeliverDue(context.Background()); err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+	if receiver.count() != 0 {
+		t.Fatalf("receiver on a loopback address got %d requests, want 0", receiver.count())
+	}
+	d := deliveries.deliveries[0]
+	if d.Status != models.DeliveryPending || !strings.Contains(d.LastError, "address is not public") {
+		t.Fatalf("delivery = status %s, error %q, want pending with the egress error", d.Status, d.LastError)
+	}
+}
+
+func TestEgressAllowed(t *testing.T) {
+	allow := []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")}
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"10.20.1.1", true},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.20.0.5", true},
+	}
+	for _, tt := range tests {
+		if got := egressAllowed(netip.MustParseAddr(tt.addr), allow); got != tt.want {
+			t.Errorf("egressAllowed(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
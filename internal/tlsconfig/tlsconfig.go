@@ -0,0 +1,59 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Options berisi file PEM dan nama server untuk koneksi TLS keluar (broker MQTT, RabbitMQ,
+// PostgreSQL). Semua field kosong = TLS tidak dikonfigurasi.
+type Options struct {
+	CAFile     string // bundle CA untuk verifikasi server; kosong = CA sistem
+	CertFile   string // sertifikat klien untuk mTLS; wajib berpasangan dengan KeyFile
+	KeyFile    string
+	ServerName string // nama di sertifikat server; kosong = host dari URL
+}
+
+// Enabled melaporkan apakah ada opsi TLS yang diisi.
+func (o Options) Enabled() bool {
+	return o.CAFile != "" || o.CertFile != "" || o.KeyFile != "" || o.ServerName != ""
+}
+
+// Client membangun konfigurasi TLS klien dari opsi. Mengembalikan nil bila opsi kosong.
+func (o Options) Client() (*tls.Config, error) {
+	if !o.Enabled() {
+		return nil, nil
+	}
+
+	c := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: o.ServerName,
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", o.CAFile)
+		}
+		c.RootCAs = pool
+	}
+
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return nil, errors.New("client certificate and key must be set together")
+	}
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
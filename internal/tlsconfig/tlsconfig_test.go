@@ -0,0 +1,236 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testPKI berisi CA, sertifikat server untuk "fleet.test" dan sertifikat klien, semuanya sebagai
+// file PEM di direktori sementara.
+type testPKI struct {
+	caFile     string
+	serverCert tls.Certificate
+	clientCert string
+	clientKey  string
+	caPool     *x509.CertPool
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	now := time.Now()
+
+	caKey := newKey(t)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fleet test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("parse CA: %v", err)
+	}
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) ([]byte, *ecdsa.PrivateKey) {
+		key := newKey(t)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{name},
+			NotBefore:    now.Add(-time.Hour),
+			NotAfter:     now.Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("create certificate %s: %v", name, err)
+		}
+		return der, key
+	}
+
+	serverDER, serverKey := issue(2, "fleet.test", x509.ExtKeyUsageServerAuth)
+	clientDER, clientKey := issue(3, "fleet-backend", x509.ExtKeyUsageClientAuth)
+
+	pki := testPKI{
+		caFile:     writePEM(t, dir, "ca.pem", "CERTIFICATE", caDER),
+		clientCert: writePEM(t, dir, "client.pem", "CERTIFICATE", clientDER),
+		clientKey:  writePEM(t, dir, "client-key.pem", "PRIVATE KEY", marshalKey(t, clientKey)),
+		caPool:     x509.NewCertPool(),
+	}
+	pki.caPool.AddCert(ca)
+	pki.serverCert, err = tls.X509KeyPair(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: marshalKey(t, serverKey)}),
+	)
+	if err != nil {
+		t.Fatalf("load server certificate: %v", err)
+	}
+	return pki
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+func marshalKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return der
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+// listenMTLS menjalankan server TLS yang mewajibkan sertifikat klien dari CA pki. Setiap koneksi
+// yang lolos handshake dijawab "ok".
+func listenMTLS(t *testing.T, pki testPKI) string {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{pki.serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pki.caPool,
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+				if err := conn.(*tls.Conn).Handshake(); err == nil {
+					_, _ = conn.Write([]byte("ok"))
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// exchange membuka koneksi TLS ke addr dengan konfigurasi dari opts dan membaca jawaban server.
+// Pada TLS 1.3 penolakan sertifikat klien baru terlihat saat membaca, bukan saat handshake.
+func exchange(addr string, opts Options) error {
+	c, err := opts.Client()
+	if err != nil {
+		return err
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", addr, c)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	buf := make([]byte, 2)
+	if _, err := conn.Read(buf); err != nil {
+		return err
+	}
+	if string(buf) != "ok" {
+		return errors.New("unexpected reply " + string(buf))
+	}
+	return nil
+}
+
+func TestClientMutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	addr := listenMTLS(t, pki)
+
+	opts := Options{CAFile: pki.caFile, CertFile: pki.clientCert, KeyFile: pki.clientKey, ServerName: "fleet.test"}
+	if err := exchange(addr, opts); err != nil {
+		t.Fatalf("mTLS handshake with client certificate failed: %v", err)
+	}
+
+	t.Run("missing client certificate", func(t *testing.T) {
+		opts := Options{CAFile: pki.caFile, ServerName: "fleet.test"}
+		if err := exchange(addr, opts); err == nil {
+			t.Fatal("server accepted a connection without a client certificate")
+		}
+	})
+
+	t.Run("wrong server name", func(t *testing.T) {
+		opts := Options{CAFile: pki.caFile, CertFile: pki.clientCert, KeyFile: pki.clientKey, ServerName: "other.test"}
+		err := exchange(addr, opts)
+		var hostErr x509.HostnameError
+		if !errors.As(err, &hostErr) {
+			t.Fatalf("exchange = %v, want a hostname verification error", err)
+		}
+	})
+
+	t.Run("unknown CA", func(t *testing.T) {
+		other := newTestPKI(t)
+		opts := Options{CAFile: other.caFile, CertFile: pki.clientCert, KeyFile: pki.clientKey, ServerName: "fleet.test"}
+		err := exchange(addr, opts)
+		var authErr x509.UnknownAuthorityError
+		if !errors.As(err, &authErr) {
+			t.Fatalf("exchange = %v, want an unknown authority error", err)
+		}
+	})
+}
+
+func TestClientOptions(t *testing.T) {
+	pki := newTestPKI(t)
+
+	if c, err := (Options{}).Client(); c != nil || err != nil {
+		t.Fatalf("empty options = %v, %v, want nil, nil", c, err)
+	}
+
+	c, err := Options{CAFile: pki.caFile, ServerName: "fleet.test"}.Client()
+	if err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	if c.ServerName != "fleet.test" || c.RootCAs == nil || c.MinVersion != tls.VersionTLS12 || c.InsecureSkipVerify {
+		t.Fatalf("config = server name %q, roots %v, min version %x, insecure %v", c.ServerName, c.RootCAs != nil, c.MinVersion, c.InsecureSkipVerify)
+	}
+
+	if _, err := (Options{CertFile: pki.clientCert}).Client(); err == nil {
+		t.Error("certificate without key was accepted")
+	}
+	if _, err := (Options{CAFile: pki.clientKey}).Client(); err == nil {
+		t.Error("CA file without certificates was accepted")
+	}
+	if _, err := (Options{CAFile: filepath.Join(t.TempDir(), "missing.pem")}).Client(); err == nil {
+		t.Error("missing CA file was accepted")
+	}
+}